@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/indexer"
+	"pundix-homework/web"
+	"strconv"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLedgerLimit = 100
+	maxLedgerLimit     = 1000
+	maxHistoryPoints   = 10000
+
+	DirectionIn   = "in"
+	DirectionOut  = "out"
+	DirectionSelf = "self"
+)
+
+type historyResponse struct {
+	Address  string                 `json:"address"`
+	Denom    string                 `json:"denom"`
+	Interval string                 `json:"interval"`
+	Points   []indexer.BalancePoint `json:"points"`
+}
+
+// ledgerEntry is a transfer seen from one address. Counterparty is the
+// address on the other chain for IBC and bridge transfers that name it.
+type ledgerEntry struct {
+	indexer.Transfer
+	Direction    string `json:"direction"`
+	Counterparty string `json:"counterparty"`
+}
+
+// ledgerResponse has NextFrom set when more transfers are left, it is the
+// from of the next page.
+type ledgerResponse struct {
+	Address   string        `json:"address"`
+	Transfers []ledgerEntry `json:"transfers"`
+	NextFrom  int64         `json:"next_from,omitempty"`
+}
+
+// indexedRange reads the address path param and the from and to query params,
+// which default to the first and last indexed blocks. It answers the request
+// itself when it returns false.
+func indexedRange(c *gin.Context) (string, int64, int64, indexer.Checkpoint, bool) {
+	if indexStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "indexer disabled, set " + indexer.PathEnvKey})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+	address := c.Param("address")
+	if _, err := sdk.AccAddressFromBech32(address); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address: " + err.Error()})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+	cp, err := indexStore.Checkpoint()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+	first, err := indexStore.FirstHeight()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+	if cp.Height == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "nothing indexed yet"})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+
+	from, to := first, cp.Height
+	for param, value := range map[string]*int64{"from": &from, "to": &to} {
+		if s := c.Query(param); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a positive integer"})
+				return "", 0, 0, indexer.Checkpoint{}, false
+			}
+			*value = n
+		}
+	}
+	if from < first || to > cp.Height || from > to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("from and to must be within the indexed blocks %d to %d", first, cp.Height)})
+		return "", 0, 0, indexer.Checkpoint{}, false
+	}
+	return address, from, to, cp, true
+}
+
+// AccountHistoryHandler samples the balance of an address per block with a
+// change or per day. The indexed changes are undone from the balance the node
+// has at the last indexed block.
+func AccountHistoryHandler(c *gin.Context) {
+	address, from, to, cp, ok := indexedRange(c)
+	if !ok {
+		return
+	}
+	denom := c.DefaultQuery("denom", "FX")
+	if err := sdk.ValidateDenom(denom); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	interval := c.DefaultQuery("interval", indexer.IntervalBlock)
+	if interval != indexer.IntervalBlock && interval != indexer.IntervalDay {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be block or day"})
+		return
+	}
+
+	res, err := clients.BankQueryClientInstance.DenomBalance(clients.WithHeight(c.Request.Context(), cp.Height), address, denom)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	points, err := indexStore.BalanceHistory(address, *res.Balance, cp.Height, from, to, interval, maxHistoryPoints)
+	switch {
+	case errors.Is(err, indexer.ErrTooManyPoints):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, indexer.ErrUnaccounted):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondRecords(c, historyResponse{Address: address, Denom: denom, Interval: interval, Points: points})
+}
+
+// AccountTransfersHandler lists the transfers of an address oldest first,
+// optionally only the ones of a direction or a kind.
+func AccountTransfersHandler(c *gin.Context) {
+	address, from, to, _, ok := indexedRange(c)
+	if !ok {
+		return
+	}
+	limit := defaultLedgerLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxLedgerLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLedgerLimit)})
+			return
+		}
+		limit = n
+	}
+	direction, kind := c.Query("direction"), c.Query("kind")
+	switch direction {
+	case "", DirectionIn, DirectionOut, DirectionSelf:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be in, out or self"})
+		return
+	}
+	switch kind {
+	case "", indexer.TransferSend, indexer.TransferFee, indexer.TransferIBC, indexer.TransferBridge, indexer.TransferModule:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be send, fee, ibc, bridge or module"})
+		return
+	}
+	keep := func(t indexer.Transfer) bool {
+		return (kind == "" || t.Kind == kind) && (direction == "" || transferDirection(address, t) == direction)
+	}
+
+	// one more than the page tells whether another page is left
+	transfers, err := indexStore.TransfersWhere(address, from, to, limit+1, keep)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	res := ledgerResponse{Address: address, Transfers: []ledgerEntry{}}
+	if len(transfers) > limit {
+		// pages end on a block boundary so from resumes without duplicates,
+		// a block with more transfers than the limit is returned whole
+		next := transfers[limit].Height
+		transfers = transfers[:limit]
+		for len(transfers) > 0 && transfers[len(transfers)-1].Height == next {
+			transfers = transfers[:len(transfers)-1]
+		}
+		if len(transfers) == 0 {
+			if transfers, err = indexStore.TransfersWhere(address, next, next, 0, keep); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			next++
+		}
+		if next <= to {
+			res.NextFrom = next
+		}
+	}
+	for _, t := range transfers {
+		res.Transfers = append(res.Transfers, newLedgerEntry(address, t))
+	}
+	respondRecords(c, res)
+}
+
+func transferDirection(address string, t indexer.Transfer) string {
+	switch {
+	case t.Sender == address && t.Recipient == address:
+		return DirectionSelf
+	case t.Sender == address:
+		return DirectionOut
+	default:
+		return DirectionIn
+	}
+}
+
+func newLedgerEntry(address string, t indexer.Transfer) ledgerEntry {
+	entry := ledgerEntry{Transfer: t, Direction: transferDirection(address, t), Counterparty: t.ExternalAddress}
+	if entry.Counterparty == "" {
+		entry.Counterparty = t.Sender
+		if entry.Direction == DirectionOut {
+			entry.Counterparty = t.Recipient
+		}
+	}
+	return entry
+}
+
+// respondRecords writes a response assembled by the service as JSON, YAML or
+// CSV, the other outputs only exist for single node queries.
+func respondRecords(c *gin.Context, res interface{}) {
+	output := web.OutputFrom(c)
+	if output == web.OutputJSON {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	if output != web.OutputYAML && output != web.OutputCSV {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "this route is only available as json, yaml or csv"})
+		return
+	}
+	bz, err := json.Marshal(res)
+	if err == nil && output == web.OutputYAML {
+		bz, err = web.JSONToYAML(bz)
+	} else if err == nil {
+		bz, err = web.JSONToCSV(bz)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	contentType := web.MIMEYAML
+	if output == web.OutputCSV {
+		contentType = web.MIMECSV
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", bz)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"pundix-homework/auth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAPIKeyStore    = "api_keys.json"
+	apiKeyStorePathEnvKey = "API_KEYS_PATH"
+	// bearer token for /admin, the admin routes are disabled without it
+	adminTokenEnvKey   = "ADMIN_TOKEN"
+	usageFlushInterval = 10 * time.Second
+)
+
+var apiKeys *auth.Store
+
+// openAPIKeys loads the key store, routes need it before the server starts.
+func openAPIKeys() {
+	path := os.Getenv(apiKeyStorePathEnvKey)
+	if path == "" {
+		path = defaultAPIKeyStore
+	}
+	store, err := auth.OpenStore(path)
+	if err != nil {
+		panic(err)
+	}
+	apiKeys = store
+}
+
+func CreateAPIKeyHandler(c *gin.Context) {
+	var key auth.Key
+	if err := c.ShouldBindJSON(&key); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := key.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := apiKeys.AddKey(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the only response that carries the key, only its hash is stored
+	key.Hash = ""
+	c.JSON(http.StatusCreated, key)
+}
+
+func ListAPIKeysHandler(c *gin.Context) {
+	keys := apiKeys.ListKeys()
+	for i := range keys {
+		keys[i] = keys[i].Public()
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+func GetAPIKeyHandler(c *gin.Context) {
+	key, err := apiKeys.Key(c.Param("id"))
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key.Public())
+}
+
+func DeleteAPIKeyHandler(c *gin.Context) {
+	if err := apiKeys.DeleteKey(c.Param("id")); err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func APIKeyUsageHandler(c *gin.Context) {
+	key, err := apiKeys.Key(c.Param("id"))
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	usage, err := apiKeys.Usage(key.ID)
+	if err != nil {
+		apiKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": key.ID, "daily_quota": key.DailyQuota, "usage": usage})
+}
+
+func apiKeyError(c *gin.Context, err error) {
+	if errors.Is(err, auth.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
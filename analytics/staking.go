@@ -0,0 +1,196 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+const (
+	bondDenom   = "FX"
+	daysPerYear = 365
+)
+
+// Mint is satisfied by *clients.MintQueryClient.
+type Mint interface {
+	AnnualProvisions(ctx context.Context) (*minttypes.QueryAnnualProvisionsResponse, error)
+}
+
+// Distribution is satisfied by *clients.DistributionQueryClient.
+type Distribution interface {
+	QueryParams(ctx context.Context) (*distrtypes.QueryParamsResponse, error)
+}
+
+// Staking is satisfied by *clients.StakingQueryClient.
+type Staking interface {
+	Validators(ctx context.Context, status string) ([]stakingtypes.Validator, error)
+	DelegatorDelegations(ctx context.Context, delegator string) ([]stakingtypes.DelegationResponse, error)
+	Pool(ctx context.Context) (*stakingtypes.QueryPoolResponse, error)
+}
+
+var ErrNoBondedTokens = errors.New("no bonded tokens, the APR is undefined")
+
+// ValidatorAPR is the APR of delegating to a bonded validator, the network
+// APR less its commission.
+type ValidatorAPR struct {
+	OperatorAddress string  `json:"operator_address"`
+	Moniker         string  `json:"moniker"`
+	CommissionRate  sdk.Dec `json:"commission_rate"`
+	APR             sdk.Dec `json:"apr"`
+}
+
+// APR is the yearly staking yield at the current inflation:
+// AnnualProvisions * (1 - CommunityTax) / BondedTokens. Fees and proposer
+// bonuses are left out.
+type APR struct {
+	AnnualProvisions sdk.Dec        `json:"annual_provisions"`
+	BondedTokens     sdk.Int        `json:"bonded_tokens"`
+	CommunityTax     sdk.Dec        `json:"community_tax"`
+	APR              sdk.Dec        `json:"apr"`
+	Validators       []ValidatorAPR `json:"validators"`
+}
+
+// DelegationProjection is the projected reward of one delegation, nothing
+// for a validator that isn't bonded.
+type DelegationProjection struct {
+	Validator       string      `json:"validator"`
+	Moniker         string      `json:"moniker"`
+	Balance         sdk.Coin    `json:"balance"`
+	APR             sdk.Dec     `json:"apr"`
+	DailyReward     sdk.DecCoin `json:"daily_reward"`
+	ProjectedReward sdk.DecCoin `json:"projected_reward"`
+}
+
+// Projection is what the delegations of a delegator earn over Days at the
+// current APR, without compounding.
+type Projection struct {
+	Delegator       string                 `json:"delegator"`
+	Days            int                    `json:"days"`
+	Delegations     []DelegationProjection `json:"delegations"`
+	DailyReward     sdk.DecCoin            `json:"daily_reward"`
+	ProjectedReward sdk.DecCoin            `json:"projected_reward"`
+}
+
+// Calculator derives staking yields from the mint, distribution and staking
+// state. Queries run at the height of their context.
+type Calculator struct {
+	mint    Mint
+	distr   Distribution
+	staking Staking
+}
+
+func NewCalculator(mint Mint, distr Distribution, staking Staking) *Calculator {
+	return &Calculator{mint: mint, distr: distr, staking: staking}
+}
+
+// APR returns the network APR and the APR of every bonded validator, highest
+// first.
+func (c *Calculator) APR(ctx context.Context) (*APR, error) {
+	apr, err := c.networkAPR(ctx)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := c.staking.Validators(ctx, stakingtypes.BondStatusBonded)
+	if err != nil {
+		return nil, err
+	}
+	apr.Validators = make([]ValidatorAPR, 0, len(validators))
+	for _, v := range validators {
+		apr.Validators = append(apr.Validators, ValidatorAPR{
+			OperatorAddress: v.OperatorAddress,
+			Moniker:         v.Description.Moniker,
+			CommissionRate:  v.Commission.Rate,
+			APR:             validatorAPR(apr.APR, v),
+		})
+	}
+	sort.SliceStable(apr.Validators, func(i, j int) bool { return apr.Validators[i].APR.GT(apr.Validators[j].APR) })
+	return apr, nil
+}
+
+// Projection projects the rewards of the delegations of delegator over days.
+func (c *Calculator) Projection(ctx context.Context, delegator string, days int) (*Projection, error) {
+	apr, err := c.networkAPR(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delegations, err := c.staking.DelegatorDelegations(ctx, delegator)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := c.staking.Validators(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	byOperator := make(map[string]stakingtypes.Validator, len(validators))
+	for _, v := range validators {
+		byOperator[v.OperatorAddress] = v
+	}
+
+	p := &Projection{
+		Delegator:       delegator,
+		Days:            days,
+		Delegations:     make([]DelegationProjection, 0, len(delegations)),
+		DailyReward:     sdk.NewDecCoin(bondDenom, sdk.ZeroInt()),
+		ProjectedReward: sdk.NewDecCoin(bondDenom, sdk.ZeroInt()),
+	}
+	for _, d := range delegations {
+		v := byOperator[d.Delegation.ValidatorAddress]
+		rate := validatorAPR(apr.APR, v)
+		daily := d.Balance.Amount.ToDec().Mul(rate).QuoInt64(daysPerYear)
+		projection := DelegationProjection{
+			Validator:       d.Delegation.ValidatorAddress,
+			Moniker:         v.Description.Moniker,
+			Balance:         d.Balance,
+			APR:             rate,
+			DailyReward:     sdk.NewDecCoinFromDec(d.Balance.Denom, daily),
+			ProjectedReward: sdk.NewDecCoinFromDec(d.Balance.Denom, daily.MulInt64(int64(days))),
+		}
+		p.Delegations = append(p.Delegations, projection)
+		if d.Balance.Denom != bondDenom {
+			continue
+		}
+		p.DailyReward = p.DailyReward.Add(projection.DailyReward)
+		p.ProjectedReward = p.ProjectedReward.Add(projection.ProjectedReward)
+	}
+	return p, nil
+}
+
+func (c *Calculator) networkAPR(ctx context.Context) (*APR, error) {
+	provisions, err := c.mint.AnnualProvisions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := c.staking.Pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := c.distr.QueryParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bonded := pool.Pool.BondedTokens
+	if !bonded.IsPositive() {
+		return nil, ErrNoBondedTokens
+	}
+	tax := params.Params.CommunityTax
+	return &APR{
+		AnnualProvisions: provisions.AnnualProvisions,
+		BondedTokens:     bonded,
+		CommunityTax:     tax,
+		APR:              provisions.AnnualProvisions.Mul(sdk.OneDec().Sub(tax)).QuoInt(bonded),
+	}, nil
+}
+
+// validatorAPR is the network APR less the commission, jailed and unbonded
+// validators earn nothing.
+func validatorAPR(network sdk.Dec, v stakingtypes.Validator) sdk.Dec {
+	if !v.IsBonded() || v.Jailed {
+		return sdk.ZeroDec()
+	}
+	return network.Mul(sdk.OneDec().Sub(v.Commission.Rate))
+}
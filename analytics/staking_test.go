@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	minttypes "github.com/cosmos/cosmos-sdk/x/mint/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	// fx address prefixes and denoms
+	_ "github.com/functionx/fx-core/app"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	lowCommission  = sdk.ValAddress("low_commission______").String()
+	highCommission = sdk.ValAddress("high_commission_____").String()
+	jailed         = sdk.ValAddress("jailed______________").String()
+	delegator      = sdk.AccAddress("delegator___________").String()
+)
+
+// fakeChain mints 100 a year over 1000 bonded tokens with a 2% community
+// tax, a 9.8% network APR.
+type fakeChain struct {
+	bonded sdk.Int
+}
+
+func (f fakeChain) AnnualProvisions(context.Context) (*minttypes.QueryAnnualProvisionsResponse, error) {
+	return &minttypes.QueryAnnualProvisionsResponse{AnnualProvisions: sdk.NewDec(100)}, nil
+}
+
+func (f fakeChain) QueryParams(context.Context) (*distrtypes.QueryParamsResponse, error) {
+	return &distrtypes.QueryParamsResponse{Params: distrtypes.Params{CommunityTax: sdk.NewDecWithPrec(2, 2)}}, nil
+}
+
+func (f fakeChain) Pool(context.Context) (*stakingtypes.QueryPoolResponse, error) {
+	return &stakingtypes.QueryPoolResponse{Pool: stakingtypes.NewPool(sdk.ZeroInt(), f.bonded)}, nil
+}
+
+func (f fakeChain) Validators(_ context.Context, status string) ([]stakingtypes.Validator, error) {
+	validators := []stakingtypes.Validator{
+		{OperatorAddress: highCommission, Status: stakingtypes.Bonded, Commission: stakingtypes.Commission{CommissionRates: stakingtypes.CommissionRates{Rate: sdk.NewDecWithPrec(5, 1)}}},
+		{OperatorAddress: lowCommission, Status: stakingtypes.Bonded, Commission: stakingtypes.Commission{CommissionRates: stakingtypes.CommissionRates{Rate: sdk.NewDecWithPrec(1, 1)}}},
+		{OperatorAddress: jailed, Status: stakingtypes.Unbonding, Jailed: true, Commission: stakingtypes.Commission{CommissionRates: stakingtypes.CommissionRates{Rate: sdk.ZeroDec()}}},
+	}
+	if status == stakingtypes.BondStatusBonded {
+		return validators[:2], nil
+	}
+	return validators, nil
+}
+
+func (f fakeChain) DelegatorDelegations(context.Context, string) ([]stakingtypes.DelegationResponse, error) {
+	return []stakingtypes.DelegationResponse{
+		{Delegation: stakingtypes.Delegation{ValidatorAddress: lowCommission}, Balance: sdk.NewInt64Coin("FX", 3650)},
+		{Delegation: stakingtypes.Delegation{ValidatorAddress: jailed}, Balance: sdk.NewInt64Coin("FX", 1000)},
+	}, nil
+}
+
+func Test_APR(t *testing.T) {
+	chain := fakeChain{bonded: sdk.NewInt(1000)}
+	apr, err := NewCalculator(chain, chain, chain).APR(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "0.098000000000000000", apr.APR.String())
+	require.Len(t, apr.Validators, 2)
+	// highest first
+	require.Equal(t, lowCommission, apr.Validators[0].OperatorAddress)
+	require.Equal(t, "0.088200000000000000", apr.Validators[0].APR.String())
+	require.Equal(t, "0.049000000000000000", apr.Validators[1].APR.String())
+
+	chain.bonded = sdk.ZeroInt()
+	_, err = NewCalculator(chain, chain, chain).APR(context.Background())
+	require.ErrorIs(t, err, ErrNoBondedTokens)
+}
+
+func Test_Projection(t *testing.T) {
+	chain := fakeChain{bonded: sdk.NewInt(1000)}
+	p, err := NewCalculator(chain, chain, chain).Projection(context.Background(), delegator, 30)
+	require.NoError(t, err)
+	require.Len(t, p.Delegations, 2)
+	// 3650 * 0.0882 / 365
+	require.Equal(t, "0.882000000000000000FX", p.Delegations[0].DailyReward.String())
+	require.Equal(t, "26.460000000000000000FX", p.Delegations[0].ProjectedReward.String())
+	require.True(t, p.Delegations[1].ProjectedReward.IsZero())
+	require.Equal(t, "26.460000000000000000FX", p.ProjectedReward.String())
+}
@@ -0,0 +1,282 @@
+package analytics
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	bsctypes "github.com/functionx/fx-core/x/bsc/types"
+	crosschaintypes "github.com/functionx/fx-core/x/crosschain/types"
+	erc20types "github.com/functionx/fx-core/x/erc20/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	polygontypes "github.com/functionx/fx-core/x/polygon/types"
+	trontypes "github.com/functionx/fx-core/x/tron/types"
+)
+
+const (
+	// ExcludedAddressesEnvKey lists, comma separated, accounts whose FX is not
+	// circulating, as foundation or team wallets.
+	ExcludedAddressesEnvKey = "SUPPLY_EXCLUDED_ADDRESSES"
+	// VestingAddressesEnvKey lists, comma separated, the vesting accounts.
+	// Without it they are found by walking every account of the chain.
+	VestingAddressesEnvKey = "SUPPLY_VESTING_ADDRESSES"
+
+	// vestingRefresh is how often the walk is made again, in the background
+	vestingRefresh       = 6 * time.Hour
+	vestingLookupTimeout = 5 * time.Minute
+)
+
+// Bank is satisfied by *clients.BankQueryClient.
+type Bank interface {
+	TotalSupply(ctx context.Context) (*banktypes.QuerySupplyOfResponse, error)
+	DenomBalance(ctx context.Context, address, denom string) (*banktypes.QueryBalanceResponse, error)
+}
+
+// CommunityPool is satisfied by *clients.DistributionQueryClient.
+type CommunityPool interface {
+	CommunityPool(ctx context.Context) (*distrtypes.QueryCommunityPoolResponse, error)
+}
+
+// Auth is satisfied by *clients.AuthQueryClient.
+type Auth interface {
+	VestingAddresses(ctx context.Context) ([]string, error)
+	VestingAccount(ctx context.Context, address string) (vestingexported.VestingAccount, error)
+}
+
+// supplyModules are the module accounts holding FX, bridge escrows back the
+// FX circulating on the other chains.
+var supplyModules = []struct {
+	name   string
+	bridge bool
+}{
+	{distrtypes.ModuleName, false},
+	{erc20types.ModuleName, false},
+	{gravitytypes.ModuleName, true},
+	{bsctypes.ModuleName, true},
+	{polygontypes.ModuleName, true},
+	{trontypes.ModuleName, true},
+	{crosschaintypes.ModuleName, true},
+}
+
+// AccountBalance is the FX of a module account or of an excluded address.
+type AccountBalance struct {
+	Name    string  `json:"name,omitempty"`
+	Address string  `json:"address"`
+	Amount  sdk.Int `json:"amount"`
+	Bridge  bool    `json:"bridge,omitempty"`
+}
+
+// Supply is a breakdown of the FX supply at a block, amounts are in the base
+// denom. Circulating is Total less CommunityPool, VestingLocked and the
+// Excluded addresses. Bridged, the sum of the bridge escrows, and the other
+// module balances stay circulating. The distribution module holds the
+// community pool and the rewards not yet withdrawn.
+type Supply struct {
+	Height        int64            `json:"height"`
+	Time          time.Time        `json:"time"`
+	Denom         string           `json:"denom"`
+	Total         sdk.Int          `json:"total"`
+	Bonded        sdk.Int          `json:"bonded"`
+	NotBonded     sdk.Int          `json:"not_bonded"`
+	CommunityPool sdk.Int          `json:"community_pool"`
+	VestingLocked sdk.Int          `json:"vesting_locked"`
+	Bridged       sdk.Int          `json:"bridged"`
+	Modules       []AccountBalance `json:"modules"`
+	Excluded      []AccountBalance `json:"excluded"`
+	Circulating   sdk.Int          `json:"circulating"`
+}
+
+// SupplyCalculator breaks the FX supply down from the bank, staking,
+// distribution and auth state. Queries run at the height of their context.
+type SupplyCalculator struct {
+	bank     Bank
+	staking  Staking
+	pool     CommunityPool
+	auth     Auth
+	excluded []string
+	vesting  []string
+
+	// the vesting addresses found on chain when none are configured
+	lookupMtx    sync.Mutex
+	mtx          sync.Mutex
+	discovered   []string
+	discoveredAt time.Time
+	refreshing   bool
+}
+
+// NewSupplyCalculator leaves excluded out of the circulating supply. vesting
+// lists the vesting accounts, nil finds them on chain.
+func NewSupplyCalculator(bank Bank, staking Staking, pool CommunityPool, auth Auth, excluded, vesting []string) *SupplyCalculator {
+	return &SupplyCalculator{bank: bank, staking: staking, pool: pool, auth: auth, excluded: excluded, vesting: vesting}
+}
+
+// ExcludedAddressesFromEnv reads SUPPLY_EXCLUDED_ADDRESSES and checks every
+// address.
+func ExcludedAddressesFromEnv() ([]string, error) {
+	return addressesFromEnv(ExcludedAddressesEnvKey)
+}
+
+// VestingAddressesFromEnv reads SUPPLY_VESTING_ADDRESSES and checks every
+// address, nil when it isn't set.
+func VestingAddressesFromEnv() ([]string, error) {
+	return addressesFromEnv(VestingAddressesEnvKey)
+}
+
+func addressesFromEnv(key string) ([]string, error) {
+	var addresses []string
+	for _, address := range strings.Split(os.Getenv(key), ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		if _, err := sdk.AccAddressFromBech32(address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// Supply breaks down the supply at the block of height, made at blockTime,
+// which tells how much of the vesting accounts is still locked.
+func (c *SupplyCalculator) Supply(ctx context.Context, height int64, blockTime time.Time) (*Supply, error) {
+	total, err := c.bank.TotalSupply(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := c.staking.Pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	communityPool, err := c.pool.CommunityPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vesting, err := c.vestingAddresses()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Supply{
+		Height:        height,
+		Time:          blockTime,
+		Denom:         bondDenom,
+		Total:         total.Amount.Amount,
+		Bonded:        pool.Pool.BondedTokens,
+		NotBonded:     pool.Pool.NotBondedTokens,
+		CommunityPool: communityPool.Pool.AmountOf(bondDenom).TruncateInt(),
+		VestingLocked: sdk.ZeroInt(),
+		Bridged:       sdk.ZeroInt(),
+		Modules:       make([]AccountBalance, 0, len(supplyModules)),
+		Excluded:      make([]AccountBalance, 0, len(c.excluded)),
+	}
+	locked := make(map[string]sdk.Int, len(vesting))
+	for _, address := range vesting {
+		account, err := c.auth.VestingAccount(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			// not created yet at this height
+			continue
+		}
+		amount := account.GetVestingCoins(blockTime).AmountOf(bondDenom)
+		locked[account.GetAddress().String()] = amount
+		s.VestingLocked = s.VestingLocked.Add(amount)
+	}
+	for _, module := range supplyModules {
+		address := authtypes.NewModuleAddress(module.name).String()
+		amount, err := c.balance(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		s.Modules = append(s.Modules, AccountBalance{Name: module.name, Address: address, Amount: amount, Bridge: module.bridge})
+		if module.bridge {
+			s.Bridged = s.Bridged.Add(amount)
+		}
+	}
+
+	s.Circulating = s.Total.Sub(s.CommunityPool).Sub(s.VestingLocked)
+	for _, address := range c.excluded {
+		amount, err := c.balance(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		s.Excluded = append(s.Excluded, AccountBalance{Address: address, Amount: amount})
+		// the locked part of an excluded vesting account is already out
+		if l, ok := locked[address]; ok {
+			amount = sdk.MaxInt(amount.Sub(l), sdk.ZeroInt())
+		}
+		s.Circulating = s.Circulating.Sub(amount)
+	}
+	return s, nil
+}
+
+// vestingAddresses returns the configured vesting accounts, or the ones found
+// by walking the accounts of the chain. Only the first walk is waited for,
+// later ones refresh the addresses in the background every vestingRefresh.
+func (c *SupplyCalculator) vestingAddresses() ([]string, error) {
+	if c.vesting != nil {
+		return c.vesting, nil
+	}
+	c.mtx.Lock()
+	addresses, discoveredAt := c.discovered, c.discoveredAt
+	stale := !discoveredAt.IsZero() && time.Since(discoveredAt) > vestingRefresh && !c.refreshing
+	if stale {
+		c.refreshing = true
+	}
+	c.mtx.Unlock()
+
+	if discoveredAt.IsZero() {
+		return c.lookupVesting()
+	}
+	if stale {
+		// a failed refresh keeps the previous addresses
+		go func() { _, _ = c.lookupVesting() }()
+	}
+	return addresses, nil
+}
+
+// lookupVesting walks the accounts at the latest block, one walk at a time.
+// Callers that waited for another walk take its result.
+func (c *SupplyCalculator) lookupVesting() ([]string, error) {
+	c.lookupMtx.Lock()
+	defer c.lookupMtx.Unlock()
+	c.mtx.Lock()
+	if !c.discoveredAt.IsZero() && time.Since(c.discoveredAt) <= vestingRefresh {
+		addresses := c.discovered
+		c.refreshing = false
+		c.mtx.Unlock()
+		return addresses, nil
+	}
+	c.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), vestingLookupTimeout)
+	defer cancel()
+	addresses, err := c.auth.VestingAddresses(ctx)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.refreshing = false
+	if err != nil {
+		return nil, err
+	}
+	c.discovered, c.discoveredAt = addresses, time.Now()
+	return addresses, nil
+}
+
+func (c *SupplyCalculator) balance(ctx context.Context, address string) (sdk.Int, error) {
+	res, err := c.bank.DenomBalance(ctx, address, bondDenom)
+	if err != nil {
+		return sdk.Int{}, err
+	}
+	if res.Balance == nil {
+		return sdk.ZeroInt(), nil
+	}
+	return res.Balance.Amount, nil
+}
@@ -0,0 +1,100 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	vestingtypes "github.com/cosmos/cosmos-sdk/x/auth/vesting/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	vesting    = sdk.AccAddress("vesting_____________")
+	foundation = sdk.AccAddress("foundation__________").String()
+	vestStart  = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// supplyChain has 10000 FX, a gravity escrow of 500, a community pool of 300
+// and an account vesting 1000 over 100 days.
+type supplyChain struct {
+	fakeChain
+	balances map[string]int64
+	walks    *int
+}
+
+func (f supplyChain) TotalSupply(context.Context) (*banktypes.QuerySupplyOfResponse, error) {
+	return &banktypes.QuerySupplyOfResponse{Amount: sdk.NewInt64Coin("FX", 10000)}, nil
+}
+
+func (f supplyChain) DenomBalance(_ context.Context, address, denom string) (*banktypes.QueryBalanceResponse, error) {
+	coin := sdk.NewInt64Coin(denom, f.balances[address])
+	return &banktypes.QueryBalanceResponse{Balance: &coin}, nil
+}
+
+func (f supplyChain) CommunityPool(context.Context) (*distrtypes.QueryCommunityPoolResponse, error) {
+	return &distrtypes.QueryCommunityPoolResponse{Pool: sdk.NewDecCoins(sdk.NewDecCoinFromDec("FX", sdk.MustNewDecFromStr("300.7")))}, nil
+}
+
+func (f supplyChain) VestingAddresses(context.Context) ([]string, error) {
+	*f.walks++
+	return []string{vesting.String()}, nil
+}
+
+func (f supplyChain) VestingAccount(_ context.Context, address string) (vestingexported.VestingAccount, error) {
+	if address != vesting.String() {
+		return nil, nil
+	}
+	return vestingtypes.NewContinuousVestingAccount(authtypes.NewBaseAccountWithAddress(vesting), sdk.NewCoins(sdk.NewInt64Coin("FX", 1000)),
+		vestStart.Unix(), vestStart.AddDate(0, 0, 100).Unix()), nil
+}
+
+func Test_Supply(t *testing.T) {
+	chain := supplyChain{fakeChain: fakeChain{bonded: sdk.NewInt(4000)}, balances: map[string]int64{
+		authtypes.NewModuleAddress(gravitytypes.ModuleName).String(): 500,
+		vesting.String(): 1000,
+		foundation:       2000,
+	}, walks: new(int)}
+
+	calc := NewSupplyCalculator(chain, chain, chain, chain, nil, nil)
+	s, err := calc.Supply(context.Background(), 7, vestStart.AddDate(0, 0, 25))
+	require.NoError(t, err)
+	require.Equal(t, int64(10000), s.Total.Int64())
+	require.Equal(t, int64(4000), s.Bonded.Int64())
+	require.Equal(t, int64(300), s.CommunityPool.Int64())
+	require.Equal(t, int64(750), s.VestingLocked.Int64())
+	require.Equal(t, int64(500), s.Bridged.Int64())
+	require.Len(t, s.Modules, len(supplyModules))
+	// bridged FX stays circulating
+	require.Equal(t, int64(10000-300-750), s.Circulating.Int64())
+
+	// the accounts are walked once, not per request
+	_, err = calc.Supply(context.Background(), 8, vestStart.AddDate(0, 0, 25))
+	require.NoError(t, err)
+	require.Equal(t, 1, *chain.walks)
+
+	// the locked part of an excluded vesting account isn't taken twice
+	s, err = NewSupplyCalculator(chain, chain, chain, chain, []string{foundation, vesting.String()}, []string{vesting.String(), foundation}).Supply(context.Background(), 7, vestStart.AddDate(0, 0, 25))
+	require.NoError(t, err)
+	require.Equal(t, 1, *chain.walks, "configured vesting accounts aren't looked up")
+	require.Equal(t, int64(750), s.VestingLocked.Int64())
+	require.Len(t, s.Excluded, 2)
+	require.Equal(t, int64(10000-300-750-2000-250), s.Circulating.Int64())
+}
+
+func Test_ExcludedAddressesFromEnv(t *testing.T) {
+	t.Setenv(ExcludedAddressesEnvKey, " "+foundation+", ,"+vesting.String())
+	addresses, err := ExcludedAddressesFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, []string{foundation, vesting.String()}, addresses)
+
+	t.Setenv(ExcludedAddressesEnvKey, "nope")
+	_, err = ExcludedAddressesFromEnv()
+	require.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"pundix-homework/analytics"
+	"pundix-homework/clients"
+	"strconv"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultProjectionDays = 365
+	maxProjectionDays     = 3650
+)
+
+var (
+	calculator       = analytics.NewCalculator(clients.MintQueryClientInstance, clients.DistrQueryClientInstance, clients.StakingQueryClientInstance)
+	supplyCalculator *analytics.SupplyCalculator
+)
+
+// setupSupply reads the addresses left out of the circulating supply and
+// the vesting accounts.
+func setupSupply() {
+	excluded, err := analytics.ExcludedAddressesFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", analytics.ExcludedAddressesEnvKey, err))
+	}
+	vesting, err := analytics.VestingAddressesFromEnv()
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", analytics.VestingAddressesEnvKey, err))
+	}
+	supplyCalculator = analytics.NewSupplyCalculator(clients.BankQueryClientInstance, clients.StakingQueryClientInstance,
+		clients.DistrQueryClientInstance, clients.AuthQueryClientInstance, excluded, vesting)
+}
+
+// StakingAPRHandler reports the network APR and the APR of every bonded
+// validator after commission.
+func StakingAPRHandler(c *gin.Context) {
+	ctx, err := queryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	apr, err := calculator.APR(ctx)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	respondRecords(c, apr)
+}
+
+// RewardProjectionHandler projects what the delegations of ?delegator= earn
+// over ?days= at the current APR.
+func RewardProjectionHandler(c *gin.Context) {
+	delegator := c.Query("delegator")
+	if _, err := sdk.AccAddressFromBech32(delegator); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delegator: " + err.Error()})
+		return
+	}
+	days := defaultProjectionDays
+	if s := c.Query("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxProjectionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxProjectionDays)})
+			return
+		}
+		days = n
+	}
+	ctx, err := queryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	projection, err := calculator.Projection(ctx, delegator, days)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+	respondRecords(c, projection)
+}
+
+// SupplyHandler breaks the FX supply down into bonded, community pool,
+// vesting, bridged and module balances next to the circulating supply.
+func SupplyHandler(c *gin.Context) {
+	supply, ok := supplyAt(c)
+	if !ok {
+		return
+	}
+	respondRecords(c, supply)
+}
+
+// CirculatingSupplyHandler answers the circulating supply alone as plain
+// text in display units, the way coin listing sites poll it.
+func CirculatingSupplyHandler(c *gin.Context) {
+	supply, ok := supplyAt(c)
+	if !ok {
+		return
+	}
+	amount := supply.Circulating.String()
+	if _, exponent, ok := clients.DenomRegistryInstance.DisplayUnit(c.Request.Context(), supply.Denom); ok && exponent > 0 {
+		amount = strings.TrimRight(strings.TrimRight(sdk.NewDecFromIntWithPrec(supply.Circulating, int64(exponent)).String(), "0"), ".")
+	}
+	c.String(http.StatusOK, amount)
+}
+
+// supplyAt breaks the supply down at ?height= or the latest block, the block
+// time tells how much of the vesting accounts is still locked.
+func supplyAt(c *gin.Context) (*analytics.Supply, bool) {
+	ctx, height, ok := dashboardHeight(c)
+	if !ok {
+		return nil, false
+	}
+	block, err := clients.RPCClientInstance.Block(ctx, &height)
+	if err != nil {
+		queryError(c, err)
+		return nil, false
+	}
+	supply, err := supplyCalculator.Supply(ctx, height, block.Block.Time)
+	if err != nil {
+		queryError(c, err)
+		return nil, false
+	}
+	return supply, true
+}
+
+// analyticsError answers a chain without bonded tokens with 503, it has no
+// APR to give yet.
+func analyticsError(c *gin.Context, err error) {
+	if errors.Is(err, analytics.ErrNoBondedTokens) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	queryError(c, err)
+}
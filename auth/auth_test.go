@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+	return store, path
+}
+
+func newTestEngine(store *Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	echo := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"query": c.Request.URL.RawQuery, "key": KeyID(c)})
+	}
+	engine.Group("/query", store.Middleware(Anonymous)).GET("/bank/balance", echo)
+	engine.Group("/query", store.Middleware(Anonymous)).GET("/bank/total", echo)
+	engine.Group("/watches", store.Middleware(KeyRequired)).GET("", echo)
+	return engine
+}
+
+func do(engine *gin.Engine, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v[0])
+	}
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func Test_MiddlewareAccess(t *testing.T) {
+	store, _ := newTestStore(t)
+	key := Key{Name: "partner", Routes: []string{"/query/bank/balance", "/watches"}}
+	require.NoError(t, key.Validate())
+	require.NoError(t, store.AddKey(key))
+	engine := newTestEngine(store)
+	withKey := http.Header{HeaderName: {key.Secret}}
+
+	require.Equal(t, http.StatusOK, do(engine, "/query/bank/total", nil).Code)
+	require.Equal(t, http.StatusUnauthorized, do(engine, "/watches", nil).Code)
+	require.Equal(t, http.StatusUnauthorized, do(engine, "/query/bank/total", http.Header{HeaderName: {"pdx_wrong"}}).Code)
+	require.Equal(t, http.StatusOK, do(engine, "/watches", withKey).Code)
+	require.Equal(t, http.StatusForbidden, do(engine, "/query/bank/total", withKey).Code)
+
+	// the query param authenticates and is stripped before the handler
+	w := do(engine, "/query/bank/balance?address=fx1&api_key="+key.Secret, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"query":"address=fx1","key":"`+key.ID+`"}`, w.Body.String())
+
+	usage, err := store.Usage(key.ID)
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	require.Equal(t, int64(2), usage[0].Requests)
+	require.Equal(t, int64(1), usage[0].Routes["/query/bank/balance"])
+}
+
+func Test_DailyQuota(t *testing.T) {
+	store, path := newTestStore(t)
+	key := Key{Name: "partner", DailyQuota: 2}
+	require.NoError(t, key.Validate())
+	require.NoError(t, store.AddKey(key))
+	engine := newTestEngine(store)
+	withKey := http.Header{HeaderName: {key.Secret}}
+
+	require.Equal(t, http.StatusOK, do(engine, "/query/bank/total", withKey).Code)
+	require.Equal(t, http.StatusOK, do(engine, "/query/bank/total", withKey).Code)
+	w := do(engine, "/query/bank/total", withKey)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// usage and keys survive a restart, the secret is never written
+	require.NoError(t, store.Flush())
+	bz, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(bz), key.Secret)
+	reopened, err := OpenStore(path)
+	require.NoError(t, err)
+	usage, err := reopened.Usage(key.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), usage[0].Requests)
+	require.Equal(t, int64(1), usage[0].Rejected)
+	require.False(t, reopened.Consume(key, "/query/bank/total", time.Now()))
+	_, ok := reopened.Authenticate(key.Secret)
+	require.True(t, ok)
+}
+
+func Test_FailedFlushKeepsUsage(t *testing.T) {
+	store, path := newTestStore(t)
+	key := Key{Name: "partner"}
+	require.NoError(t, key.Validate())
+	require.NoError(t, store.AddKey(key))
+	require.True(t, store.Consume(key, "/query/bank/total", time.Now()))
+
+	// the file can't be replaced, the counters wait for the next flush
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.MkdirAll(filepath.Join(path, "sub"), 0o700))
+	require.Error(t, store.Flush())
+	require.NoError(t, os.RemoveAll(path))
+	require.NoError(t, store.Flush())
+
+	reopened, err := OpenStore(path)
+	require.NoError(t, err)
+	usage, err := reopened.Usage(key.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), usage[0].Requests)
+}
+
+func Test_KeyAllows(t *testing.T) {
+	key := Key{Routes: []string{"/query/*", "/tx/build/:type"}}
+	require.True(t, key.Allows("/query/bank/total"))
+	require.True(t, key.Allows("/tx/build/:type"))
+	require.False(t, key.Allows("/watches"))
+	require.True(t, Key{}.Allows("/watches"))
+}
+
+func Test_Admin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	disabled := gin.New()
+	disabled.GET("/admin/keys", Admin(""), ok)
+	require.Equal(t, http.StatusNotFound, do(disabled, "/admin/keys", http.Header{"Authorization": {"Bearer "}}).Code)
+
+	engine := gin.New()
+	engine.GET("/admin/keys", Admin("s3cret"), ok)
+	require.Equal(t, http.StatusUnauthorized, do(engine, "/admin/keys", http.Header{"Authorization": {"Bearer nope"}}).Code)
+	require.Equal(t, http.StatusOK, do(engine, "/admin/keys", http.Header{"Authorization": {"Bearer s3cret"}}).Code)
+}
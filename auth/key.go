@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"pundix-homework/random"
+	"strings"
+	"time"
+)
+
+const keyPrefix = "pdx_"
+
+// Key is an API key issued to a partner. Only the hash of the secret is kept,
+// the secret itself is returned once when the key is created.
+type Key struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Hash string `json:"hash,omitempty"`
+	// Routes lists the route templates the key may call, a trailing /*
+	// matches every route below the prefix. Empty allows every route.
+	Routes []string `json:"routes,omitempty"`
+	// DailyQuota is the number of requests per UTC day, 0 is unlimited.
+	DailyQuota int64     `json:"daily_quota,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Secret     string    `json:"key,omitempty"`
+}
+
+// Validate checks the user supplied fields and fills ID, Secret, Hash and
+// CreatedAt.
+func (k *Key) Validate() error {
+	if strings.TrimSpace(k.Name) == "" {
+		return errors.New("name empty")
+	}
+	if k.DailyQuota < 0 {
+		return errors.New("daily_quota must be positive")
+	}
+	for _, route := range k.Routes {
+		if !strings.HasPrefix(route, "/") {
+			return fmt.Errorf("invalid route %q", route)
+		}
+	}
+
+	k.ID = random.Hex(8)
+	k.Secret = keyPrefix + random.Hex(24)
+	k.Hash = HashSecret(k.Secret)
+	k.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Public hides the secret and its hash.
+func (k Key) Public() Key {
+	k.Secret = ""
+	k.Hash = ""
+	return k
+}
+
+// Allows reports whether the key may call route, a gin route template.
+func (k Key) Allows(route string) bool {
+	if len(k.Routes) == 0 {
+		return true
+	}
+	for _, pattern := range k.Routes {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(route, prefix) {
+				return true
+			}
+		} else if route == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// HashSecret is how secrets are stored at rest, keys put in the store file
+// by hand need their hash computed the same way.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"pundix-homework/logging"
+	"pundix-homework/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderName = "X-API-Key"
+	QueryParam = "api_key"
+
+	keyContextKey = "api_key"
+)
+
+// Access decides whether a route group can be called without a key.
+type Access int
+
+const (
+	Anonymous Access = iota
+	KeyRequired
+)
+
+// Middleware authenticates the key sent in the X-API-Key header or the
+// api_key query parameter, then enforces its route allowlist and daily
+// quota. Without a key the request only passes under Anonymous access, a
+// wrong key is always rejected. The query parameter is removed so it never
+// reaches caches or logs.
+func (s *Store) Middleware(access Access) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(HeaderName)
+		if query := c.Request.URL.Query(); query.Has(QueryParam) {
+			if secret == "" {
+				secret = query.Get(QueryParam)
+			}
+			query.Del(QueryParam)
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		if secret == "" {
+			if access == Anonymous {
+				c.Next()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key required"})
+			return
+		}
+
+		key, ok := s.Authenticate(secret)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			return
+		}
+		ctx := c.Request.Context()
+		logging.AddField(ctx, "api_key", key.ID)
+		tracing.SpanFromContext(ctx).SetAttr("api_key.id", key.ID)
+		c.Set(keyContextKey, key)
+
+		route := c.FullPath()
+		if !key.Allows(route) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api key not allowed on " + route})
+			return
+		}
+		if s.Charge(c, key, route) {
+			c.Next()
+		}
+	}
+}
+
+// Charge counts a request of key to each of routes against its daily quota,
+// all of them or none. When they don't fit it answers 429 and returns false.
+func (s *Store) Charge(c *gin.Context, key Key, routes ...string) bool {
+	now := time.Now()
+	if !s.ConsumeRoutes(key, routes, now) {
+		c.Header("Retry-After", strconv.Itoa(int(untilNextDay(now).Seconds())+1))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "daily quota exceeded"})
+		return false
+	}
+	return true
+}
+
+// KeyFrom returns the key that authenticated the request, false for
+// anonymous requests.
+func KeyFrom(c *gin.Context) (Key, bool) {
+	key, ok := c.Get(keyContextKey)
+	if !ok {
+		return Key{}, false
+	}
+	return key.(Key), true
+}
+
+// KeyID returns the id of the key that authenticated the request, empty for
+// anonymous requests.
+func KeyID(c *gin.Context) string {
+	key, _ := KeyFrom(c)
+	return key.ID
+}
+
+// Admin guards the key management routes with a bearer token, the routes
+// answer 404 when no token is configured.
+func Admin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func untilNextDay(now time.Time) time.Duration {
+	now = now.UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"pundix-homework/jsonfile"
+	"pundix-homework/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	dayFormat          = "2006-01-02"
+	usageRetentionDays = 31
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Usage counts the requests of one key during one UTC day.
+type Usage struct {
+	Day      string           `json:"day"`
+	Requests int64            `json:"requests"`
+	Rejected int64            `json:"rejected"`
+	Routes   map[string]int64 `json:"routes"`
+}
+
+// Store keeps the keys and their daily usage in a JSON file. Key changes are
+// written immediately, usage counters every flush so requests never wait on
+// the disk.
+type Store struct {
+	path string
+
+	mtx    sync.RWMutex
+	Keys   map[string]Key               `json:"keys"`
+	Usages map[string]map[string]*Usage `json:"usage"`
+	byHash map[string]string
+	dirty  bool
+}
+
+// OpenStore loads the keys and their usage kept at path.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		Keys:   make(map[string]Key),
+		Usages: make(map[string]map[string]*Usage),
+	}
+	if err := jsonfile.Load(path, s); err != nil {
+		return nil, err
+	}
+	if s.Usages == nil {
+		s.Usages = make(map[string]map[string]*Usage)
+	}
+	s.byHash = make(map[string]string, len(s.Keys))
+	for id, key := range s.Keys {
+		s.byHash[key.Hash] = id
+	}
+	return s, nil
+}
+
+func (s *Store) AddKey(key Key) error {
+	key.Secret = ""
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.Keys[key.ID] = key
+	s.byHash[key.Hash] = key.ID
+	return s.save()
+}
+
+func (s *Store) DeleteKey(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	key, ok := s.Keys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.Keys, id)
+	delete(s.byHash, key.Hash)
+	delete(s.Usages, id)
+	return s.save()
+}
+
+func (s *Store) Key(id string) (Key, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	key, ok := s.Keys[id]
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+	return key, nil
+}
+
+// ListKeys returns the keys ordered by creation time.
+func (s *Store) ListKeys() []Key {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	keys := make([]Key, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Authenticate returns the key whose secret is secret.
+func (s *Store) Authenticate(secret string) (Key, bool) {
+	hash := HashSecret(secret)
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	id, ok := s.byHash[hash]
+	if !ok {
+		return Key{}, false
+	}
+	return s.Keys[id], true
+}
+
+// Consume counts a request of key to route and reports whether it fits in
+// the daily quota. Rejected requests are counted but don't use the quota.
+func (s *Store) Consume(key Key, route string, now time.Time) bool {
+	return s.ConsumeRoutes(key, []string{route}, now)
+}
+
+// ConsumeRoutes counts one request of key per route, as the queries of a
+// batch, and reports whether all of them fit in the daily quota.
+func (s *Store) ConsumeRoutes(key Key, routes []string, now time.Time) bool {
+	day := now.UTC().Format(dayFormat)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	days, ok := s.Usages[key.ID]
+	if !ok {
+		days = make(map[string]*Usage)
+		s.Usages[key.ID] = days
+	}
+	usage, ok := days[day]
+	if !ok {
+		usage = &Usage{Day: day, Routes: make(map[string]int64)}
+		days[day] = usage
+	}
+	s.dirty = true
+
+	n := int64(len(routes))
+	if key.DailyQuota > 0 && usage.Requests+n > key.DailyQuota {
+		usage.Rejected += n
+		return false
+	}
+	usage.Requests += n
+	for _, route := range routes {
+		usage.Routes[route]++
+	}
+	return true
+}
+
+// Usage returns the daily usage of key id, most recent day first.
+func (s *Store) Usage(id string) ([]Usage, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if _, ok := s.Keys[id]; !ok {
+		return nil, ErrNotFound
+	}
+	usages := make([]Usage, 0, len(s.Usages[id]))
+	for _, usage := range s.Usages[id] {
+		u := *usage
+		u.Routes = make(map[string]int64, len(usage.Routes))
+		for route, n := range usage.Routes {
+			u.Routes[route] = n
+		}
+		usages = append(usages, u)
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Day > usages[j].Day })
+	return usages, nil
+}
+
+// Flush persists the usage counters if they changed and forgets days older
+// than the retention window.
+func (s *Store) Flush() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	oldest := time.Now().UTC().AddDate(0, 0, -usageRetentionDays).Format(dayFormat)
+	for _, days := range s.Usages {
+		for day := range days {
+			if day < oldest {
+				delete(days, day)
+			}
+		}
+	}
+	return s.save()
+}
+
+// Run flushes the usage counters every interval and once more when ctx is
+// done.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				logging.L().Error().Err(err).Msg("flush api key usage")
+			}
+			return
+		}
+		if err := s.Flush(); err != nil {
+			logging.L().Error().Err(err).Msg("flush api key usage")
+		}
+	}
+}
+
+// save persists the keys and usage, the counters stay dirty until they are
+// written. Callers hold the lock.
+func (s *Store) save() error {
+	// keys are credentials, keep the file private
+	if err := jsonfile.Save(s.path, s, 0o600); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
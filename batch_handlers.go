@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"pundix-homework/auth"
+	"pundix-homework/clients"
+	"pundix-homework/ratelimit"
+	"pundix-homework/verify"
+	"pundix-homework/web"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tendermint/tendermint/libs/math"
+)
+
+const (
+	// the most sub-queries of one batch with an api key and without one, a
+	// batch also never exceeds the burst of its rate limit
+	batchMaxQueriesEnvKey           = "BATCH_MAX_QUERIES"
+	batchMaxAnonymousQueriesEnvKey  = "BATCH_MAX_ANONYMOUS_QUERIES"
+	defaultBatchMaxQueries          = 5000
+	defaultBatchMaxAnonymousQueries = 10
+	// batchParallel is how many sub-queries of one batch run at once,
+	// whatever the size of the batch.
+	batchParallel = 8
+)
+
+// maxBatchQueries and maxAnonymousBatchQueries bound the sub-queries of one
+// batch.
+var (
+	maxBatchQueries          = defaultBatchMaxQueries
+	maxAnonymousBatchQueries = defaultBatchMaxAnonymousQueries
+)
+
+// batchRequest is the body of POST /query/batch. Every query runs at Height,
+// the height of the snapshot token or the latest block when it's 0. Verify
+// answers every query from proven store reads.
+type batchRequest struct {
+	Height  int64        `json:"height"`
+	Verify  bool         `json:"verify"`
+	Queries []batchQuery `json:"queries"`
+}
+
+// batchQuery is one of the GET /query routes, as "bank/balance" or
+// "/query/bank/balance", and its query params.
+type batchQuery struct {
+	Route  string            `json:"route"`
+	Params map[string]string `json:"params"`
+}
+
+// batchResult holds the response of one query or its error, Status is the
+// code the matching GET request would have answered with.
+type batchResult struct {
+	Route    string          `json:"route"`
+	Status   int             `json:"status"`
+	Verified bool            `json:"verified,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Height  int64         `json:"height"`
+	Results []batchResult `json:"results"`
+}
+
+// QueryBatchHandler runs the queries of a batch. Each of them is checked
+// against the route allowlist of the key and counts against the rate limit
+// and the daily quota like the GET request would. Results are encoded in the
+// negotiated JSON output, as the GET responses.
+func QueryBatchHandler(c *gin.Context) {
+	output := web.OutputFrom(c)
+	if output != web.OutputJSON && output != web.OutputProtoJSON && output != web.OutputAmino {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "batch responses are only available as json, protojson or amino"})
+		return
+	}
+
+	var req batchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, keyed := auth.KeyFrom(c)
+	limit, limiter := maxAnonymousBatchQueries, ipLimiter
+	if keyed {
+		limit, limiter = maxBatchQueries, keyLimiter
+	}
+	if err := req.validate(math.MinInt(limit, limiter.Burst())); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]batchResult, len(req.Queries))
+	var routes []string
+	for i, q := range req.Queries {
+		route := "/query/" + batchRoute(q.Route)
+		if keyed && !key.Allows(route) {
+			results[i] = batchResult{Route: batchRoute(q.Route), Status: http.StatusForbidden, Error: "api key not allowed on " + route}
+			continue
+		}
+		routes = append(routes, route)
+	}
+	// the request itself took the token of the first query, the quota counts
+	// the batch and every query by its route
+	if len(routes) > 1 && !ratelimit.Charge(c, ipLimiter, keyLimiter, auth.KeyID, len(routes)-1) {
+		return
+	}
+	if keyed && !apiKeys.Charge(c, key, routes...) {
+		return
+	}
+
+	// pin the batch to one block so the results are consistent with each other
+	height := req.Height
+	if pinned := c.Query("height"); pinned != "" {
+		// set from a snapshot token
+		h, err := strconv.ParseInt(pinned, 10, 64)
+		if err != nil || h < 0 || height != 0 && height != h {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "height conflicts with the snapshot"})
+			return
+		}
+		height = h
+	}
+	if height == 0 {
+		latest, err := clients.LatestHeight(c.Request.Context())
+		if err != nil {
+			queryError(c, err)
+			return
+		}
+		height = latest
+	}
+	ctx := clients.WithHeight(c.Request.Context(), height)
+
+	sem := make(chan struct{}, batchParallel)
+	var wg sync.WaitGroup
+	for i, q := range req.Queries {
+		if results[i].Status != 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, q batchQuery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = runBatchQuery(ctx, q, req.Verify, output)
+		}(i, q)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, batchResponse{Height: height, Results: results})
+}
+
+func (req batchRequest) validate(maxQueries int) error {
+	if req.Height < 0 {
+		return errors.New("height must be a positive integer")
+	}
+	if len(req.Queries) == 0 {
+		return errors.New("queries empty")
+	}
+	if len(req.Queries) > maxQueries {
+		return fmt.Errorf("at most %d queries per batch", maxQueries)
+	}
+	return nil
+}
+
+// batchRoute strips the /query prefix and slashes off a route.
+func batchRoute(route string) string {
+	route = strings.Trim(route, "/")
+	return strings.TrimPrefix(route, "query/")
+}
+
+// runBatchQuery runs one query of a batch and encodes it in output, one of
+// the JSON outputs. Its errors are answered the way queryError answers them.
+func runBatchQuery(ctx context.Context, q batchQuery, verified bool, output web.Output) batchResult {
+	route := batchRoute(q.Route)
+	res := batchResult{Route: route}
+	query, ok := queryRoutes[route]
+	if !ok {
+		res.Status = http.StatusNotFound
+		res.Error = fmt.Sprintf("unknown route %s", q.Route)
+		return res
+	}
+	if verified {
+		if query, ok = verifiedQueries[route]; !ok {
+			res.Status = http.StatusBadRequest
+			res.Error = fmt.Sprintf("route %s can't be verified", q.Route)
+			return res
+		}
+	}
+
+	msg, err := query(ctx, batchParams(q.Params))
+	if err != nil {
+		switch {
+		case errors.Is(err, clients.ErrUpstreamBusy):
+			res.Status = http.StatusTooManyRequests
+		case errors.Is(err, verify.ErrVerification):
+			res.Status = http.StatusBadGateway
+		case errors.Is(err, verify.ErrDisabled):
+			res.Status = http.StatusNotImplemented
+		default:
+			res.Status = http.StatusBadRequest
+		}
+		res.Error = err.Error()
+		return res
+	}
+	bz, err := marshalJSON(output, msg)
+	if err != nil {
+		res.Status = http.StatusInternalServerError
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = http.StatusOK
+	res.Verified = verified
+	res.Result = bz
+	return res
+}
+
+func batchParams(params map[string]string) url.Values {
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return values
+}
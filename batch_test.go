@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"pundix-homework/auth"
+	"pundix-homework/ratelimit"
+	"pundix-homework/web"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_BatchQueryErrors(t *testing.T) {
+	require.Equal(t, "bank/balance", batchRoute("/query/bank/balance"))
+	require.Equal(t, "bank/balance", batchRoute("bank/balance/"))
+
+	res := runBatchQuery(context.Background(), batchQuery{Route: "/query/staking/pool"}, false, web.OutputJSON)
+	require.Equal(t, http.StatusNotFound, res.Status)
+
+	// rejected before the node is asked
+	res = runBatchQuery(context.Background(), batchQuery{Route: "distribution/validatorCommission"}, false, web.OutputJSON)
+	require.Equal(t, http.StatusBadRequest, res.Status)
+	require.Equal(t, "validator empty", res.Error)
+
+	// params live outside the stores the verified client reads
+	res = runBatchQuery(context.Background(), batchQuery{Route: "distribution/queryParams"}, true, web.OutputJSON)
+	require.Equal(t, http.StatusBadRequest, res.Status)
+	// refused without a trusted header
+	res = runBatchQuery(context.Background(), batchQuery{Route: "bank/total"}, true, web.OutputJSON)
+	require.Equal(t, http.StatusNotImplemented, res.Status)
+	require.False(t, res.Verified)
+
+	require.Error(t, batchRequest{}.validate(10))
+	require.Error(t, batchRequest{Height: -1, Queries: []batchQuery{{}}}.validate(10))
+	require.Error(t, batchRequest{Queries: make([]batchQuery, 11)}.validate(10))
+	require.NoError(t, batchRequest{Queries: []batchQuery{{Route: "bank/total"}}}.validate(10))
+}
+
+func Test_BatchChargesEachQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := auth.OpenStore(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err)
+	key := auth.Key{Name: "partner", Routes: []string{"/query/batch", "/query/distribution/*"}, DailyQuota: 9}
+	require.NoError(t, key.Validate())
+	require.NoError(t, store.AddKey(key))
+	savedKeys, savedIP, savedKey := apiKeys, ipLimiter, keyLimiter
+	defer func() { apiKeys, ipLimiter, keyLimiter = savedKeys, savedIP, savedKey }()
+	apiKeys, ipLimiter, keyLimiter = store, ratelimit.NewLimiter(0.001, 20), ratelimit.NewLimiter(0.001, 5)
+
+	r := gin.New()
+	r.POST("/query/batch", append(guard(auth.Anonymous), web.Negotiate(), QueryBatchHandler)...)
+	post := func(secret string, routes ...string) *httptest.ResponseRecorder {
+		req := batchRequest{Height: 1}
+		for _, route := range routes {
+			req.Queries = append(req.Queries, batchQuery{Route: route})
+		}
+		bz, err := json.Marshal(req)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		httpReq := httptest.NewRequest(http.MethodPost, "/query/batch", strings.NewReader(string(bz)))
+		if secret != "" {
+			httpReq.Header.Set(auth.HeaderName, secret)
+		}
+		r.ServeHTTP(w, httpReq)
+		return w
+	}
+	commission := "distribution/validatorCommission"
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/query/batch?output=yaml", strings.NewReader(`{"queries":[{"route":"bank/total"}]}`)))
+	require.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	// anonymous batches are capped lower, keyed ones by the burst of their bucket
+	require.Equal(t, http.StatusBadRequest, post("", make([]string, maxAnonymousBatchQueries+1)...).Code)
+	require.Equal(t, http.StatusBadRequest, post(key.Secret, commission, commission, commission, commission, commission, commission).Code)
+	keyLimiter = ratelimit.NewLimiter(0.001, 5)
+
+	// routes outside the allowlist are refused one by one and not charged
+	w = post(key.Secret, commission, "bank/total", commission)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res batchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, http.StatusBadRequest, res.Results[0].Status)
+	require.Equal(t, http.StatusForbidden, res.Results[1].Status)
+	require.Equal(t, http.StatusBadRequest, res.Results[2].Status)
+	usage, err := store.Usage(key.ID)
+	require.NoError(t, err)
+	// the refused batch counted too
+	require.Equal(t, int64(4), usage[0].Requests)
+	require.Equal(t, int64(2), usage[0].Routes["/query/"+commission])
+
+	// 2 of the 5 tokens are gone, a batch of 3 takes the rest
+	require.Equal(t, http.StatusOK, post(key.Secret, commission, commission, commission).Code)
+	require.Equal(t, http.StatusTooManyRequests, post(key.Secret, commission).Code)
+
+	// 9 requests fit in the quota, 4 + 4 + 1 used, a fresh bucket doesn't help
+	keyLimiter = ratelimit.NewLimiter(0.001, 5)
+	w = post(key.Secret, commission)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Contains(t, w.Body.String(), "daily quota exceeded")
+}
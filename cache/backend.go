@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Backend stores serialized entries. Replicas pointing at the same redis
+// reuse each other's results, a disk backend is locked by one process.
+type Backend interface {
+	// Get returns ok=false for missing and expired keys.
+	Get(key string) (value []byte, ok bool, err error)
+	// Set stores value, a ttl of 0 never expires.
+	Set(key string, value []byte, ttl time.Duration) error
+	Close() error
+}
+
+// OpenBackend builds the backend described by rawURL:
+//
+//	memory://?size=4096
+//	disk:///var/lib/pundix/cache.db
+//	redis://:password@localhost:6379/0
+func OpenBackend(rawURL string) (Backend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "", "memory":
+		size := defaultMemorySize
+		if s := u.Query().Get("size"); s != "" {
+			if _, err := fmt.Sscanf(s, "%d", &size); err != nil {
+				return nil, fmt.Errorf("invalid memory cache size %q", s)
+			}
+		}
+		return NewMemoryBackend(size)
+	case "disk":
+		return OpenBoltBackend(u.Path)
+	case "redis":
+		return NewRedisBackend(u)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %s", u.Scheme)
+	}
+}
+
+const defaultMemorySize = 4096
+
+type memoryItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryBackend is an in-process LRU, nothing is shared between replicas.
+type MemoryBackend struct {
+	lru *lru.Cache
+}
+
+func NewMemoryBackend(size int) (*MemoryBackend, error) {
+	l, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryBackend{lru: l}, nil
+}
+
+func (m *MemoryBackend) Get(key string) ([]byte, bool, error) {
+	v, ok := m.lru.Get(key)
+	if !ok {
+		return nil, false, nil
+	}
+	item := v.(memoryItem)
+	if !item.expiresAt.IsZero() && time.Now().After(item.expiresAt) {
+		m.lru.Remove(key)
+		return nil, false, nil
+	}
+	return item.value, true, nil
+}
+
+func (m *MemoryBackend) Set(key string, value []byte, ttl time.Duration) error {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = time.Now().Add(ttl)
+	}
+	m.lru.Add(key, item)
+	return nil
+}
+
+func (m *MemoryBackend) Close() error {
+	m.lru.Purge()
+	return nil
+}
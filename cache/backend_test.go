@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// fakeRedis is a tiny RESP server supporting the commands RedisBackend uses.
+type fakeRedis struct {
+	listener net.Listener
+	mtx      sync.Mutex
+	values   map[string]string
+	expiry   map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{listener: l, values: make(map[string]string), expiry: make(map[string]time.Time)}
+	go f.serve()
+	t.Cleanup(func() { l.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		io.WriteString(conn, f.exec(args))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		bz := make([]byte, size+2)
+		if _, err = io.ReadFull(r, bz); err != nil {
+			return nil, err
+		}
+		args[i] = string(bz[:size])
+	}
+	return args, nil
+}
+
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if line[0] != prefix {
+		return 0, fmt.Errorf("unexpected %q", line)
+	}
+	return strconv.Atoi(strings.TrimSpace(line[1:]))
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		f.values[args[1]] = args[2]
+		delete(f.expiry, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			f.expiry[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.values[args[1]]
+		if exp, has := f.expiry[args[1]]; !ok || (has && time.Now().After(exp)) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func testBackend(t *testing.T, backend Backend) {
+	_, ok, err := backend.Get("missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, backend.Set("k", []byte("v\r\nwith newline"), 0))
+	v, ok, err := backend.Get("k")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "v\r\nwith newline", string(v))
+
+	require.NoError(t, backend.Set("empty", []byte{}, 0))
+	v, ok, err = backend.Get("empty")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Empty(t, v)
+
+	require.NoError(t, backend.Set("short", []byte("x"), 20*time.Millisecond))
+	time.Sleep(40 * time.Millisecond)
+	_, ok, err = backend.Get("short")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func Test_MemoryBackend(t *testing.T) {
+	backend, err := OpenBackend("memory://?size=8")
+	require.NoError(t, err)
+	testBackend(t, backend)
+}
+
+func Test_BoltBackendSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	backend, err := OpenBackend("disk://" + path)
+	require.NoError(t, err)
+	testBackend(t, backend)
+	require.NoError(t, backend.Close())
+
+	reopened, err := OpenBoltBackend(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	v, ok, err := reopened.Get("k")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "v\r\nwith newline", string(v))
+}
+
+func Test_BoltBackendDeletesExpired(t *testing.T) {
+	backend, err := OpenBoltBackend(filepath.Join(t.TempDir(), "cache.db"))
+	require.NoError(t, err)
+	defer backend.Close()
+	// neighbours expire together, Next used to skip every other one
+	for i := 0; i < 10; i++ {
+		require.NoError(t, backend.Set(fmt.Sprintf("expired-%d", i), []byte("v"), time.Millisecond))
+	}
+	require.NoError(t, backend.Set("kept", []byte("v"), 0))
+
+	require.NoError(t, backend.deleteExpired(time.Now().Add(time.Second)))
+	var keys []string
+	require.NoError(t, backend.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	}))
+	require.Equal(t, []string{"kept"}, keys)
+}
+
+func Test_RedisBackend(t *testing.T) {
+	server := newFakeRedis(t)
+	backend, err := OpenBackend("redis://:secret@" + server.listener.Addr().String() + "/2")
+	require.NoError(t, err)
+	defer backend.Close()
+	testBackend(t, backend)
+
+	// replicas sharing the server see each other's entries
+	u, _ := url.Parse("redis://" + server.listener.Addr().String())
+	other, err := NewRedisBackend(u)
+	require.NoError(t, err)
+	_, ok, err := other.Get("k")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
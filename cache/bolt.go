@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltSweepInterval = time.Minute
+
+var boltBucket = []byte("cache")
+
+// BoltBackend keeps entries in a bbolt file so a restarted process starts
+// warm. Values are prefixed with their expiry in unix nanoseconds.
+type BoltBackend struct {
+	db   *bolt.DB
+	done chan struct{}
+}
+
+func OpenBoltBackend(path string) (*BoltBackend, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	b := &BoltBackend{db: db, done: make(chan struct{})}
+	go b.sweep()
+	return b, nil
+}
+
+func (b *BoltBackend) Get(key string) ([]byte, bool, error) {
+	var value []byte
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltBucket).Get([]byte(key))
+		if raw == nil || isExpired(raw, time.Now()) {
+			return nil
+		}
+		// raw is only valid inside the transaction, an empty value is found too
+		value, found = append([]byte{}, raw[8:]...), true
+		return nil
+	})
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (b *BoltBackend) Set(key string, value []byte, ttl time.Duration) error {
+	raw := make([]byte, 8+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(raw, uint64(time.Now().Add(ttl).UnixNano()))
+	}
+	copy(raw[8:], value)
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), raw)
+	})
+}
+
+func (b *BoltBackend) Close() error {
+	close(b.done)
+	return b.db.Close()
+}
+
+// sweep deletes expired entries, Get only skips them.
+func (b *BoltBackend) sweep() {
+	ticker := time.NewTicker(boltSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+			_ = b.deleteExpired(time.Now())
+		}
+	}
+}
+
+func (b *BoltBackend) deleteExpired(now time.Time) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		// deleting under the cursor makes Next skip a key, delete afterwards
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			if isExpired(v, now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func isExpired(raw []byte, now time.Time) bool {
+	if len(raw) < 8 {
+		return true
+	}
+	expiresAt := binary.BigEndian.Uint64(raw)
+	return expiresAt != 0 && now.UnixNano() > int64(expiresAt)
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tmtypes "github.com/tendermint/tendermint/types"
+	"golang.org/x/sync/singleflight"
+
+	"pundix-homework/stream"
+)
+
+const newBlockQuery = "tm.event='NewBlock'"
+
+// EventSource is satisfied by *stream.Hub.
+type EventSource interface {
+	Subscribe(ctx context.Context, query string) (*stream.Subscriber, error)
+	Unsubscribe(s *stream.Subscriber)
+}
+
+// Entry is a cached HTTP response.
+type Entry struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+	ETag        string `json:"etag"`
+}
+
+// Cache keeps responses for the latest height until the next block and
+// responses for explicit historical heights, which never change, until
+// historicalTTL. Latest entries are keyed by the latest block seen on the
+// event stream, so a new block makes them unreachable on every replica
+// sharing the backend without any cross-replica invalidation.
+type Cache struct {
+	backend       Backend
+	latestTTL     time.Duration
+	historicalTTL time.Duration
+
+	mtx    sync.RWMutex
+	height int64
+
+	group singleflight.Group
+
+	hits   uint64
+	misses uint64
+	errors uint64
+}
+
+// New creates a cache on top of backend. Latest height entries also expire
+// after latestTTL, so a broken event stream only degrades freshness to the
+// TTL.
+func New(backend Backend, latestTTL, historicalTTL time.Duration) *Cache {
+	return &Cache{
+		backend:       backend,
+		latestTTL:     latestTTL,
+		historicalTTL: historicalTTL,
+	}
+}
+
+func backendKey(key string, historical bool, latestHeight int64) string {
+	if historical {
+		return "h/" + key
+	}
+	return fmt.Sprintf("l/%d/%s", latestHeight, key)
+}
+
+func (c *Cache) get(key string) (Entry, bool) {
+	bz, ok, err := c.backend.Get(key)
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+		return Entry{}, false
+	}
+	if !ok {
+		return Entry{}, false
+	}
+	var e Entry
+	if err = json.Unmarshal(bz, &e); err != nil {
+		atomic.AddUint64(&c.errors, 1)
+		return Entry{}, false
+	}
+	return e, true
+}
+
+func (c *Cache) set(key string, historical bool, e Entry) {
+	bz, err := json.Marshal(e)
+	if err != nil {
+		atomic.AddUint64(&c.errors, 1)
+		return
+	}
+	ttl := c.latestTTL
+	if historical {
+		ttl = c.historicalTTL
+	}
+	if err = c.backend.Set(key, bz, ttl); err != nil {
+		atomic.AddUint64(&c.errors, 1)
+	}
+}
+
+// NewBlock moves the latest height forward, which retires every latest
+// height entry.
+func (c *Cache) NewBlock(height int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if height > c.height {
+		c.height = height
+	}
+}
+
+// Height returns the latest block height seen on the event stream.
+func (c *Cache) Height() int64 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.height
+}
+
+// Follow tracks NewBlock events until ctx is done, resubscribing after the
+// stream drops.
+func (c *Cache) Follow(ctx context.Context, events EventSource, retry time.Duration) {
+	for {
+		sub, err := events.Subscribe(ctx, newBlockQuery)
+		if err == nil {
+			c.consume(ctx, sub)
+			events.Unsubscribe(sub)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func (c *Cache) consume(ctx context.Context, sub *stream.Subscriber) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if block, ok := event.Data.(tmtypes.EventDataNewBlock); ok && block.Block != nil {
+				c.NewBlock(block.Block.Height)
+			}
+		}
+	}
+}
+
+// Stats returns the number of responses served from and missing in the
+// cache, and the number of backend failures.
+func (c *Cache) Stats() (hits, misses, errors uint64) {
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses), atomic.LoadUint64(&c.errors)
+}
+
+// Ping checks that the backend answers, a missing key is not an error.
+func (c *Cache) Ping() error {
+	_, _, err := c.backend.Get("ping")
+	return err
+}
+
+func (c *Cache) Close() error {
+	return c.backend.Close()
+}
@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T, calls *int64, release <-chan struct{}) (*gin.Engine, *Cache) {
+	gin.SetMode(gin.TestMode)
+	backend, err := NewMemoryBackend(16)
+	require.NoError(t, err)
+	c := New(backend, time.Minute, time.Hour)
+
+	engine := gin.New()
+	engine.GET("/query/bank/total", c.Middleware(), func(ctx *gin.Context) {
+		atomic.AddInt64(calls, 1)
+		if release != nil {
+			<-release
+		}
+		ctx.JSON(http.StatusOK, gin.H{"height": ctx.Query("height")})
+	})
+	return engine, c
+}
+
+func get(engine *gin.Engine, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func Test_KeyNormalizesParams(t *testing.T) {
+	a, _ := Key(httptest.NewRequest(http.MethodGet, "/q?b=2&a=1&a=0", nil))
+	b, height := Key(httptest.NewRequest(http.MethodGet, "/q?a=0&a=1&b=2", nil))
+	require.Equal(t, a, b)
+	require.Equal(t, int64(0), height)
+
+	_, height = Key(httptest.NewRequest(http.MethodGet, "/q?height=42", nil))
+	require.Equal(t, int64(42), height)
+
+	// each negotiated output is cached separately
+	req := httptest.NewRequest(http.MethodGet, "/q?a=0&a=1&b=2", nil)
+	req.Header.Set("Accept", "application/yaml")
+	yaml, _ := Key(req)
+	require.NotEqual(t, a, yaml)
+}
+
+func Test_LatestInvalidatedOnNewBlock(t *testing.T) {
+	var calls int64
+	engine, c := newTestEngine(t, &calls, nil)
+
+	first := get(engine, "/query/bank/total", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, "MISS", first.Header().Get("X-Cache"))
+
+	second := get(engine, "/query/bank/total", nil)
+	require.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	require.Equal(t, first.Body.String(), second.Body.String())
+	require.Equal(t, int64(1), calls)
+
+	notModified := get(engine, "/query/bank/total", http.Header{"If-None-Match": {first.Header().Get("ETag")}})
+	require.Equal(t, http.StatusNotModified, notModified.Code)
+	require.Empty(t, notModified.Body.String())
+
+	c.NewBlock(10)
+	third := get(engine, "/query/bank/total", nil)
+	require.Equal(t, "MISS", third.Header().Get("X-Cache"))
+	require.Equal(t, int64(2), calls)
+}
+
+func Test_HistoricalSurvivesNewBlock(t *testing.T) {
+	var calls int64
+	engine, c := newTestEngine(t, &calls, nil)
+
+	first := get(engine, "/query/bank/total?height=5", nil)
+	require.Contains(t, first.Header().Get("Cache-Control"), "immutable")
+
+	c.NewBlock(10)
+	second := get(engine, "/query/bank/total?height=5", nil)
+	require.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	require.Equal(t, int64(1), calls)
+
+	hits, misses, _ := c.Stats()
+	require.Equal(t, uint64(1), hits)
+	require.Equal(t, uint64(1), misses)
+}
+
+func Test_ConcurrentMissesCoalesced(t *testing.T) {
+	var calls int64
+	release := make(chan struct{})
+	engine, _ := newTestEngine(t, &calls, release)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = get(engine, "/query/bank/total", nil).Body.String()
+		}(i)
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int64(1), calls)
+	for _, body := range bodies {
+		require.Equal(t, bodies[0], body)
+	}
+}
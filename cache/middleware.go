@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// Key normalizes a request into its cache key and returns the height it was
+// pinned to, 0 for latest.
+func Key(r *http.Request) (string, int64) {
+	params := r.URL.Query()
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	for _, k := range keys {
+		values := append([]string(nil), params[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			b.WriteString("&")
+			b.WriteString(url.QueryEscape(k))
+			b.WriteString("=")
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+
+	// the same query renders differently depending on the negotiated output
+	if accept := r.Header.Get("Accept"); accept != "" {
+		b.WriteString("#accept=")
+		b.WriteString(accept)
+	}
+
+	height, err := strconv.ParseInt(params.Get("height"), 10, 64)
+	if err != nil || height < 0 {
+		height = 0
+	}
+	return b.String(), height
+}
+
+// Middleware serves GET requests from the cache, coalescing concurrent
+// identical misses into a single upstream call. Only 200 responses are kept.
+func (c *Cache) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet {
+			ctx.Next()
+			return
+		}
+
+		key, height := Key(ctx.Request)
+		historical := height > 0
+		latestHeight := c.Height()
+		key = backendKey(key, historical, latestHeight)
+		if e, ok := c.get(key); ok {
+			atomic.AddUint64(&c.hits, 1)
+			c.write(ctx, e, historical, "HIT")
+			ctx.Abort()
+			return
+		}
+
+		atomic.AddUint64(&c.misses, 1)
+		v, _, shared := c.group.Do(key, func() (interface{}, error) {
+			rec := &recorder{ResponseWriter: ctx.Writer, status: http.StatusOK}
+			ctx.Writer = rec
+			ctx.Next()
+			ctx.Writer = rec.ResponseWriter
+
+			e := Entry{
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+				ETag:        etag(rec.body.Bytes()),
+			}
+			// a block that arrived meanwhile may have made the response stale
+			if e.Status == http.StatusOK && (historical || c.Height() == latestHeight) {
+				c.set(key, historical, e)
+			}
+			return e, nil
+		})
+
+		status := "MISS"
+		if shared {
+			status = "SHARED"
+		}
+		c.write(ctx, v.(Entry), historical, status)
+		ctx.Abort()
+	}
+}
+
+func (c *Cache) write(ctx *gin.Context, e Entry, historical bool, status string) {
+	header := ctx.Writer.Header()
+	header.Set("X-Cache", status)
+	header.Set("Vary", "Accept")
+	if e.Status != http.StatusOK {
+		header.Set("Cache-Control", "no-store")
+		ctx.Data(e.Status, e.ContentType, e.Body)
+		return
+	}
+
+	header.Set("ETag", e.ETag)
+	if historical {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(immutableMaxAge.Seconds())))
+	} else {
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(c.latestTTL.Seconds())))
+	}
+	if match := ctx.GetHeader("If-None-Match"); match != "" && match == e.ETag {
+		ctx.Status(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
+		return
+	}
+	ctx.Data(e.Status, e.ContentType, e.Body)
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// recorder buffers the handler output so it can be cached and replayed to
+// every coalesced request.
+type recorder struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.status = code
+}
+
+func (r *recorder) WriteHeaderNow() {}
+
+func (r *recorder) Write(bz []byte) (int, error) {
+	return r.body.Write(bz)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *recorder) Status() int {
+	return r.status
+}
+
+func (r *recorder) Size() int {
+	return r.body.Len()
+}
+
+func (r *recorder) Written() bool {
+	return r.body.Len() > 0
+}
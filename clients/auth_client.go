@@ -0,0 +1,68 @@
+package clients
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	"github.com/cosmos/cosmos-sdk/x/auth/types"
+	vestingexported "github.com/cosmos/cosmos-sdk/x/auth/vesting/exported"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type AuthQueryClient struct {
+	Context client.Context
+	Client  types.QueryClient
+}
+
+func (a *AuthQueryClient) New() {
+	a.Context = newClientContext()
+	a.Client = types.NewQueryClient(newConn(a.Context))
+}
+
+// VestingAddresses returns the address of every vesting account, following
+// pagination over all the accounts of the chain. It is slow on a large chain.
+func (a *AuthQueryClient) VestingAddresses(ctx context.Context) ([]string, error) {
+	var addresses []string
+	pageReq := &query.PageRequest{Limit: 1000}
+	for {
+		res, err := a.Client.Accounts(ctx, &types.QueryAccountsRequest{Pagination: pageReq})
+		if err != nil {
+			return nil, err
+		}
+		for _, any := range res.Accounts {
+			var account types.AccountI
+			if err = a.Context.InterfaceRegistry.UnpackAny(any, &account); err != nil {
+				return nil, err
+			}
+			if _, ok := account.(vestingexported.VestingAccount); ok {
+				addresses = append(addresses, account.GetAddress().String())
+			}
+		}
+		if res.Pagination == nil || len(res.Pagination.NextKey) == 0 {
+			return addresses, nil
+		}
+		pageReq = &query.PageRequest{Key: res.Pagination.NextKey, Limit: 1000}
+	}
+}
+
+// VestingAccount returns the account at address if it is a vesting account,
+// nil when it isn't one or doesn't exist.
+func (a *AuthQueryClient) VestingAccount(ctx context.Context, address string) (vestingexported.VestingAccount, error) {
+	res, err := a.Client.Account(ctx, &types.QueryAccountRequest{Address: address})
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var account types.AccountI
+	if err = a.Context.InterfaceRegistry.UnpackAny(res.Account, &account); err != nil {
+		return nil, err
+	}
+	vesting, _ := account.(vestingexported.VestingAccount)
+	return vesting, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/cosmos/cosmos-sdk/client"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
 	"github.com/cosmos/cosmos-sdk/x/bank/types"
 )
 
@@ -15,33 +16,52 @@ type BankQueryClient struct {
 
 func (b *BankQueryClient) New() {
 	b.Context = newClientContext()
-	b.Client = types.NewQueryClient(b.Context)
+	b.Client = types.NewQueryClient(newConn(b.Context))
 }
 
-func (b *BankQueryClient) Balance(address string) (*types.QueryBalanceResponse, error) {
+func (b *BankQueryClient) Balance(ctx context.Context, address string) (*types.QueryBalanceResponse, error) {
+	return b.DenomBalance(ctx, address, "FX")
+}
+
+func (b *BankQueryClient) DenomBalance(ctx context.Context, address, denom string) (*types.QueryBalanceResponse, error) {
 	addr, err := sdk.AccAddressFromBech32(address)
 	if err != nil {
 		return nil, err
 	}
-	params := types.NewQueryBalanceRequest(addr, "FX")
-	res, err := b.Client.Balance(context.Background(), params)
+	params := types.NewQueryBalanceRequest(addr, denom)
+	res, err := b.Client.Balance(ctx, params)
 	if err != nil {
 		return nil, err
 	}
-	b.Context.PrintProto(res.Balance)
 	return res, nil
 }
 
-func (b *BankQueryClient) TotalSupply() (*types.QuerySupplyOfResponse, error) {
+func (b *BankQueryClient) TotalSupply(ctx context.Context) (*types.QuerySupplyOfResponse, error) {
 	// pageReq = &query.PageRequest{
 	// 	Limit: 100,
 	// }
 
-	res, err := b.Client.SupplyOf(context.Background(), &types.QuerySupplyOfRequest{Denom: "FX"})
+	res, err := b.Client.SupplyOf(ctx, &types.QuerySupplyOfRequest{Denom: "FX"})
 	if err != nil {
 		return nil, err
 	}
 
-	b.Context.PrintProto(&res.Amount)
 	return res, nil
 }
+
+// DenomsMetadata returns the metadata of every denom, following pagination.
+func (b *BankQueryClient) DenomsMetadata(ctx context.Context) ([]types.Metadata, error) {
+	var metadatas []types.Metadata
+	pageReq := &query.PageRequest{Limit: 100}
+	for {
+		res, err := b.Client.DenomsMetadata(ctx, &types.QueryDenomsMetadataRequest{Pagination: pageReq})
+		if err != nil {
+			return nil, err
+		}
+		metadatas = append(metadatas, res.Metadatas...)
+		if res.Pagination == nil || len(res.Pagination.NextKey) == 0 {
+			return metadatas, nil
+		}
+		pageReq = &query.PageRequest{Key: res.Pagination.NextKey, Limit: 100}
+	}
+}
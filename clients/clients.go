@@ -1,9 +1,40 @@
 package clients
 
+import rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+
 var DistrQueryClientInstance = &DistributionQueryClient{}
 var BankQueryClientInstance = &BankQueryClient{}
+var GravityQueryClientInstance = &GravityQueryClient{}
+var StakingQueryClientInstance = &StakingQueryClient{}
+var SlashingQueryClientInstance = &SlashingQueryClient{}
+var MintQueryClientInstance = &MintQueryClient{}
+var AuthQueryClientInstance = &AuthQueryClient{}
+var TxBuilderClientInstance = &TxBuilderClient{}
+
+// DenomRegistryInstance resolves display units for ?format=display.
+var DenomRegistryInstance = NewDenomRegistry(BankQueryClientInstance)
+
+// RPCClientInstance is the tendermint RPC client used for event subscriptions,
+// its websocket is only started once something subscribes.
+var RPCClientInstance *rpchttp.HTTP
 
 func init() {
 	DistrQueryClientInstance.New()
 	BankQueryClientInstance.New()
+	GravityQueryClientInstance.New()
+	StakingQueryClientInstance.New()
+	SlashingQueryClientInstance.New()
+	MintQueryClientInstance.New()
+	AuthQueryClientInstance.New()
+	TxBuilderClientInstance.New()
+	RPCClientInstance = newRPCClient()
+}
+
+// Close stops the websocket of RPCClientInstance if a subscription started
+// it, the query clients hold no connection of their own.
+func Close() error {
+	if RPCClientInstance.IsRunning() {
+		return RPCClientInstance.Stop()
+	}
+	return nil
 }
@@ -1,42 +1,64 @@
 package clients
 
 import (
-	"os"
+	"context"
+	"strconv"
 
 	"github.com/cosmos/cosmos-sdk/client"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/functionx/fx-core/app"
 	"github.com/functionx/fx-core/crypto/hd"
 	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
+	// ChainID is the network the node must report to be considered ready
+	ChainID            = "fxcore"
 	rpcURI             = "https://fx-json.functionx.io:26657"
 	singaporeValidator = "fxvaloper1a73plz6w7fc8ydlwxddanc7a239kk45jnl9xwj"
 	userAccount1       = "fx15sy7ph7j6vma607y80cxdc7qg7pgvjdhnql3q6" // pick from explorer randomly
 )
 
+var encodingConfig = app.MakeEncodingConfig()
+
+// EncodingConfig returns the codecs of the chain, shared by every client.
+func EncodingConfig() app.EncodingConfig {
+	return encodingConfig
+}
+
 func newClientContext() client.Context {
-	encodingConfig := app.MakeEncodingConfig()
 	clientCtx := client.Context{}.
 		WithCodec(encodingConfig.Marshaler).
 		WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
 		WithTxConfig(encodingConfig.TxConfig).
 		WithLegacyAmino(encodingConfig.Amino).
-		WithInput(os.Stdin).
-		WithOutput(os.Stdout).
-		// WithAccountRetriever(types.AccountRetriever{}).
+		WithAccountRetriever(authtypes.AccountRetriever{}).
 		WithBroadcastMode("sync").
 		WithHomeDir(app.DefaultNodeHome).
 		WithViper("FX").
 		WithKeyringOptions(hd.EthSecp256k1Option()).
-		WithChainID("fxcore")
+		WithChainID(ChainID)
 
 	clientCtx = clientCtx.WithNodeURI(rpcURI)
 
+	return clientCtx.WithClient(newRPCClient())
+}
+
+func newRPCClient() *rpchttp.HTTP {
 	client, err := rpchttp.New(rpcURI, "/websocket")
 	if err != nil {
 		panic(err)
 	}
+	return client
+}
 
-	return clientCtx.WithClient(client)
+// WithHeight pins every query made with the returned context to height,
+// a height of 0 queries the latest block.
+func WithHeight(ctx context.Context, height int64) context.Context {
+	if height <= 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
 }
@@ -0,0 +1,131 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"pundix-homework/logging"
+	"pundix-homework/metrics"
+	"pundix-homework/tracing"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	"github.com/cosmos/cosmos-sdk/types/tx"
+	gogogrpc "github.com/gogo/protobuf/grpc"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/metadata"
+)
+
+var protoCodec = encoding.GetCodec(proto.Name)
+
+// instrumentedConn is the grpc connection handed to every QueryClient. It
+// performs the same ABCI query as client.Context.Invoke, split into traced
+// steps, and records each call with its latency and the height it was
+// served at.
+type instrumentedConn struct {
+	client.Context
+}
+
+var _ gogogrpc.ClientConn = instrumentedConn{}
+
+func newConn(clientCtx client.Context) instrumentedConn {
+	return instrumentedConn{Context: clientCtx}
+}
+
+func (c instrumentedConn) Invoke(ctx context.Context, method string, req, reply interface{}, opts ...grpc.CallOption) error {
+	ctx, span := tracing.Start(ctx, method, tracing.KindClient)
+	defer span.End()
+	span.SetAttr("rpc.method", method)
+	span.SetAttr("net.peer.name", c.NodeURI)
+
+	start := time.Now()
+	height, err := c.invoke(ctx, method, req, reply, opts...)
+	latency := time.Since(start)
+
+	span.SetAttr("block.height", height)
+	span.RecordError(err)
+	logging.RecordUpstream(ctx, method, latency, height, err)
+	metrics.ObserveUpstream(c.NodeURI, method, latency, err)
+	return err
+}
+
+func (c instrumentedConn) invoke(ctx context.Context, method string, req, reply interface{}, opts ...grpc.CallOption) (int64, error) {
+	if reflect.ValueOf(req).IsNil() {
+		return 0, fmt.Errorf("request cannot be nil")
+	}
+	// broadcasts are not queries, leave them to the sdk
+	if _, ok := req.(*tx.BroadcastTxRequest); ok {
+		return 0, c.Context.Invoke(ctx, method, req, reply, opts...)
+	}
+
+	queryHeight, err := requestHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tracing.SpanFromContext(ctx).SetAttr("query.height", queryHeight)
+
+	_, marshalSpan := tracing.Start(ctx, "marshal", tracing.KindInternal)
+	reqBz, err := protoCodec.Marshal(req)
+	marshalSpan.SetAttr("bytes", len(reqBz))
+	marshalSpan.RecordError(err)
+	marshalSpan.End()
+	if err != nil {
+		return 0, err
+	}
+
+	release, err := acquireUpstream(ctx, c.NodeURI)
+	if err != nil {
+		return 0, err
+	}
+	_, querySpan := tracing.Start(ctx, "abci_query", tracing.KindClient)
+	res, err := c.Context.QueryABCI(abci.RequestQuery{Path: method, Data: reqBz, Height: queryHeight})
+	release()
+	querySpan.SetAttr("bytes", len(res.Value))
+	querySpan.RecordError(err)
+	querySpan.End()
+	if err != nil {
+		return 0, err
+	}
+
+	_, unmarshalSpan := tracing.Start(ctx, "unmarshal", tracing.KindInternal)
+	err = protoCodec.Unmarshal(res.Value, reply)
+	if err == nil && c.InterfaceRegistry != nil {
+		err = codectypes.UnpackInterfaces(reply, c.InterfaceRegistry)
+	}
+	unmarshalSpan.RecordError(err)
+	unmarshalSpan.End()
+	if err != nil {
+		return res.Height, err
+	}
+
+	header := metadata.Pairs(grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(res.Height, 10))
+	for _, opt := range opts {
+		if h, ok := opt.(grpc.HeaderCallOption); ok {
+			*h.HeaderAddr = header
+		}
+	}
+	return res.Height, nil
+}
+
+// requestHeight reads the height set by WithHeight, 0 means latest.
+func requestHeight(ctx context.Context) (int64, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	heights := md.Get(grpctypes.GRPCBlockHeightHeader)
+	if len(heights) == 0 {
+		return 0, nil
+	}
+	height, err := strconv.ParseInt(heights[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if height < 0 {
+		return 0, fmt.Errorf("height (%d) must be >= 0", height)
+	}
+	return height, nil
+}
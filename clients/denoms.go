@@ -0,0 +1,116 @@
+package clients
+
+import (
+	"context"
+	"pundix-homework/logging"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// FX has no denom metadata on chain, its base unit is 1e-18 FX
+	fxDenom    = "FX"
+	fxExponent = 18
+
+	denomsRefresh      = 10 * time.Minute
+	denomsRetryOnError = time.Minute
+	denomsLoadTimeout  = 10 * time.Second
+)
+
+// DenomUnit is how amounts of a base denom are displayed.
+type DenomUnit struct {
+	Display  string
+	Exponent uint32
+}
+
+// DenomRegistry caches the bank denom metadata, refreshed every
+// denomsRefresh.
+type DenomRegistry struct {
+	load func(ctx context.Context) ([]types.Metadata, error)
+
+	// one refresh at a time, the callers that find the units expired wait
+	// for it
+	group singleflight.Group
+
+	mtx     sync.Mutex
+	units   map[string]DenomUnit
+	expires time.Time
+}
+
+func NewDenomRegistry(client *BankQueryClient) *DenomRegistry {
+	return &DenomRegistry{load: client.DenomsMetadata}
+}
+
+// DisplayUnit returns the display denom and exponent of base, ok is false for
+// denoms without metadata.
+func (r *DenomRegistry) DisplayUnit(ctx context.Context, base string) (string, uint32, bool) {
+	units := r.current()
+	if units == nil {
+		// the refresh runs on its own context, a cancelled request doesn't
+		// fail it for the others
+		v, _, _ := r.group.Do("refresh", func() (interface{}, error) {
+			return r.refresh(logging.FromContext(ctx)), nil
+		})
+		units = v.(map[string]DenomUnit)
+	}
+	unit, ok := units[base]
+	return unit.Display, unit.Exponent, ok
+}
+
+// current returns the units, nil once they expired.
+func (r *DenomRegistry) current() map[string]DenomUnit {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if time.Now().After(r.expires) {
+		return nil
+	}
+	return r.units
+}
+
+// refresh reloads the metadata without holding the lock, on failure the
+// previous units are kept.
+func (r *DenomRegistry) refresh(logger *zerolog.Logger) map[string]DenomUnit {
+	ctx, cancel := context.WithTimeout(context.Background(), denomsLoadTimeout)
+	defer cancel()
+	metadatas, err := r.load(ctx)
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err != nil {
+		logger.Warn().Err(err).Msg("load denom metadata")
+		if r.units == nil {
+			r.units = unitsFromMetadata(nil)
+		}
+		r.expires = time.Now().Add(denomsRetryOnError)
+		return r.units
+	}
+	r.units = unitsFromMetadata(metadatas)
+	r.expires = time.Now().Add(denomsRefresh)
+	return r.units
+}
+
+func unitsFromMetadata(metadatas []types.Metadata) map[string]DenomUnit {
+	units := map[string]DenomUnit{fxDenom: {Display: fxDenom, Exponent: fxExponent}}
+	for _, metadata := range metadatas {
+		var display *types.DenomUnit
+		for _, unit := range metadata.DenomUnits {
+			if unit.Denom == metadata.Display {
+				display = unit
+				break
+			}
+			// without a matching display unit the largest one is used
+			if display == nil || unit.Exponent > display.Exponent {
+				display = unit
+			}
+		}
+		if display == nil {
+			continue
+		}
+		units[metadata.Base] = DenomUnit{Display: display.Denom, Exponent: display.Exponent}
+	}
+	return units
+}
@@ -0,0 +1,73 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UnitsFromMetadata(t *testing.T) {
+	units := unitsFromMetadata([]types.Metadata{
+		{
+			Base:    "upundix",
+			Display: "pundix",
+			DenomUnits: []*types.DenomUnit{
+				{Denom: "upundix", Exponent: 0},
+				{Denom: "pundix", Exponent: 6},
+				{Denom: "kpundix", Exponent: 9},
+			},
+		},
+		{
+			Base:       "eth0xabc",
+			Display:    "missing",
+			DenomUnits: []*types.DenomUnit{{Denom: "eth0xabc"}, {Denom: "USDT", Exponent: 6}},
+		},
+	})
+
+	require.Equal(t, DenomUnit{Display: "FX", Exponent: 18}, units["FX"])
+	require.Equal(t, DenomUnit{Display: "pundix", Exponent: 6}, units["upundix"])
+	require.Equal(t, DenomUnit{Display: "USDT", Exponent: 6}, units["eth0xabc"])
+}
+
+func Test_DisplayUnitRefreshesOnce(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	r := &DenomRegistry{load: func(ctx context.Context) ([]types.Metadata, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		// the refresh doesn't run on the request context
+		require.NoError(t, ctx.Err())
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return []types.Metadata{{Base: "upundix", Display: "pundix", DenomUnits: []*types.DenomUnit{{Denom: "pundix", Exponent: 6}}}}, nil
+	}}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			display, exponent, ok := r.DisplayUnit(cancelled, "upundix")
+			require.True(t, ok)
+			require.Equal(t, "pundix", display)
+			require.Equal(t, uint32(6), exponent)
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&loads) == 1 }, time.Second, time.Millisecond)
+	// the lock isn't held while the metadata loads
+	r.mtx.Lock()
+	r.mtx.Unlock()
+	close(release)
+	wg.Wait()
+
+	_, _, ok := r.DisplayUnit(context.Background(), "FX")
+	require.True(t, ok)
+	require.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
@@ -16,57 +16,51 @@ type DistributionQueryClient struct {
 
 func (d *DistributionQueryClient) New() {
 	d.Context = newClientContext()
-	d.Client = types.NewQueryClient(d.Context)
+	d.Client = types.NewQueryClient(newConn(d.Context))
 }
 
-func (d *DistributionQueryClient) QueryParams() (*types.QueryParamsResponse, error) {
-	res, err := d.Client.Params(context.Background(), &types.QueryParamsRequest{})
+func (d *DistributionQueryClient) QueryParams(ctx context.Context) (*types.QueryParamsResponse, error) {
+	res, err := d.Client.Params(ctx, &types.QueryParamsRequest{})
 	if err != nil {
 		return nil, err
 	}
-	if err = d.Context.PrintProto(&res.Params); err != nil {
-		return nil, err
-	}
 	return res, nil
 }
 
-func (d *DistributionQueryClient) ValidatorOutstandingRewards(validitorAddr string) (*types.QueryValidatorOutstandingRewardsResponse, error) {
+func (d *DistributionQueryClient) ValidatorOutstandingRewards(ctx context.Context, validitorAddr string) (*types.QueryValidatorOutstandingRewardsResponse, error) {
 	validatorAddr, err := sdk.ValAddressFromBech32(validitorAddr)
 	if err != nil {
 		return nil, err
 	}
 
 	res, err := d.Client.ValidatorOutstandingRewards(
-		context.Background(),
+		ctx,
 		&types.QueryValidatorOutstandingRewardsRequest{ValidatorAddress: validatorAddr.String()},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = d.Context.PrintProto(&res.Rewards); err != nil {
-		return nil, err
-	}
 	return res, nil
 }
 
-func (d *DistributionQueryClient) ValidatorCommission(validitorAddr string) (*types.QueryValidatorCommissionResponse, error) {
+func (d *DistributionQueryClient) ValidatorCommission(ctx context.Context, validitorAddr string) (*types.QueryValidatorCommissionResponse, error) {
 	validatorAddr, err := sdk.ValAddressFromBech32(validitorAddr)
 	if err != nil {
 		return nil, err
 	}
 
 	res, err := d.Client.ValidatorCommission(
-		context.Background(),
+		ctx,
 		&types.QueryValidatorCommissionRequest{ValidatorAddress: validatorAddr.String()},
 	)
-
-	if err = d.Context.PrintProto(&res.Commission); err != nil {
+	if err != nil {
 		return nil, err
 	}
+
 	return res, nil
 }
-func (d *DistributionQueryClient) ValidatorSlashes(validator string, startHeight, endHeight, limit uint64) (*types.QueryValidatorSlashesResponse, error) {
+func (d *DistributionQueryClient) ValidatorSlashes(ctx context.Context, validator string, startHeight, endHeight, limit uint64) (*types.QueryValidatorSlashesResponse, error) {
 	validatorAddr, err := sdk.ValAddressFromBech32(validator)
 	if err != nil {
 		return nil, err
@@ -77,7 +71,7 @@ func (d *DistributionQueryClient) ValidatorSlashes(validator string, startHeight
 	}
 
 	res, err := d.Client.ValidatorSlashes(
-		context.Background(),
+		ctx,
 		&types.QueryValidatorSlashesRequest{
 			ValidatorAddress: validatorAddr.String(),
 			StartingHeight:   startHeight,
@@ -89,20 +83,14 @@ func (d *DistributionQueryClient) ValidatorSlashes(validator string, startHeight
 		return nil, err
 	}
 
-	if err = d.Context.PrintProto(res); err != nil {
-		return nil, err
-	}
 	return res, nil
 }
 
-func (d *DistributionQueryClient) CommunityPool() (*types.QueryCommunityPoolResponse, error) {
-	res, err := d.Client.CommunityPool(context.Background(), &types.QueryCommunityPoolRequest{})
+func (d *DistributionQueryClient) CommunityPool(ctx context.Context) (*types.QueryCommunityPoolResponse, error) {
+	res, err := d.Client.CommunityPool(ctx, &types.QueryCommunityPoolRequest{})
 	if err != nil {
 		return nil, err
 	}
 
-	if err = d.Context.PrintProto(res); err != nil {
-		return nil, err
-	}
 	return res, nil
 }
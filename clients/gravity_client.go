@@ -0,0 +1,26 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/functionx/fx-core/x/gravity/types"
+)
+
+type GravityQueryClient struct {
+	Context client.Context
+	Client  types.QueryClient
+}
+
+func (g *GravityQueryClient) New() {
+	g.Context = newClientContext()
+	g.Client = types.NewQueryClient(newConn(g.Context))
+}
+
+func (g *GravityQueryClient) OutgoingTxBatches(ctx context.Context) (*types.QueryOutgoingTxBatchesResponse, error) {
+	res, err := g.Client.OutgoingTxBatches(ctx, &types.QueryOutgoingTxBatchesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"pundix-homework/metrics"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// ErrUpstreamBusy is returned when no concurrency slot on the node freed up
+// within the wait time, handlers answer it with 429.
+var ErrUpstreamBusy = errors.New("upstream node busy, retry later")
+
+var (
+	upstreamMtx         sync.Mutex
+	upstreamMaxInflight int64 = 16
+	upstreamMaxWait           = 2 * time.Second
+	upstreamSlots             = make(map[string]*semaphore.Weighted)
+)
+
+// SetUpstreamConcurrency caps the ABCI queries in flight per node, a query
+// waits at most maxWait for a slot. It must be called before the first
+// query.
+func SetUpstreamConcurrency(maxInflight int64, maxWait time.Duration) {
+	upstreamMtx.Lock()
+	defer upstreamMtx.Unlock()
+	upstreamMaxInflight = maxInflight
+	upstreamMaxWait = maxWait
+	upstreamSlots = make(map[string]*semaphore.Weighted)
+}
+
+// UpstreamRetryAfter is the wait suggested to callers rejected with
+// ErrUpstreamBusy.
+func UpstreamRetryAfter() time.Duration {
+	upstreamMtx.Lock()
+	defer upstreamMtx.Unlock()
+	return upstreamMaxWait
+}
+
+// acquireUpstream takes a slot on node, the returned func releases it.
+func acquireUpstream(ctx context.Context, node string) (func(), error) {
+	upstreamMtx.Lock()
+	slots, ok := upstreamSlots[node]
+	if !ok {
+		slots = semaphore.NewWeighted(upstreamMaxInflight)
+		upstreamSlots[node] = slots
+	}
+	maxWait := upstreamMaxWait
+	upstreamMtx.Unlock()
+
+	if !slots.TryAcquire(1) {
+		waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+		metrics.UpstreamWaiting(node, 1)
+		err := slots.Acquire(waitCtx, 1)
+		metrics.UpstreamWaiting(node, -1)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			metrics.UpstreamRejected(node)
+			return nil, ErrUpstreamBusy
+		}
+	}
+
+	metrics.UpstreamInflight(node, 1)
+	return func() {
+		metrics.UpstreamInflight(node, -1)
+		slots.Release(1)
+	}, nil
+}
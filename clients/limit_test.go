@@ -0,0 +1,33 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UpstreamConcurrency(t *testing.T) {
+	SetUpstreamConcurrency(1, 20*time.Millisecond)
+	defer SetUpstreamConcurrency(16, 2*time.Second)
+
+	release, err := acquireUpstream(context.Background(), "node")
+	require.NoError(t, err)
+
+	_, err = acquireUpstream(context.Background(), "node")
+	require.ErrorIs(t, err, ErrUpstreamBusy)
+
+	// nodes don't share slots
+	releaseOther, err := acquireUpstream(context.Background(), "other")
+	require.NoError(t, err)
+	releaseOther()
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		release()
+	}()
+	release, err = acquireUpstream(context.Background(), "node")
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,26 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/x/mint/types"
+)
+
+type MintQueryClient struct {
+	Context client.Context
+	Client  types.QueryClient
+}
+
+func (m *MintQueryClient) New() {
+	m.Context = newClientContext()
+	m.Client = types.NewQueryClient(newConn(m.Context))
+}
+
+func (m *MintQueryClient) AnnualProvisions(ctx context.Context) (*types.QueryAnnualProvisionsResponse, error) {
+	res, err := m.Client.AnnualProvisions(ctx, &types.QueryAnnualProvisionsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"pundix-homework/logging"
+	"pundix-homework/metrics"
+	"sync"
+	"time"
+)
+
+// NodeHealth is the result of the latest status check of the node.
+type NodeHealth struct {
+	Node       string    `json:"node"`
+	Up         bool      `json:"up"`
+	Network    string    `json:"network"`
+	Height     int64     `json:"height"`
+	CatchingUp bool      `json:"catching_up"`
+	BlockTime  time.Time `json:"block_time"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	nodeHealthMtx sync.RWMutex
+	nodeHealth    = NodeHealth{Node: rpcURI}
+)
+
+// Ready reports why the node can't serve fresh data for ChainID: it is down,
+// still syncing, on another network or its latest block is older than
+// maxBlockAge.
+func (h NodeHealth) Ready(maxBlockAge time.Duration, now time.Time) error {
+	switch {
+	case !h.Up:
+		return fmt.Errorf("node unreachable: %s", h.Error)
+	case h.CatchingUp:
+		return fmt.Errorf("node is catching up at height %d", h.Height)
+	case h.Network != ChainID:
+		return fmt.Errorf("node is on network %q, expected %q", h.Network, ChainID)
+	case now.Sub(h.BlockTime) > maxBlockAge:
+		return fmt.Errorf("latest block %d is %s old", h.Height, now.Sub(h.BlockTime).Round(time.Second))
+	}
+	return nil
+}
+
+// CurrentNodeHealth returns the result of the latest status check.
+func CurrentNodeHealth() NodeHealth {
+	nodeHealthMtx.RLock()
+	defer nodeHealthMtx.RUnlock()
+	return nodeHealth
+}
+
+// CheckNode asks the node for its status and records the result.
+func CheckNode(ctx context.Context) NodeHealth {
+	health := NodeHealth{Node: rpcURI, CheckedAt: time.Now()}
+	status, err := RPCClientInstance.Status(ctx)
+	if err != nil {
+		health.Error = err.Error()
+		logging.FromContext(ctx).Warn().Err(err).Str("node", rpcURI).Msg("node status check failed")
+	} else {
+		health.Up = true
+		health.Network = status.NodeInfo.Network
+		health.Height = status.SyncInfo.LatestBlockHeight
+		health.CatchingUp = status.SyncInfo.CatchingUp
+		health.BlockTime = status.SyncInfo.LatestBlockTime
+	}
+	metrics.SetNodeStatus(rpcURI, health.Up, health.Height)
+
+	nodeHealthMtx.Lock()
+	nodeHealth = health
+	nodeHealthMtx.Unlock()
+	return health
+}
+
+// LatestHeight returns the height of the latest block of the node.
+func LatestHeight(ctx context.Context) (int64, error) {
+	status, err := RPCClientInstance.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+// MonitorNode checks the node every interval until ctx is done.
+func MonitorNode(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		CheckNode(checkCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
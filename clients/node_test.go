@@ -0,0 +1,29 @@
+package clients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NodeHealthReady(t *testing.T) {
+	now := time.Now()
+	healthy := NodeHealth{Up: true, Network: ChainID, Height: 10, BlockTime: now.Add(-5 * time.Second)}
+	require.NoError(t, healthy.Ready(time.Minute, now))
+
+	down := NodeHealth{Error: "connection refused"}
+	require.EqualError(t, down.Ready(time.Minute, now), "node unreachable: connection refused")
+
+	syncing := healthy
+	syncing.CatchingUp = true
+	require.Error(t, syncing.Ready(time.Minute, now))
+
+	otherChain := healthy
+	otherChain.Network = "dhobyghaut"
+	require.Error(t, otherChain.Ready(time.Minute, now))
+
+	stale := healthy
+	stale.BlockTime = now.Add(-2 * time.Minute)
+	require.EqualError(t, stale.Ready(time.Minute, now), "latest block 10 is 2m0s old")
+}
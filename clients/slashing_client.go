@@ -0,0 +1,54 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	"github.com/cosmos/cosmos-sdk/x/slashing/types"
+)
+
+type SlashingQueryClient struct {
+	Context client.Context
+	Client  types.QueryClient
+}
+
+func (s *SlashingQueryClient) New() {
+	s.Context = newClientContext()
+	s.Client = types.NewQueryClient(newConn(s.Context))
+}
+
+func (s *SlashingQueryClient) Params(ctx context.Context) (*types.QueryParamsResponse, error) {
+	res, err := s.Client.Params(ctx, &types.QueryParamsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SigningInfo returns the liveness of a validator by its fxvalcons address.
+func (s *SlashingQueryClient) SigningInfo(ctx context.Context, consAddress string) (*types.QuerySigningInfoResponse, error) {
+	res, err := s.Client.SigningInfo(ctx, &types.QuerySigningInfoRequest{ConsAddress: consAddress})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SigningInfos returns the signing info of every validator, following
+// pagination.
+func (s *SlashingQueryClient) SigningInfos(ctx context.Context) ([]types.ValidatorSigningInfo, error) {
+	var infos []types.ValidatorSigningInfo
+	pageReq := &query.PageRequest{Limit: 100}
+	for {
+		res, err := s.Client.SigningInfos(ctx, &types.QuerySigningInfosRequest{Pagination: pageReq})
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, res.Info...)
+		if res.Pagination == nil || len(res.Pagination.NextKey) == 0 {
+			return infos, nil
+		}
+		pageReq = &query.PageRequest{Key: res.Pagination.NextKey, Limit: 100}
+	}
+}
@@ -0,0 +1,107 @@
+package clients
+
+import (
+	"context"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	"github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+type StakingQueryClient struct {
+	Context client.Context
+	Client  types.QueryClient
+}
+
+func (s *StakingQueryClient) New() {
+	s.Context = newClientContext()
+	s.Client = types.NewQueryClient(newConn(s.Context))
+}
+
+func (s *StakingQueryClient) Validator(ctx context.Context, validator string) (*types.QueryValidatorResponse, error) {
+	validatorAddr, err := sdk.ValAddressFromBech32(validator)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Validator(ctx, &types.QueryValidatorRequest{ValidatorAddr: validatorAddr.String()})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Validators returns every validator with the status, all of them when the
+// status is empty, following pagination.
+func (s *StakingQueryClient) Validators(ctx context.Context, status string) ([]types.Validator, error) {
+	var validators []types.Validator
+	pageReq := &query.PageRequest{Limit: 100}
+	for {
+		res, err := s.Client.Validators(ctx, &types.QueryValidatorsRequest{Status: status, Pagination: pageReq})
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, res.Validators...)
+		if res.Pagination == nil || len(res.Pagination.NextKey) == 0 {
+			return validators, nil
+		}
+		pageReq = &query.PageRequest{Key: res.Pagination.NextKey, Limit: 100}
+	}
+}
+
+// ValidatorDelegations returns one page of the delegations to a validator,
+// a nil pageReq returns the first page.
+func (s *StakingQueryClient) ValidatorDelegations(ctx context.Context, validator string, pageReq *query.PageRequest) (*types.QueryValidatorDelegationsResponse, error) {
+	validatorAddr, err := sdk.ValAddressFromBech32(validator)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.ValidatorDelegations(ctx, &types.QueryValidatorDelegationsRequest{
+		ValidatorAddr: validatorAddr.String(),
+		Pagination:    pageReq,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DelegatorDelegations returns every delegation of a delegator, following
+// pagination.
+func (s *StakingQueryClient) DelegatorDelegations(ctx context.Context, delegator string) ([]types.DelegationResponse, error) {
+	delegatorAddr, err := sdk.AccAddressFromBech32(delegator)
+	if err != nil {
+		return nil, err
+	}
+	var delegations []types.DelegationResponse
+	pageReq := &query.PageRequest{Limit: 100}
+	for {
+		res, err := s.Client.DelegatorDelegations(ctx, &types.QueryDelegatorDelegationsRequest{DelegatorAddr: delegatorAddr.String(), Pagination: pageReq})
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, res.DelegationResponses...)
+		if res.Pagination == nil || len(res.Pagination.NextKey) == 0 {
+			return delegations, nil
+		}
+		pageReq = &query.PageRequest{Key: res.Pagination.NextKey, Limit: 100}
+	}
+}
+
+// Delegation returns the delegation of a delegator to a validator, the node
+// answers NotFound when there is none.
+func (s *StakingQueryClient) Delegation(ctx context.Context, delegator, validator string) (*types.QueryDelegationResponse, error) {
+	res, err := s.Client.Delegation(ctx, &types.QueryDelegationRequest{DelegatorAddr: delegator, ValidatorAddr: validator})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *StakingQueryClient) Pool(ctx context.Context) (*types.QueryPoolResponse, error) {
+	res, err := s.Client.Pool(ctx, &types.QueryPoolRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
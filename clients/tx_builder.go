@@ -0,0 +1,250 @@
+package clients
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/functionx/fx-core/crypto/ethsecp256k1"
+)
+
+const defaultGasLimit = 200000
+
+// TxOptions describes the signer and the fee/memo settings shared by every
+// transaction built by TxBuilderClient.
+type TxOptions struct {
+	Signer        string `json:"signer"`
+	PublicKey     string `json:"public_key"` // base64 compressed key, only needed when the account has no pubkey on chain
+	KeyType       string `json:"key_type"`   // eth_secp256k1 (default) or secp256k1
+	Fee           string `json:"fee"`
+	Gas           uint64 `json:"gas"`
+	Memo          string `json:"memo"`
+	TimeoutHeight uint64 `json:"timeout_height"`
+}
+
+// UnsignedTx holds everything a wallet needs to sign a transaction offline.
+// Tx declares SIGN_MODE_DIRECT and is signed over SignDocBytes, AminoTx is the
+// same tx declaring SIGN_MODE_LEGACY_AMINO_JSON, signed over
+// AminoJSONSignBytes.
+type UnsignedTx struct {
+	Tx                 json.RawMessage  `json:"tx"`
+	SignDoc            *txtypes.SignDoc `json:"sign_doc"`
+	SignDocBytes       []byte           `json:"sign_doc_bytes"`
+	AminoTx            json.RawMessage  `json:"amino_tx"`
+	AminoJSONSignBytes string           `json:"amino_json_sign_bytes"`
+	AccountNumber      uint64           `json:"account_number"`
+	Sequence           uint64           `json:"sequence"`
+	ChainID            string           `json:"chain_id"`
+}
+
+type TxBuilderClient struct {
+	Context client.Context
+}
+
+func (t *TxBuilderClient) New() {
+	t.Context = newClientContext()
+}
+
+func (t *TxBuilderClient) BankSend(opts TxOptions, toAddress, amount string) (*UnsignedTx, error) {
+	from, err := sdk.AccAddressFromBech32(opts.Signer)
+	if err != nil {
+		return nil, err
+	}
+	to, err := sdk.AccAddressFromBech32(toAddress)
+	if err != nil {
+		return nil, err
+	}
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, banktypes.NewMsgSend(from, to, coins))
+}
+
+func (t *TxBuilderClient) Delegate(opts TxOptions, validator, amount string) (*UnsignedTx, error) {
+	delAddr, valAddr, coin, err := parseStakingArgs(opts.Signer, validator, amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, stakingtypes.NewMsgDelegate(delAddr, valAddr, coin))
+}
+
+func (t *TxBuilderClient) Undelegate(opts TxOptions, validator, amount string) (*UnsignedTx, error) {
+	delAddr, valAddr, coin, err := parseStakingArgs(opts.Signer, validator, amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, stakingtypes.NewMsgUndelegate(delAddr, valAddr, coin))
+}
+
+func (t *TxBuilderClient) Redelegate(opts TxOptions, srcValidator, dstValidator, amount string) (*UnsignedTx, error) {
+	delAddr, srcAddr, coin, err := parseStakingArgs(opts.Signer, srcValidator, amount)
+	if err != nil {
+		return nil, err
+	}
+	dstAddr, err := sdk.ValAddressFromBech32(dstValidator)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, stakingtypes.NewMsgBeginRedelegate(delAddr, srcAddr, dstAddr, coin))
+}
+
+func (t *TxBuilderClient) WithdrawRewards(opts TxOptions, validator string) (*UnsignedTx, error) {
+	delAddr, err := sdk.AccAddressFromBech32(opts.Signer)
+	if err != nil {
+		return nil, err
+	}
+	valAddr, err := sdk.ValAddressFromBech32(validator)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, distrtypes.NewMsgWithdrawDelegatorReward(delAddr, valAddr))
+}
+
+// WithdrawCommission builds a commission withdrawal for the validator operated
+// by the signer account.
+func (t *TxBuilderClient) WithdrawCommission(opts TxOptions) (*UnsignedTx, error) {
+	operator, err := sdk.AccAddressFromBech32(opts.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return t.build(opts, distrtypes.NewMsgWithdrawValidatorCommission(sdk.ValAddress(operator)))
+}
+
+func parseStakingArgs(signer, validator, amount string) (sdk.AccAddress, sdk.ValAddress, sdk.Coin, error) {
+	delAddr, err := sdk.AccAddressFromBech32(signer)
+	if err != nil {
+		return nil, nil, sdk.Coin{}, err
+	}
+	valAddr, err := sdk.ValAddressFromBech32(validator)
+	if err != nil {
+		return nil, nil, sdk.Coin{}, err
+	}
+	coin, err := sdk.ParseCoinNormalized(amount)
+	if err != nil {
+		return nil, nil, sdk.Coin{}, err
+	}
+	return delAddr, valAddr, coin, nil
+}
+
+func (t *TxBuilderClient) build(opts TxOptions, msgs ...sdk.Msg) (*UnsignedTx, error) {
+	for _, msg := range msgs {
+		if err := msg.ValidateBasic(); err != nil {
+			return nil, err
+		}
+	}
+
+	signer, err := sdk.AccAddressFromBech32(opts.Signer)
+	if err != nil {
+		return nil, err
+	}
+	account, err := t.Context.AccountRetriever.GetAccount(t.Context, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := account.GetPubKey()
+	if opts.PublicKey != "" {
+		if pubKey, err = decodePubKey(opts.PublicKey, opts.KeyType); err != nil {
+			return nil, err
+		}
+		if !signer.Equals(sdk.AccAddress(pubKey.Address())) {
+			return nil, errors.New("public key does not match signer")
+		}
+	}
+	if pubKey == nil {
+		return nil, errors.New("signer has no public key on chain, public_key required")
+	}
+
+	fees, err := sdk.ParseCoinsNormalized(opts.Fee)
+	if err != nil {
+		return nil, err
+	}
+	gas := opts.Gas
+	if gas == 0 {
+		gas = defaultGasLimit
+	}
+
+	txConfig := t.Context.TxConfig
+	builder := txConfig.NewTxBuilder()
+	if err = builder.SetMsgs(msgs...); err != nil {
+		return nil, err
+	}
+	builder.SetFeeAmount(fees)
+	builder.SetGasLimit(gas)
+	builder.SetMemo(opts.Memo)
+	builder.SetTimeoutHeight(opts.TimeoutHeight)
+
+	signerData := authsigning.SignerData{
+		ChainID:       t.Context.ChainID,
+		AccountNumber: account.GetAccountNumber(),
+		Sequence:      account.GetSequence(),
+	}
+	// SignerInfos are part of the direct mode sign bytes, so the signature is
+	// set with an empty payload before the sign bytes are generated. The
+	// signer info has to declare the mode the tx is signed with.
+	signed := make(map[signing.SignMode][]byte, 2)
+	txs := make(map[signing.SignMode][]byte, 2)
+	for _, mode := range []signing.SignMode{signing.SignMode_SIGN_MODE_DIRECT, signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON} {
+		err = builder.SetSignatures(signing.SignatureV2{
+			PubKey:   pubKey,
+			Data:     &signing.SingleSignatureData{SignMode: mode},
+			Sequence: account.GetSequence(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if signed[mode], err = txConfig.SignModeHandler().GetSignBytes(mode, signerData, builder.GetTx()); err != nil {
+			return nil, err
+		}
+		if txs[mode], err = txConfig.TxJSONEncoder()(builder.GetTx()); err != nil {
+			return nil, err
+		}
+	}
+
+	directBytes := signed[signing.SignMode_SIGN_MODE_DIRECT]
+	signDoc := &txtypes.SignDoc{}
+	if err = signDoc.Unmarshal(directBytes); err != nil {
+		return nil, err
+	}
+
+	return &UnsignedTx{
+		Tx:                 txs[signing.SignMode_SIGN_MODE_DIRECT],
+		SignDoc:            signDoc,
+		SignDocBytes:       directBytes,
+		AminoTx:            txs[signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON],
+		AminoJSONSignBytes: string(signed[signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON]),
+		AccountNumber:      account.GetAccountNumber(),
+		Sequence:           account.GetSequence(),
+		ChainID:            t.Context.ChainID,
+	}, nil
+}
+
+func decodePubKey(encoded, keyType string) (cryptotypes.PubKey, error) {
+	bz, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(bz) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("invalid compressed public key length %d", len(bz))
+	}
+	switch keyType {
+	case "", ethsecp256k1.KeyType:
+		return &ethsecp256k1.PubKey{Key: bz}, nil
+	case "secp256k1":
+		return &secp256k1.PubKey{Key: bz}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", keyType)
+	}
+}
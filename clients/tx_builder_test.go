@@ -0,0 +1,166 @@
+package clients
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/functionx/fx-core/crypto/ethsecp256k1"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedAccounts answers every account with account number 7 and sequence 3,
+// without a pubkey on chain.
+type fixedAccounts struct{}
+
+func (fixedAccounts) GetAccount(_ client.Context, addr sdk.AccAddress) (client.Account, error) {
+	return authtypes.NewBaseAccount(addr, nil, 7, 3), nil
+}
+
+func (f fixedAccounts) GetAccountWithHeight(clientCtx client.Context, addr sdk.AccAddress) (client.Account, int64, error) {
+	account, err := f.GetAccount(clientCtx, addr)
+	return account, 0, err
+}
+
+func (fixedAccounts) EnsureExists(client.Context, sdk.AccAddress) error { return nil }
+
+func (fixedAccounts) GetAccountNumberSequence(client.Context, sdk.AccAddress) (uint64, uint64, error) {
+	return 7, 3, nil
+}
+
+func newOfflineTxBuilder() *TxBuilderClient {
+	return &TxBuilderClient{Context: client.Context{}.
+		WithCodec(encodingConfig.Marshaler).
+		WithInterfaceRegistry(encodingConfig.InterfaceRegistry).
+		WithTxConfig(encodingConfig.TxConfig).
+		WithLegacyAmino(encodingConfig.Amino).
+		WithAccountRetriever(fixedAccounts{}).
+		WithChainID(ChainID)}
+}
+
+func Test_DecodePubKey(t *testing.T) {
+	key := secp256k1.GenPrivKeyFromSecret([]byte("tx builder")).PubKey().Bytes()
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	pubKey, err := decodePubKey(encoded, "")
+	require.NoError(t, err)
+	require.IsType(t, &ethsecp256k1.PubKey{}, pubKey)
+	pubKey, err = decodePubKey(encoded, ethsecp256k1.KeyType)
+	require.NoError(t, err)
+	require.IsType(t, &ethsecp256k1.PubKey{}, pubKey)
+	require.Equal(t, key, pubKey.Bytes())
+	pubKey, err = decodePubKey(encoded, "secp256k1")
+	require.NoError(t, err)
+	require.Equal(t, key, pubKey.Bytes())
+
+	_, err = decodePubKey(encoded, "ed25519")
+	require.EqualError(t, err, "unsupported key type ed25519")
+	_, err = decodePubKey(base64.StdEncoding.EncodeToString(key[1:]), "")
+	require.EqualError(t, err, "invalid compressed public key length 32")
+	_, err = decodePubKey("not base64!", "")
+	require.Error(t, err)
+}
+
+func Test_ParseStakingArgs(t *testing.T) {
+	delAddr, valAddr, coin, err := parseStakingArgs(userAccount1, singaporeValidator, "10FX")
+	require.NoError(t, err)
+	require.Equal(t, userAccount1, delAddr.String())
+	require.Equal(t, singaporeValidator, valAddr.String())
+	require.Equal(t, "10FX", coin.String())
+
+	_, _, _, err = parseStakingArgs(singaporeValidator, singaporeValidator, "10FX")
+	require.Error(t, err)
+	_, _, _, err = parseStakingArgs(userAccount1, userAccount1, "10FX")
+	require.Error(t, err)
+	_, _, _, err = parseStakingArgs(userAccount1, singaporeValidator, "ten")
+	require.Error(t, err)
+}
+
+func Test_BuildSignBytes(t *testing.T) {
+	privKey := secp256k1.GenPrivKeyFromSecret([]byte("tx builder"))
+	signer := sdk.AccAddress(privKey.PubKey().Address())
+	opts := TxOptions{
+		Signer:    signer.String(),
+		PublicKey: base64.StdEncoding.EncodeToString(privKey.PubKey().Bytes()),
+		KeyType:   "secp256k1",
+		Fee:       "4FX",
+		Memo:      "memo",
+	}
+	builder := newOfflineTxBuilder()
+
+	unsigned, err := builder.Delegate(opts, singaporeValidator, "10FX")
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), unsigned.AccountNumber)
+	require.Equal(t, uint64(3), unsigned.Sequence)
+	require.Equal(t, ChainID, unsigned.ChainID)
+	require.Equal(t, uint64(7), unsigned.SignDoc.AccountNumber)
+	require.Equal(t, ChainID, unsigned.SignDoc.ChainId)
+
+	// the sign doc carries the message, the fee and the signer
+	tx, err := encodingConfig.TxConfig.TxJSONDecoder()(unsigned.Tx)
+	require.NoError(t, err)
+	msgs := tx.GetMsgs()
+	require.Len(t, msgs, 1)
+	delegate, ok := msgs[0].(*stakingtypes.MsgDelegate)
+	require.True(t, ok)
+	require.Equal(t, signer.String(), delegate.DelegatorAddress)
+	require.Equal(t, singaporeValidator, delegate.ValidatorAddress)
+	require.Equal(t, "10FX", delegate.Amount.String())
+
+	require.JSONEq(t, `{
+		"account_number": "7",
+		"chain_id": "fxcore",
+		"fee": {"amount": [{"amount": "4", "denom": "FX"}], "gas": "200000"},
+		"memo": "memo",
+		"msgs": [{"type": "cosmos-sdk/MsgDelegate", "value": {
+			"amount": {"amount": "10", "denom": "FX"},
+			"delegator_address": "`+signer.String()+`",
+			"validator_address": "`+singaporeValidator+`"
+		}}],
+		"sequence": "3"
+	}`, unsigned.AminoJSONSignBytes)
+
+	// each tx declares the sign mode of its sign bytes
+	for raw, mode := range map[string]signing.SignMode{
+		string(unsigned.Tx):      signing.SignMode_SIGN_MODE_DIRECT,
+		string(unsigned.AminoTx): signing.SignMode_SIGN_MODE_LEGACY_AMINO_JSON,
+	} {
+		var decoded struct {
+			AuthInfo struct {
+				SignerInfos []struct {
+					ModeInfo struct {
+						Single struct {
+							Mode string `json:"mode"`
+						} `json:"single"`
+					} `json:"mode_info"`
+					Sequence string `json:"sequence"`
+				} `json:"signer_infos"`
+			} `json:"auth_info"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(raw), &decoded))
+		require.Len(t, decoded.AuthInfo.SignerInfos, 1)
+		require.Equal(t, mode.String(), decoded.AuthInfo.SignerInfos[0].ModeInfo.Single.Mode)
+		require.Equal(t, "3", decoded.AuthInfo.SignerInfos[0].Sequence)
+	}
+
+	send, err := builder.BankSend(opts, userAccount1, "1FX")
+	require.NoError(t, err)
+	tx, err = encodingConfig.TxConfig.TxJSONDecoder()(send.Tx)
+	require.NoError(t, err)
+	require.IsType(t, &banktypes.MsgSend{}, tx.GetMsgs()[0])
+
+	// the key must belong to the signer, and a key is needed without one on chain
+	_, err = builder.BankSend(TxOptions{Signer: userAccount1, PublicKey: opts.PublicKey, KeyType: "secp256k1"}, userAccount1, "1FX")
+	require.EqualError(t, err, "public key does not match signer")
+	_, err = builder.BankSend(TxOptions{Signer: userAccount1}, userAccount1, "1FX")
+	require.EqualError(t, err, "signer has no public key on chain, public_key required")
+	_, err = builder.BankSend(opts, userAccount1, "0FX")
+	require.Error(t, err)
+}
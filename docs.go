@@ -0,0 +1,352 @@
+package main
+
+import (
+	"net/http"
+	"pundix-homework/analytics"
+	"pundix-homework/auth"
+	"pundix-homework/clients"
+	"pundix-homework/gql"
+	"pundix-homework/indexer"
+	"pundix-homework/openapi"
+	"pundix-homework/snapshot"
+	"pundix-homework/validators"
+	"pundix-homework/verify"
+	"pundix-homework/watch"
+	"pundix-homework/web"
+
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	openAPIPath = "/openapi.json"
+	apiTitle    = "pundix-homework"
+	apiVersion  = "1.0.0"
+)
+
+var apiSpec = openapi.New(openapi.Info{
+	Title:       apiTitle,
+	Description: "f(x)Core chain queries, unsigned tx construction, event streams and webhooks",
+	Version:     apiVersion,
+}, clients.EncodingConfig().InterfaceRegistry, apiRoutes)
+
+// queryParams are accepted by every /query route on top of its own.
+func queryParams(params ...openapi.Param) []openapi.Param {
+	return append(params,
+		openapi.Param{Name: "height", Type: "integer", Description: "block height to query at, latest when unset"},
+		snapshotParam,
+		openapi.Param{Name: web.OutputParam, Description: "response encoding, overrides the Accept header",
+			Enum: []string{string(web.OutputJSON), string(web.OutputProtoJSON), string(web.OutputAmino), string(web.OutputProto), string(web.OutputYAML), string(web.OutputCSV)}},
+		openapi.Param{Name: web.FormatParam, Description: "display converts coin amounts to display units", Enum: []string{"display"}},
+		openapi.Param{Name: web.PrecisionParam, Type: "integer", Description: "decimals of display amounts, 6 by default"},
+		openapi.Param{Name: web.SeparatorParam, Description: "thousands separator of display amounts, comma by default"},
+		openapi.Param{Name: web.FieldsParam, Description: "comma separated dotted paths to keep"},
+		openapi.Param{Name: web.PrettyParam, Type: "boolean", Description: "indent the json"},
+	)
+}
+
+var queryOutputs = []string{web.MIMEYAML, web.MIMECSV, web.MIMEProtobuf}
+
+// accountParams are accepted by every /accounts route.
+func accountParams() []openapi.Param {
+	return []openapi.Param{
+		{Name: "address", In: "path", Required: true, Description: "fx address"},
+		{Name: "from", Type: "integer", Description: "first block, the first indexed one by default"},
+		{Name: "to", Type: "integer", Description: "last block, the last indexed one by default"},
+		{Name: web.OutputParam, Description: "response encoding, overrides the Accept header", Enum: []string{string(web.OutputJSON), string(web.OutputYAML), string(web.OutputCSV)}},
+	}
+}
+
+// dashboardParams are accepted by every /validators and /analytics route.
+func dashboardParams() []openapi.Param {
+	return []openapi.Param{
+		{Name: "height", Type: "integer", Description: "block height to query at, latest when unset"},
+		snapshotParam,
+		{Name: web.OutputParam, Description: "response encoding, overrides the Accept header", Enum: []string{string(web.OutputJSON), string(web.OutputYAML), string(web.OutputCSV)}},
+	}
+}
+
+var recordOutputs = []string{web.MIMEYAML, web.MIMECSV}
+
+var snapshotParam = openapi.Param{Name: snapshot.QueryParam, Description: "token from POST /snapshot pinning the height, also read from the X-Snapshot header"}
+
+var validatorParam = openapi.Param{Name: "validator", Required: true, Description: "fxvaloper address"}
+
+var verifyParam = openapi.Param{Name: verify.QueryParam, Type: "boolean",
+	Description: "prove the response against a light client verified header, 502 when it can't be, verified responses carry X-Verified: true"}
+
+// apiRoutes describes every route, a test fails when setupRoutes registers
+// one that is missing here.
+var apiRoutes = map[string]openapi.Route{
+	"GET /ping": {
+		Summary: "ping",
+		Response: struct {
+			Message string `json:"message"`
+		}{},
+	},
+	"GET /metrics": {Summary: "prometheus metrics", Response: "", ContentType: "text/plain"},
+	"GET /healthz": {
+		Summary: "liveness, never touches the node",
+		Response: struct {
+			Status string `json:"status"`
+		}{},
+	},
+	"GET /readyz": {
+		Summary:     "readiness of the node and the cache",
+		Description: "503 when the node is down, behind, catching up or on another chain",
+		Response: struct {
+			Status       string `json:"status"`
+			Dependencies struct {
+				Node struct {
+					dependencyStatus
+					Details clients.NodeHealth `json:"details"`
+				} `json:"node"`
+				Cache dependencyStatus `json:"cache"`
+			} `json:"dependencies"`
+		}{},
+	},
+	"GET " + openAPIPath:     {Summary: "this document", Response: openapi.Document{}},
+	"GET /swagger/*filepath": {Summary: "swagger ui", Response: "", ContentType: "text/html"},
+
+	"GET /query/distribution/queryParams": {
+		Summary:  "distribution module params",
+		Params:   queryParams(),
+		Response: distrtypes.QueryParamsResponse{},
+		Outputs:  queryOutputs,
+	},
+	"GET /query/distribution/communityPool": {
+		Summary:  "community pool coins",
+		Params:   queryParams(verifyParam),
+		Response: distrtypes.QueryCommunityPoolResponse{},
+		Outputs:  queryOutputs,
+	},
+	"GET /query/distribution/validatorCommission": {
+		Summary:  "accumulated commission of a validator",
+		Params:   queryParams(validatorParam, verifyParam),
+		Response: distrtypes.QueryValidatorCommissionResponse{},
+		Outputs:  queryOutputs,
+	},
+	"GET /query/distribution/validatorOutstandingRewards": {
+		Summary:  "rewards not yet withdrawn from a validator",
+		Params:   queryParams(validatorParam, verifyParam),
+		Response: distrtypes.QueryValidatorOutstandingRewardsResponse{},
+		Outputs:  queryOutputs,
+	},
+	"GET /query/bank/balance": {
+		Summary:  "FX balance of an address",
+		Params:   queryParams(openapi.Param{Name: "address", Required: true, Description: "fx address"}, verifyParam),
+		Response: banktypes.QueryBalanceResponse{},
+		Outputs:  queryOutputs,
+	},
+	"GET /query/bank/total": {
+		Summary:  "total supply of FX",
+		Params:   queryParams(verifyParam),
+		Response: banktypes.QuerySupplyOfResponse{},
+		Outputs:  queryOutputs,
+	},
+	"POST /query/batch": {
+		Summary:     "several /query routes at one height",
+		Description: "queries run concurrently at the given height or the latest block, every result carries the status its GET request would have answered with. Each query counts against the rate limit and the daily quota and is checked against the route allowlist of the key",
+		Params: []openapi.Param{snapshotParam,
+			{Name: web.OutputParam, Description: "encoding of the results, overrides the Accept header", Enum: []string{string(web.OutputJSON), string(web.OutputProtoJSON), string(web.OutputAmino)}}},
+		Body:     batchRequest{},
+		Response: batchResponse{},
+	},
+
+	"GET /validators/:valoper/summary": {
+		Summary:     "staking, commission, rewards, uptime, slashes and self delegation of a validator",
+		Description: "404 when the validator doesn't exist, slash events are read up to the height, at most 100",
+		Params: append([]openapi.Param{{Name: "valoper", In: "path", Required: true, Description: "fxvaloper address"}},
+			dashboardParams()...),
+		Response: validators.Summary{},
+		Outputs:  recordOutputs,
+	},
+	"GET /validators/leaderboard": {
+		Summary:     "validators ranked by voting power, commission rate, uptime or slashes",
+		Description: "voting power and uptime rank the highest first, commission and slashes the lowest first, ties go to the higher voting power",
+		Params: append(dashboardParams(),
+			openapi.Param{Name: "sort", Description: "voting_power by default", Enum: []string{validators.SortVotingPower, validators.SortCommission, validators.SortUptime, validators.SortSlashes}},
+			openapi.Param{Name: "status", Description: "bonded by default", Enum: []string{"bonded", "unbonding", "unbonded", "all"}},
+		),
+		Response: leaderboardResponse{},
+		Outputs:  recordOutputs,
+	},
+
+	"GET /analytics/staking/apr": {
+		Summary:     "network APR and the APR of every bonded validator after commission",
+		Description: "annual provisions less the community tax over the bonded tokens, fees and proposer bonuses are left out, 503 while nothing is bonded",
+		Params:      dashboardParams(),
+		Response:    analytics.APR{},
+		Outputs:     recordOutputs,
+	},
+	"GET /analytics/rewards/projection": {
+		Summary:     "rewards the delegations of a delegator earn over a number of days at the current APR",
+		Description: "without compounding, delegations to validators that aren't bonded or are jailed earn nothing",
+		Params: append([]openapi.Param{
+			{Name: "delegator", Required: true, Description: "fx address"},
+			{Name: "days", Type: "integer", Description: "365 by default, at most 3650"},
+		}, dashboardParams()...),
+		Response: analytics.Projection{},
+		Outputs:  recordOutputs,
+	},
+	"GET /analytics/supply": {
+		Summary:     "FX supply broken down into bonded, not bonded, community pool, vesting, bridged and module balances",
+		Description: "circulating is the total less the community pool, the locked vesting coins and the balances of SUPPLY_EXCLUDED_ADDRESSES, bridge escrows stay circulating, amounts are in the base denom",
+		Params:      dashboardParams(),
+		Response:    analytics.Supply{},
+		Outputs:     recordOutputs,
+	},
+	"GET /analytics/supply/circulating": {
+		Summary:     "circulating FX supply in display units, as plain text",
+		Params:      []openapi.Param{{Name: "height", Type: "integer", Description: "block height to query at, latest when unset"}, snapshotParam},
+		Response:    "",
+		ContentType: "text/plain",
+	},
+
+	"GET /indexer/status": {
+		Summary:     "progress of the chain history index",
+		Description: "404 when INDEXER_PATH is unset",
+		Response:    indexer.Status{},
+	},
+	"GET /accounts/:address/history": {
+		Summary:     "balance of an address per block with a change or per day",
+		Description: "the indexed changes are undone from the balance at the last indexed block, 404 when INDEXER_PATH is unset",
+		Params: append(accountParams(),
+			openapi.Param{Name: "denom", Description: "FX by default"},
+			openapi.Param{Name: "interval", Description: "block samples every balance change, day the balance at the end of every day", Enum: []string{indexer.IntervalBlock, indexer.IntervalDay}},
+		),
+		Response: historyResponse{},
+		Outputs:  recordOutputs,
+	},
+	"GET /accounts/:address/transfers": {
+		Summary:     "transfers from and to an address, oldest first",
+		Description: "bank sends, fees, IBC transfers, bridge deposits and withdrawals and module payouts, 404 when INDEXER_PATH is unset",
+		Params: append(accountParams(),
+			openapi.Param{Name: "limit", Type: "integer", Description: "100 by default, at most 1000, pages end on a block boundary"},
+			openapi.Param{Name: "direction", Enum: []string{DirectionIn, DirectionOut, DirectionSelf}},
+			openapi.Param{Name: "kind", Enum: []string{indexer.TransferSend, indexer.TransferFee, indexer.TransferIBC, indexer.TransferBridge, indexer.TransferModule}},
+		),
+		Response: ledgerResponse{},
+		Outputs:  recordOutputs,
+	},
+
+	"POST /snapshot": {
+		Summary:     "pin reads to the latest block",
+		Description: "the token is accepted by the /query and /graphql routes until the height nears the pruning window of the node, then they answer 410",
+		Response:    snapshot.Snapshot{},
+	},
+
+	"GET /graphql": {
+		Summary:     "graphql query from the query, operationName and variables params",
+		Description: "validators, delegations and account balances, queries over the cost limit are rejected before they run",
+		Params: []openapi.Param{
+			{Name: "query", Required: true},
+			{Name: "operationName"},
+			{Name: "variables", Description: "json object"},
+			{Name: "height", Type: "integer", Description: "block height to query at, latest when unset"},
+			snapshotParam,
+		},
+		Response: graphql.Result{},
+	},
+	"POST /graphql": {
+		Summary:     "graphql query",
+		Description: "validators, delegations and account balances, queries over the cost limit are rejected before they run",
+		Params: []openapi.Param{
+			{Name: "height", Type: "integer", Description: "block height to query at, latest when unset"},
+			snapshotParam,
+		},
+		Body:     gql.Request{},
+		Response: graphql.Result{},
+	},
+
+	"POST /tx/build/:type": {
+		Summary: "build an unsigned tx for an offline signer",
+		Params: []openapi.Param{{
+			Name: "type", In: "path",
+			Enum: []string{"bank-send", "delegate", "undelegate", "redelegate", "withdraw-rewards", "withdraw-commission"},
+		}},
+		Body:     txBuildRequest{},
+		Response: clients.UnsignedTx{},
+	},
+
+	"GET /stream/blocks": {
+		Summary:     "new blocks",
+		Description: "server-sent events, or a websocket when the request asks for an upgrade",
+		Response:    "", ContentType: "text/event-stream",
+	},
+	"GET /stream/txs": {
+		Summary:     "txs, optionally filtered by an extra tendermint query",
+		Description: "server-sent events, or a websocket when the request asks for an upgrade",
+		Params:      []openapi.Param{{Name: "query", Description: "ANDed with tm.event='Tx'"}},
+		Response:    "", ContentType: "text/event-stream",
+	},
+	"GET /stream/events": {
+		Summary:     "txs with messages of a module",
+		Description: "server-sent events, or a websocket when the request asks for an upgrade",
+		Params:      []openapi.Param{{Name: "module", Required: true}},
+		Response:    "", ContentType: "text/event-stream",
+	},
+
+	"POST /watches": {
+		Summary: "create a webhook rule, the response is the only one carrying its signing secret",
+		Body:    watch.Rule{}, Response: watch.Rule{}, Status: http.StatusCreated,
+		Security: []string{openapi.SecurityAPIKey},
+	},
+	"GET /watches": {
+		Summary: "list the webhook rules of the key",
+		Response: struct {
+			Watches []watch.Rule `json:"watches"`
+		}{},
+		Security: []string{openapi.SecurityAPIKey},
+	},
+	"GET /watches/:id": {
+		Summary: "get a webhook rule", Response: watch.Rule{},
+		Security: []string{openapi.SecurityAPIKey},
+	},
+	"DELETE /watches/:id": {
+		Summary: "delete a webhook rule", Status: http.StatusNoContent,
+		Security: []string{openapi.SecurityAPIKey},
+	},
+	"GET /watches/dead-letters": {
+		Summary: "deliveries of the rules of the key that ran out of retries",
+		Response: struct {
+			DeadLetters []watch.Delivery `json:"dead_letters"`
+		}{},
+		Security: []string{openapi.SecurityAPIKey},
+	},
+	"POST /watches/dead-letters/:id/redeliver": {
+		Summary: "retry a dead letter", Status: http.StatusAccepted,
+		Security: []string{openapi.SecurityAPIKey},
+	},
+
+	"POST /admin/keys": {
+		Summary: "create an api key, the response is the only one carrying the key",
+		Body:    auth.Key{}, Response: auth.Key{}, Status: http.StatusCreated,
+		Security: []string{openapi.SecurityAdmin},
+	},
+	"GET /admin/keys": {
+		Summary: "list api keys",
+		Response: struct {
+			Keys []auth.Key `json:"keys"`
+		}{},
+		Security: []string{openapi.SecurityAdmin},
+	},
+	"GET /admin/keys/:id": {
+		Summary: "get an api key", Response: auth.Key{},
+		Security: []string{openapi.SecurityAdmin},
+	},
+	"DELETE /admin/keys/:id": {
+		Summary: "delete an api key", Status: http.StatusNoContent,
+		Security: []string{openapi.SecurityAdmin},
+	},
+	"GET /admin/keys/:id/usage": {
+		Summary: "daily usage of an api key",
+		Response: struct {
+			ID         string       `json:"id"`
+			DailyQuota int64        `json:"daily_quota"`
+			Usage      []auth.Usage `json:"usage"`
+		}{},
+		Security: []string{openapi.SecurityAdmin},
+	},
+}
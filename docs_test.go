@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pundix-homework/openapi"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OpenAPICoversRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	setupRoutes(engine)
+
+	require.Empty(t, apiSpec.Missing(engine.Routes()), "describe the routes in apiRoutes")
+}
+
+func Test_OpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	setupRoutes(engine)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Equal(t, openapi.Version, doc.OpenAPI)
+
+	op := doc.Paths["/query/bank/balance"]["get"]
+	require.NotNil(t, op)
+	require.Equal(t, "#/components/schemas/cosmos.bank.v1beta1.QueryBalanceResponse", op.Responses["200"].Content[gin.MIMEJSON].Schema.Ref)
+	coin := doc.Components.Schemas["cosmos.base.v1beta1.Coin"]
+	require.NotNil(t, coin)
+	require.Equal(t, "string", coin.Properties["amount"].Type)
+
+	require.NotNil(t, doc.Paths["/watches/{id}"]["delete"])
+	require.NotNil(t, doc.Paths["/swagger/{filepath}"]["get"])
+}
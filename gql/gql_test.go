@@ -0,0 +1,166 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	// fx address prefixes and denoms
+	_ "github.com/functionx/fx-core/app"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testValidator = "fxvaloper1a73plz6w7fc8ydlwxddanc7a239kk45jnl9xwj"
+	testDelegator = "fx15sy7ph7j6vma607y80cxdc7qg7pgvjdhnql3q6"
+)
+
+type fakeChain struct {
+	mtx   sync.Mutex
+	calls map[string]int
+}
+
+func (f *fakeChain) count(name string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.calls[name]++
+}
+
+func (f *fakeChain) DenomBalance(_ context.Context, address, denom string) (*banktypes.QueryBalanceResponse, error) {
+	f.count("balance " + address)
+	coin := sdk.NewCoin(denom, sdk.NewInt(42))
+	return &banktypes.QueryBalanceResponse{Balance: &coin}, nil
+}
+
+func (f *fakeChain) ValidatorCommission(_ context.Context, validator string) (*distrtypes.QueryValidatorCommissionResponse, error) {
+	f.count("commission")
+	return &distrtypes.QueryValidatorCommissionResponse{Commission: distrtypes.ValidatorAccumulatedCommission{
+		Commission: sdk.NewDecCoins(sdk.NewDecCoinFromDec("FX", sdk.MustNewDecFromStr("1.5"))),
+	}}, nil
+}
+
+func (f *fakeChain) ValidatorOutstandingRewards(_ context.Context, validator string) (*distrtypes.QueryValidatorOutstandingRewardsResponse, error) {
+	f.count("rewards")
+	return &distrtypes.QueryValidatorOutstandingRewardsResponse{}, nil
+}
+
+func (f *fakeChain) ValidatorSlashes(_ context.Context, validator string, startHeight, endHeight, limit uint64) (*distrtypes.QueryValidatorSlashesResponse, error) {
+	f.count("slashes")
+	return &distrtypes.QueryValidatorSlashesResponse{Slashes: []distrtypes.ValidatorSlashEvent{
+		{ValidatorPeriod: 3, Fraction: sdk.MustNewDecFromStr("0.01")},
+	}}, nil
+}
+
+func (f *fakeChain) Validator(_ context.Context, validator string) (*stakingtypes.QueryValidatorResponse, error) {
+	f.count("validator")
+	return &stakingtypes.QueryValidatorResponse{Validator: testValidatorOf(validator)}, nil
+}
+
+func (f *fakeChain) Validators(_ context.Context, status string) ([]stakingtypes.Validator, error) {
+	f.count("validators")
+	return []stakingtypes.Validator{testValidatorOf(testValidator), testValidatorOf(testValidator)}, nil
+}
+
+func (f *fakeChain) ValidatorDelegations(_ context.Context, validator string, pageReq *query.PageRequest) (*stakingtypes.QueryValidatorDelegationsResponse, error) {
+	f.count("delegations")
+	delegation := stakingtypes.DelegationResponse{
+		Delegation: stakingtypes.Delegation{DelegatorAddress: testDelegator, ValidatorAddress: validator, Shares: sdk.NewDec(10)},
+		Balance:    sdk.NewCoin("FX", sdk.NewInt(10)),
+	}
+	// the same delegator twice, its balance is fetched once
+	return &stakingtypes.QueryValidatorDelegationsResponse{
+		DelegationResponses: stakingtypes.DelegationResponses{delegation, delegation},
+		Pagination:          &query.PageResponse{NextKey: []byte{1}},
+	}, nil
+}
+
+func testValidatorOf(address string) stakingtypes.Validator {
+	return stakingtypes.Validator{
+		OperatorAddress:   address,
+		Status:            stakingtypes.Bonded,
+		Tokens:            sdk.NewInt(100),
+		DelegatorShares:   sdk.NewDec(100),
+		MinSelfDelegation: sdk.NewInt(1),
+		Description:       stakingtypes.Description{Moniker: "singapore"},
+		Commission:        stakingtypes.NewCommission(sdk.MustNewDecFromStr("0.1"), sdk.OneDec(), sdk.MustNewDecFromStr("0.01")),
+	}
+}
+
+func newTestServer(t *testing.T, cfg Config) (*Server, *fakeChain) {
+	chain := &fakeChain{calls: map[string]int{}}
+	s, err := New(cfg, chain, chain, chain)
+	require.NoError(t, err)
+	return s, chain
+}
+
+func Test_ValidatorPage(t *testing.T) {
+	s, chain := newTestServer(t, Config{MaxCost: 100, MaxDepth: 8})
+	res := s.Execute(context.Background(), Request{
+		Query: `query($v: String!) {
+			validator(address: $v) {
+				moniker
+				commissionRate
+				commission { denom amount }
+				slashes { fraction }
+				account { address balance { amount } }
+				delegations(first: 2) {
+					next
+					delegations { shares delegator { balance { denom amount } } }
+				}
+			}
+		}`,
+		Variables: map[string]interface{}{"v": testValidator},
+	})
+	require.Empty(t, res.Errors)
+
+	bz, err := json.Marshal(res.Data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"validator":{
+		"moniker":"singapore",
+		"commissionRate":"0.100000000000000000",
+		"commission":[{"denom":"FX","amount":"1.500000000000000000"}],
+		"slashes":[{"fraction":"0.010000000000000000"}],
+		"account":{"address":"fx1a73plz6w7fc8ydlwxddanc7a239kk45jmwcesj","balance":{"amount":"42"}},
+		"delegations":{"next":"AQ==","delegations":[
+			{"shares":"10.000000000000000000","delegator":{"balance":{"denom":"FX","amount":"42"}}},
+			{"shares":"10.000000000000000000","delegator":{"balance":{"denom":"FX","amount":"42"}}}
+		]}
+	}}`, string(bz))
+	require.Equal(t, 1, chain.calls["balance "+testDelegator])
+	// validator, commission, slashes, operator balance, delegations and a balance per delegation
+	require.Equal(t, 7, res.Extensions["cost"])
+}
+
+func Test_ValidatorsBatched(t *testing.T) {
+	s, chain := newTestServer(t, Config{MaxCost: 1000, MaxDepth: 8})
+	res := s.Execute(context.Background(), Request{Query: `{ validators(status: BONDED) { operatorAddress commission { amount } } }`})
+	require.Empty(t, res.Errors)
+	// both entries are the same validator, its commission is fetched once
+	require.Equal(t, 1, chain.calls["commission"])
+}
+
+func Test_CostLimits(t *testing.T) {
+	s, chain := newTestServer(t, Config{MaxCost: 50, MaxDepth: 8})
+
+	res := s.Execute(context.Background(), Request{
+		Query:     `query($n: Int) { validators { delegations(first: $n) { delegations { delegator { balance { amount } } } } } }`,
+		Variables: map[string]interface{}{"n": float64(20)},
+	})
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, "QUERY_TOO_EXPENSIVE", res.Errors[0].Extensions["code"])
+	require.Equal(t, 100*(1+20*1)+1, res.Errors[0].Extensions["value"])
+	require.Empty(t, chain.calls)
+
+	s, _ = newTestServer(t, Config{MaxCost: 50, MaxDepth: 4})
+	res = s.Execute(context.Background(), Request{
+		Query: `{ ...f } fragment f on Query { validator(address: "x") { delegations { delegations { delegator { balance { amount } } } } } }`,
+	})
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, "QUERY_TOO_DEEP", res.Errors[0].Extensions["code"])
+}
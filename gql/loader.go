@@ -0,0 +1,74 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the keys asked for while one level of the query is resolved
+// and fetches them concurrently once the first value is needed. Every key is
+// fetched at most once per request.
+type loader struct {
+	fetch    func(ctx context.Context, key string) (interface{}, error)
+	parallel int
+
+	mtx     sync.Mutex
+	pending []string
+	results map[string]*loaded
+}
+
+type loaded struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newLoader(parallel int, fetch func(ctx context.Context, key string) (interface{}, error)) *loader {
+	return &loader{fetch: fetch, parallel: parallel, results: map[string]*loaded{}}
+}
+
+// load returns a thunk, the executor calls thunks after every sibling field
+// was resolved so their keys end up in the same batch.
+func (l *loader) load(ctx context.Context, key string) func() (interface{}, error) {
+	l.mtx.Lock()
+	r, ok := l.results[key]
+	if !ok {
+		r = &loaded{done: make(chan struct{})}
+		l.results[key] = r
+		l.pending = append(l.pending, key)
+	}
+	l.mtx.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch(ctx)
+		<-r.done
+		return r.value, r.err
+	}
+}
+
+func (l *loader) dispatch(ctx context.Context) {
+	l.mtx.Lock()
+	keys := l.pending
+	l.pending = nil
+	batch := make([]*loaded, len(keys))
+	for i, key := range keys {
+		batch[i] = l.results[key]
+	}
+	l.mtx.Unlock()
+
+	sem := make(chan struct{}, l.parallel)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string, r *loaded) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			r.value, r.err = l.fetch(ctx, key)
+			close(r.done)
+		}(key, batch[i])
+	}
+	wg.Wait()
+}
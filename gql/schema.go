@@ -0,0 +1,372 @@
+package gql
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	defaultDenom    = "FX"
+)
+
+// Bank is satisfied by *clients.BankQueryClient.
+type Bank interface {
+	DenomBalance(ctx context.Context, address, denom string) (*banktypes.QueryBalanceResponse, error)
+}
+
+// Distribution is satisfied by *clients.DistributionQueryClient.
+type Distribution interface {
+	ValidatorCommission(ctx context.Context, validator string) (*distrtypes.QueryValidatorCommissionResponse, error)
+	ValidatorOutstandingRewards(ctx context.Context, validator string) (*distrtypes.QueryValidatorOutstandingRewardsResponse, error)
+	ValidatorSlashes(ctx context.Context, validator string, startHeight, endHeight, limit uint64) (*distrtypes.QueryValidatorSlashesResponse, error)
+}
+
+// Staking is satisfied by *clients.StakingQueryClient.
+type Staking interface {
+	Validator(ctx context.Context, validator string) (*stakingtypes.QueryValidatorResponse, error)
+	Validators(ctx context.Context, status string) ([]stakingtypes.Validator, error)
+	ValidatorDelegations(ctx context.Context, validator string, pageReq *query.PageRequest) (*stakingtypes.QueryValidatorDelegationsResponse, error)
+}
+
+// loaders are created per request so nothing is shared between callers.
+type loaders struct {
+	validator   *loader
+	commission  *loader
+	rewards     *loader
+	slashes     *loader
+	delegations *loader
+	balance     *loader
+}
+
+type loadersKey struct{}
+
+func (s *Server) newLoaders() *loaders {
+	p := s.cfg.Parallel
+	return &loaders{
+		validator: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			res, err := s.staking.Validator(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			return res.Validator, nil
+		}),
+		commission: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			res, err := s.distr.ValidatorCommission(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			return res.Commission.Commission, nil
+		}),
+		rewards: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			res, err := s.distr.ValidatorOutstandingRewards(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			return res.Rewards.Rewards, nil
+		}),
+		slashes: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			var (
+				validator         string
+				start, end, limit uint64
+			)
+			if _, err := fmt.Sscanf(key, "%s %d %d %d", &validator, &start, &end, &limit); err != nil {
+				return nil, err
+			}
+			res, err := s.distr.ValidatorSlashes(ctx, validator, start, end, limit)
+			if err != nil {
+				return nil, err
+			}
+			return res.Slashes, nil
+		}),
+		delegations: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			var (
+				validator, after string
+				limit            uint64
+			)
+			fields := strings.Fields(key)
+			validator = fields[0]
+			if _, err := fmt.Sscanf(fields[1], "%d", &limit); err != nil {
+				return nil, err
+			}
+			if len(fields) > 2 {
+				after = fields[2]
+			}
+			pageReq := &query.PageRequest{Limit: limit}
+			if after != "" {
+				nextKey, err := base64.StdEncoding.DecodeString(after)
+				if err != nil {
+					return nil, fmt.Errorf("invalid after cursor")
+				}
+				pageReq.Key = nextKey
+			}
+			return s.staking.ValidatorDelegations(ctx, validator, pageReq)
+		}),
+		balance: newLoader(p, func(ctx context.Context, key string) (interface{}, error) {
+			parts := strings.SplitN(key, " ", 2)
+			res, err := s.bank.DenomBalance(ctx, parts[0], parts[1])
+			if err != nil {
+				return nil, err
+			}
+			return *res.Balance, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+var validatorStatus = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ValidatorStatus",
+	Values: graphql.EnumValueConfigMap{
+		"BONDED":    {Value: stakingtypes.BondStatusBonded},
+		"UNBONDING": {Value: stakingtypes.BondStatusUnbonding},
+		"UNBONDED":  {Value: stakingtypes.BondStatusUnbonded},
+	},
+})
+
+var coinType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Coin",
+	Fields: graphql.Fields{
+		"denom": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(sdk.Coin).Denom, nil
+		}},
+		"amount": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(sdk.Coin).Amount.String(), nil
+		}},
+	},
+})
+
+var decCoinType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "DecCoin",
+	Fields: graphql.Fields{
+		"denom": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(sdk.DecCoin).Denom, nil
+		}},
+		"amount": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(sdk.DecCoin).Amount.String(), nil
+		}},
+	},
+})
+
+var slashType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Slash",
+	Fields: graphql.Fields{
+		"validatorPeriod": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return fmt.Sprint(p.Source.(distrtypes.ValidatorSlashEvent).ValidatorPeriod), nil
+		}},
+		"fraction": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(distrtypes.ValidatorSlashEvent).Fraction.String(), nil
+		}},
+	},
+})
+
+// pageArgs are the arguments of paginated lists, cost analysis multiplies the
+// cost of the items by first.
+var pageArgs = graphql.FieldConfigArgument{
+	"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+	"after": &graphql.ArgumentConfig{Type: graphql.String, Description: "next cursor of the previous page"},
+}
+
+func pageSize(p graphql.ResolveParams) (int, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return 0, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+	return first, nil
+}
+
+func (s *Server) buildSchema() (graphql.Schema, error) {
+	accountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Account",
+		Fields: graphql.Fields{
+			"address": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(string), nil
+			}},
+			"balance": &graphql.Field{
+				Type: graphql.NewNonNull(coinType),
+				Args: graphql.FieldConfigArgument{
+					"denom": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: defaultDenom},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					key := p.Source.(string) + " " + p.Args["denom"].(string)
+					return loadersFrom(p.Context).balance.load(p.Context, key), nil
+				},
+			},
+		},
+	})
+
+	delegationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Delegation",
+		Fields: graphql.Fields{
+			"delegatorAddress": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.DelegationResponse).Delegation.DelegatorAddress, nil
+			}},
+			"validatorAddress": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.DelegationResponse).Delegation.ValidatorAddress, nil
+			}},
+			"shares": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.DelegationResponse).Delegation.Shares.String(), nil
+			}},
+			"balance": &graphql.Field{Type: graphql.NewNonNull(coinType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.DelegationResponse).Balance, nil
+			}},
+			"delegator": &graphql.Field{Type: graphql.NewNonNull(accountType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.DelegationResponse).Delegation.DelegatorAddress, nil
+			}},
+		},
+	})
+
+	delegationPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DelegationPage",
+		Fields: graphql.Fields{
+			"delegations": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(delegationType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return []stakingtypes.DelegationResponse(p.Source.(*stakingtypes.QueryValidatorDelegationsResponse).DelegationResponses), nil
+			}},
+			"next": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page := p.Source.(*stakingtypes.QueryValidatorDelegationsResponse).Pagination
+				if page == nil || len(page.NextKey) == 0 {
+					return nil, nil
+				}
+				return base64.StdEncoding.EncodeToString(page.NextKey), nil
+			}},
+		},
+	})
+
+	validatorField := func(resolve func(v stakingtypes.Validator) interface{}) *graphql.Field {
+		return &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return resolve(p.Source.(stakingtypes.Validator)), nil
+		}}
+	}
+	validatorType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Validator",
+		Fields: graphql.Fields{
+			"operatorAddress": validatorField(func(v stakingtypes.Validator) interface{} { return v.OperatorAddress }),
+			"moniker":         validatorField(func(v stakingtypes.Validator) interface{} { return v.Description.Moniker }),
+			"identity":        validatorField(func(v stakingtypes.Validator) interface{} { return v.Description.Identity }),
+			"website":         validatorField(func(v stakingtypes.Validator) interface{} { return v.Description.Website }),
+			"details":         validatorField(func(v stakingtypes.Validator) interface{} { return v.Description.Details }),
+			"status":          validatorField(func(v stakingtypes.Validator) interface{} { return v.Status.String() }),
+			"tokens":          validatorField(func(v stakingtypes.Validator) interface{} { return v.Tokens.String() }),
+			"delegatorShares": validatorField(func(v stakingtypes.Validator) interface{} { return v.DelegatorShares.String() }),
+			"commissionRate":  validatorField(func(v stakingtypes.Validator) interface{} { return v.Commission.Rate.String() }),
+			"commissionMaxRate": validatorField(func(v stakingtypes.Validator) interface{} {
+				return v.Commission.MaxRate.String()
+			}),
+			"commissionMaxChangeRate": validatorField(func(v stakingtypes.Validator) interface{} {
+				return v.Commission.MaxChangeRate.String()
+			}),
+			"minSelfDelegation": validatorField(func(v stakingtypes.Validator) interface{} { return v.MinSelfDelegation.String() }),
+			"jailed": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(stakingtypes.Validator).Jailed, nil
+			}},
+			// the operator account, where self delegation and commission go
+			"account": &graphql.Field{Type: graphql.NewNonNull(accountType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				valAddr, err := sdk.ValAddressFromBech32(p.Source.(stakingtypes.Validator).OperatorAddress)
+				if err != nil {
+					return nil, err
+				}
+				return sdk.AccAddress(valAddr).String(), nil
+			}},
+			"commission": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(decCoinType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).commission.load(p.Context, p.Source.(stakingtypes.Validator).OperatorAddress), nil
+			}},
+			"outstandingRewards": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(decCoinType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).rewards.load(p.Context, p.Source.(stakingtypes.Validator).OperatorAddress), nil
+			}},
+			"slashes": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(slashType))),
+				Args: graphql.FieldConfigArgument{
+					"startHeight": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"endHeight":   &graphql.ArgumentConfig{Type: graphql.Int, Description: "latest when unset"},
+					"first":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultPageSize},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, err := pageSize(p)
+					if err != nil {
+						return nil, err
+					}
+					start, _ := p.Args["startHeight"].(int)
+					end, ok := p.Args["endHeight"].(int)
+					if !ok {
+						end = 1<<31 - 1
+					}
+					if start < 0 || end < start {
+						return nil, fmt.Errorf("invalid height range")
+					}
+					key := fmt.Sprintf("%s %d %d %d", p.Source.(stakingtypes.Validator).OperatorAddress, start, end, first)
+					return loadersFrom(p.Context).slashes.load(p.Context, key), nil
+				},
+			},
+			"delegations": &graphql.Field{
+				Type: graphql.NewNonNull(delegationPageType),
+				Args: pageArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, err := pageSize(p)
+					if err != nil {
+						return nil, err
+					}
+					key := fmt.Sprintf("%s %d", p.Source.(stakingtypes.Validator).OperatorAddress, first)
+					if after, _ := p.Args["after"].(string); after != "" {
+						key += " " + after
+					}
+					return loadersFrom(p.Context).delegations.load(p.Context, key), nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"validator": &graphql.Field{
+				Type: validatorType,
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "fxvaloper address"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).validator.load(p.Context, p.Args["address"].(string)), nil
+				},
+			},
+			"validators": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(validatorType))),
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: validatorStatus, Description: "every validator when unset"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					var status string
+					if s, ok := p.Args["status"].(stakingtypes.BondStatus); ok {
+						status = s.String()
+					}
+					return s.staking.Validators(p.Context, status)
+				},
+			},
+			"account": &graphql.Field{
+				Type: graphql.NewNonNull(accountType),
+				Args: graphql.FieldConfigArgument{
+					"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "fx address"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					address := p.Args["address"].(string)
+					if _, err := sdk.AccAddressFromBech32(address); err != nil {
+						return nil, err
+					}
+					return address, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
@@ -0,0 +1,263 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+const (
+	MaxCostEnvKey  = "GRAPHQL_MAX_COST"
+	MaxDepthEnvKey = "GRAPHQL_MAX_DEPTH"
+
+	defaultMaxCost  = 250
+	defaultMaxDepth = 8
+	defaultParallel = 8
+)
+
+// Config bounds the work a single query can make the node do.
+type Config struct {
+	// MaxCost is the most node queries a query may need, estimated before it
+	// runs with lists counted at their page size.
+	MaxCost int
+	// MaxDepth is the deepest nesting of selections.
+	MaxDepth int
+	// Parallel is how many node queries of one batch run at once.
+	Parallel int
+}
+
+// ConfigFromEnv reads GRAPHQL_MAX_COST and GRAPHQL_MAX_DEPTH.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{MaxCost: defaultMaxCost, MaxDepth: defaultMaxDepth, Parallel: defaultParallel}
+	for key, value := range map[string]*int{MaxCostEnvKey: &cfg.MaxCost, MaxDepthEnvKey: &cfg.MaxDepth} {
+		if s := os.Getenv(key); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return Config{}, fmt.Errorf("%s must be a positive integer", key)
+			}
+			*value = n
+		}
+	}
+	return cfg, nil
+}
+
+// Server executes queries over bank, distribution and staking data.
+type Server struct {
+	cfg     Config
+	schema  graphql.Schema
+	bank    Bank
+	distr   Distribution
+	staking Staking
+}
+
+func New(cfg Config, bank Bank, distr Distribution, staking Staking) (*Server, error) {
+	if cfg.Parallel <= 0 {
+		cfg.Parallel = defaultParallel
+	}
+	s := &Server{cfg: cfg, bank: bank, distr: distr, staking: staking}
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Request is a GraphQL request, from the json body of a POST or the query
+// string of a GET.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func ParseRequest(r *http.Request) (Request, error) {
+	var req Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return Request{}, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return Request{}, fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Query == "" {
+		return Request{}, errors.New("query empty")
+	}
+	return req, nil
+}
+
+// Execute runs the query once its cost and depth are within the limits.
+func (s *Server) Execute(ctx context.Context, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	cost, depth := s.analyze(doc, req.OperationName, req.Variables)
+	if depth > s.cfg.MaxDepth {
+		return rejected(fmt.Sprintf("query depth %d exceeds the limit of %d", depth, s.cfg.MaxDepth), "QUERY_TOO_DEEP", depth, s.cfg.MaxDepth)
+	}
+	if cost > s.cfg.MaxCost {
+		return rejected(fmt.Sprintf("query cost %d exceeds the limit of %d", cost, s.cfg.MaxCost), "QUERY_TOO_EXPENSIVE", cost, s.cfg.MaxCost)
+	}
+
+	res := graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, loadersKey{}, s.newLoaders()),
+	})
+	if res.Extensions == nil {
+		res.Extensions = map[string]interface{}{}
+	}
+	res.Extensions["cost"] = cost
+	return res
+}
+
+func rejected(message, code string, value, limit int) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+		Message:    message,
+		Extensions: map[string]interface{}{"code": code, "value": value, "limit": limit},
+	}}}
+}
+
+// fieldCost is the node queries one resolution of a field makes, the cost of
+// its selections is multiplied by the size of the list it returns.
+type fieldCost struct {
+	calls int
+	// sizeArg names the argument holding the page size, size is used when
+	// the list isn't paginated.
+	sizeArg string
+	size    int
+}
+
+// validatorsEstimate bounds the validator set, the node returns all of them.
+const validatorsEstimate = 100
+
+var fieldCosts = map[string]fieldCost{
+	"Query.validator":              {calls: 1},
+	"Query.validators":             {calls: 1, size: validatorsEstimate},
+	"Validator.commission":         {calls: 1},
+	"Validator.outstandingRewards": {calls: 1},
+	"Validator.slashes":            {calls: 1},
+	"Validator.delegations":        {calls: 1, sizeArg: "first", size: defaultPageSize},
+	"Account.balance":              {calls: 1},
+}
+
+// analyze estimates the node queries and the depth of the operation. Unknown
+// fields count nothing, validation reports them when the query runs.
+func (s *Server) analyze(doc *ast.Document, operationName string, vars map[string]interface{}) (int, int) {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0
+	}
+	a := analyzer{schema: s.schema, fragments: fragments, vars: vars}
+	return a.selections(operation.SelectionSet, s.schema.QueryType(), 1, map[string]bool{})
+}
+
+type analyzer struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	vars      map[string]interface{}
+}
+
+func (a analyzer) selections(set *ast.SelectionSet, parent *graphql.Object, depth int, visiting map[string]bool) (int, int) {
+	if set == nil || parent == nil {
+		return 0, depth - 1
+	}
+	cost, maxDepth := 0, depth
+	add := func(c, d int) {
+		cost += c
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			add(a.field(selection, parent, depth, visiting))
+		case *ast.InlineFragment:
+			add(a.selections(selection.SelectionSet, parent, depth, visiting))
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := a.fragments[name]
+			// cycles are invalid, validation rejects them
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			add(a.selections(fragment.SelectionSet, parent, depth, visiting))
+			delete(visiting, name)
+		}
+	}
+	return cost, maxDepth
+}
+
+func (a analyzer) field(field *ast.Field, parent *graphql.Object, depth int, visiting map[string]bool) (int, int) {
+	def, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return 0, depth
+	}
+	child, _ := graphql.GetNamed(def.Type).(*graphql.Object)
+	childCost, childDepth := a.selections(field.SelectionSet, child, depth+1, visiting)
+
+	fc := fieldCosts[parent.Name()+"."+field.Name.Value]
+	size := fc.size
+	if fc.sizeArg != "" {
+		for _, arg := range field.Arguments {
+			if arg.Name.Value == fc.sizeArg {
+				size = a.intValue(arg.Value, size)
+			}
+		}
+	}
+	if size < 1 {
+		size = 1
+	}
+	return fc.calls + size*childCost, childDepth
+}
+
+func (a analyzer) intValue(value ast.Value, fallback int) int {
+	switch value := value.(type) {
+	case *ast.IntValue:
+		if n, err := strconv.Atoi(value.Value); err == nil {
+			return n
+		}
+	case *ast.Variable:
+		switch n := a.vars[value.Name.Value].(type) {
+		case float64:
+			return int(n)
+		case int:
+			return n
+		}
+	}
+	return fallback
+}
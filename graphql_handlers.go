@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/gql"
+
+	"github.com/gin-gonic/gin"
+)
+
+var graphQLServer *gql.Server
+
+func setupGraphQL() {
+	cfg, err := gql.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	graphQLServer, err = gql.New(cfg, clients.BankQueryClientInstance, clients.DistrQueryClientInstance, clients.StakingQueryClientInstance)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// GraphQLHandler runs a query, pinned to ?height= like the /query routes.
+// Errors of single fields are reported next to the data as GraphQL does.
+func GraphQLHandler(c *gin.Context) {
+	req, err := gql.ParseRequest(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx, err := queryContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, graphQLServer.Execute(ctx, req))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"pundix-homework/clients"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	readyCheckTimeout = 3 * time.Second
+	// /readyz is unauthenticated, within readyCacheTTL probes share one
+	// check so they can't be used to load the node
+	readyCacheTTL = 2 * time.Second
+)
+
+const (
+	dependencyOK       = "ok"
+	dependencyDegraded = "degraded" // failing but requests are still served
+	dependencyDown     = "down"
+)
+
+type dependencyStatus struct {
+	Status  string      `json:"status"`
+	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// HealthzHandler reports that the process is up, it never touches the node.
+func HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": dependencyOK})
+}
+
+var readyz = newReadiness(checkReady, readyCacheTTL)
+
+// ReadyzHandler checks the node is synced, fresh and on the configured chain.
+// A failing cache only degrades readiness since queries fall through to the
+// node.
+func ReadyzHandler(c *gin.Context) {
+	code, body := readyz.get()
+	c.JSON(code, body)
+}
+
+// readiness caches the result of check for ttl, concurrent probes of an
+// expired result wait for one check.
+type readiness struct {
+	check func(ctx context.Context) (int, gin.H)
+	ttl   time.Duration
+	group singleflight.Group
+
+	mtx     sync.Mutex
+	result  readyResult
+	expires time.Time
+}
+
+type readyResult struct {
+	code int
+	body gin.H
+}
+
+func newReadiness(check func(ctx context.Context) (int, gin.H), ttl time.Duration) *readiness {
+	return &readiness{check: check, ttl: ttl}
+}
+
+func (r *readiness) get() (int, gin.H) {
+	r.mtx.Lock()
+	result, fresh := r.result, time.Now().Before(r.expires)
+	r.mtx.Unlock()
+	if !fresh {
+		// the check runs on its own context, a probe that hangs up doesn't
+		// fail it for the others
+		v, _, _ := r.group.Do("check", func() (interface{}, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), readyCheckTimeout)
+			defer cancel()
+			code, body := r.check(ctx)
+
+			r.mtx.Lock()
+			defer r.mtx.Unlock()
+			r.result, r.expires = readyResult{code: code, body: body}, time.Now().Add(r.ttl)
+			return r.result, nil
+		})
+		result = v.(readyResult)
+	}
+	return result.code, result.body
+}
+
+// checkReady asks the node and the cache backend for their status.
+func checkReady(ctx context.Context) (int, gin.H) {
+	node := clients.CheckNode(ctx)
+	nodeStatus := dependencyStatus{Status: dependencyOK, Details: node}
+	if err := node.Ready(readyMaxBlockAge, time.Now()); err != nil {
+		nodeStatus.Status = dependencyDown
+		nodeStatus.Error = err.Error()
+	}
+
+	cacheStatus := dependencyStatus{Status: dependencyOK}
+	if err := queryCache.Ping(); err != nil {
+		cacheStatus.Status = dependencyDegraded
+		cacheStatus.Error = err.Error()
+	}
+
+	status, code := dependencyOK, http.StatusOK
+	if nodeStatus.Status == dependencyDown {
+		status, code = dependencyDown, http.StatusServiceUnavailable
+	}
+	return code, gin.H{
+		"status": status,
+		"dependencies": gin.H{
+			"node":  nodeStatus,
+			"cache": cacheStatus,
+		},
+	}
+}
@@ -0,0 +1,305 @@
+package indexer
+
+import (
+	"fmt"
+	"pundix-homework/logging"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	bsctypes "github.com/functionx/fx-core/x/bsc/types"
+	crosschaintypes "github.com/functionx/fx-core/x/crosschain/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	ibctransfertypes "github.com/functionx/fx-core/x/ibc/applications/transfer/types"
+	polygontypes "github.com/functionx/fx-core/x/polygon/types"
+	trontypes "github.com/functionx/fx-core/x/tron/types"
+	abci "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// feeCollector receives the fees, failed txs pay them without emitting the
+// transfer.
+var feeCollector = authtypes.NewModuleAddress(authtypes.FeeCollectorName).String()
+
+// attribute is an event attribute of either the block results or a tx log.
+type attribute struct {
+	key, value string
+}
+
+// decodeBlock extracts the records of a block. Transfers come from the
+// events so module transfers are included, their kind from the message whose
+// log they appear in. Delegations and withdrawals come from the messages of
+// successful txs paired with the events of their log.
+func decodeBlock(block *ctypes.ResultBlock, results *ctypes.ResultBlockResults, decode sdk.TxDecoder) (*Block, error) {
+	if len(results.TxsResults) != len(block.Block.Txs) {
+		return nil, fmt.Errorf("block %d has %d txs but %d results", block.Block.Height, len(block.Block.Txs), len(results.TxsResults))
+	}
+	b := &Block{
+		Height: block.Block.Height,
+		Hash:   block.BlockID.Hash,
+		Time:   block.Block.Time,
+	}
+
+	b.addTransfers("", TransferModule, results.BeginBlockEvents)
+	for i, txBytes := range block.Block.Txs {
+		txHash := fmt.Sprintf("%X", txBytes.Hash())
+		res := results.TxsResults[i]
+		tx, err := decode(txBytes)
+		if err != nil {
+			// the events are still indexed, only the messages are lost
+			logging.L().Warn().Err(err).Int64("height", b.Height).Str("tx", txHash).Msg("decode tx")
+		}
+
+		if !res.IsOK() {
+			if feeTx, ok := tx.(sdk.FeeTx); ok && !feeTx.GetFee().IsZero() {
+				b.Transfers = append(b.Transfers, Transfer{
+					Height:    b.Height,
+					Time:      b.Time,
+					TxHash:    txHash,
+					Kind:      TransferFee,
+					Sender:    feeTx.FeePayer().String(),
+					Recipient: feeCollector,
+					Amount:    feeTx.GetFee(),
+				})
+			}
+			continue
+		}
+		first := len(b.Transfers)
+		b.addTransfers(txHash, TransferSend, res.Events)
+		if tx == nil {
+			continue
+		}
+		logs, err := sdk.ParseABCILogs(res.Log)
+		if err != nil {
+			logging.L().Warn().Err(err).Int64("height", b.Height).Str("tx", txHash).Msg("parse tx log")
+			continue
+		}
+		// the fee is paid before the messages run, its transfer is the only
+		// one missing from the message logs
+		matched := make([]bool, len(b.Transfers)-first)
+		for _, log := range logs {
+			msgs := tx.GetMsgs()
+			if int(log.MsgIndex) >= len(msgs) {
+				continue
+			}
+			b.addMsg(txHash, msgs[log.MsgIndex], log.Events)
+			kind, external := transferKind(msgs[log.MsgIndex], log.Events)
+			for _, fields := range eventFields(log.Events, banktypes.EventTypeTransfer) {
+				for j := range matched {
+					t := &b.Transfers[first+j]
+					if !matched[j] && t.Sender == fields[banktypes.AttributeKeySender] && t.Recipient == fields[banktypes.AttributeKeyRecipient] && t.Amount.String() == normalizeCoins(fields[sdk.AttributeKeyAmount]) {
+						matched[j] = true
+						t.Kind, t.ExternalAddress = kind, external
+						break
+					}
+				}
+			}
+		}
+		for j := range matched {
+			if t := &b.Transfers[first+j]; !matched[j] && t.Recipient == feeCollector {
+				t.Kind = TransferFee
+			}
+		}
+	}
+	b.addTransfers("", TransferModule, results.EndBlockEvents)
+	b.addCompletedUnbondings(results.EndBlockEvents)
+
+	// what the messages didn't tell apart is told by the module account on
+	// the other side
+	for i := range b.Transfers {
+		t := &b.Transfers[i]
+		if t.Kind != TransferSend && t.Kind != TransferModule {
+			continue
+		}
+		if kind, ok := moduleAccountKinds[t.Sender]; ok {
+			t.Kind = kind
+		} else if kind, ok = moduleAccountKinds[t.Recipient]; ok {
+			t.Kind = kind
+		}
+	}
+	return b, nil
+}
+
+func (b *Block) addTransfers(txHash, kind string, events []abci.Event) {
+	for _, event := range events {
+		if event.Type != banktypes.EventTypeTransfer {
+			continue
+		}
+		for _, fields := range split(abciAttributes(event)) {
+			amount, err := sdk.ParseCoinsNormalized(fields[sdk.AttributeKeyAmount])
+			if err != nil {
+				logging.L().Warn().Err(err).Int64("height", b.Height).Str("tx", txHash).Msg("parse transfer amount")
+				continue
+			}
+			b.Transfers = append(b.Transfers, Transfer{
+				Height:    b.Height,
+				Time:      b.Time,
+				TxHash:    txHash,
+				Kind:      kind,
+				Sender:    fields[banktypes.AttributeKeySender],
+				Recipient: fields[banktypes.AttributeKeyRecipient],
+				Amount:    amount,
+			})
+		}
+	}
+}
+
+func (b *Block) addCompletedUnbondings(events []abci.Event) {
+	for _, event := range events {
+		if event.Type != stakingtypes.EventTypeCompleteUnbonding {
+			continue
+		}
+		for _, fields := range split(abciAttributes(event)) {
+			amount, err := sdk.ParseCoinsNormalized(fields[sdk.AttributeKeyAmount])
+			if err != nil || amount.Empty() {
+				continue
+			}
+			b.addDelegation("", DelegationCompleteUnbonding, fields[stakingtypes.AttributeKeyDelegator], fields[stakingtypes.AttributeKeyValidator], "", amount[0])
+		}
+	}
+}
+
+// moduleAccountKinds maps the module accounts of the bridges and of IBC
+// vouchers to the kind of the transfers they take part in.
+var moduleAccountKinds = map[string]string{
+	authtypes.NewModuleAddress(gravitytypes.ModuleName).String():     TransferBridge,
+	authtypes.NewModuleAddress(bsctypes.ModuleName).String():         TransferBridge,
+	authtypes.NewModuleAddress(polygontypes.ModuleName).String():     TransferBridge,
+	authtypes.NewModuleAddress(trontypes.ModuleName).String():        TransferBridge,
+	authtypes.NewModuleAddress(ibctransfertypes.ModuleName).String(): TransferIBC,
+}
+
+// transferKind tells the kind of the transfers a message made and the address
+// on the other chain when the message names it.
+func transferKind(msg sdk.Msg, events sdk.StringEvents) (string, string) {
+	switch msg := msg.(type) {
+	case *ibctransfertypes.MsgTransfer:
+		return TransferIBC, msg.Receiver
+	case *gravitytypes.MsgSendToEth:
+		return TransferBridge, msg.EthDest
+	case *gravitytypes.MsgDepositClaim:
+		return TransferBridge, msg.EthSender
+	case *crosschaintypes.MsgSendToExternal:
+		return TransferBridge, msg.Dest
+	case *crosschaintypes.MsgSendToFxClaim:
+		return TransferBridge, msg.Sender
+	}
+	// a received packet doesn't carry its sender
+	for _, event := range events {
+		if event.Type == ibctransfertypes.EventTypePacket {
+			return TransferIBC, ""
+		}
+	}
+	return TransferSend, ""
+}
+
+// normalizeCoins formats an event amount the way the parsed amount of a
+// transfer prints.
+func normalizeCoins(amount string) string {
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		return amount
+	}
+	return coins.String()
+}
+
+func abciAttributes(event abci.Event) []attribute {
+	attrs := make([]attribute, 0, len(event.Attributes))
+	for _, attr := range event.Attributes {
+		attrs = append(attrs, attribute{key: string(attr.Key), value: string(attr.Value)})
+	}
+	return attrs
+}
+
+// addMsg records a staking or distribution message. Delegation changes pay
+// out the pending rewards first, they are recorded as withdrawals too.
+func (b *Block) addMsg(txHash string, msg sdk.Msg, events sdk.StringEvents) {
+	var delegator string
+	switch msg := msg.(type) {
+	case *stakingtypes.MsgDelegate:
+		delegator = msg.DelegatorAddress
+		b.addDelegation(txHash, DelegationDelegate, msg.DelegatorAddress, msg.ValidatorAddress, "", msg.Amount)
+	case *stakingtypes.MsgUndelegate:
+		delegator = msg.DelegatorAddress
+		b.addDelegation(txHash, DelegationUndelegate, msg.DelegatorAddress, msg.ValidatorAddress, "", msg.Amount)
+	case *stakingtypes.MsgBeginRedelegate:
+		delegator = msg.DelegatorAddress
+		b.addDelegation(txHash, DelegationRedelegate, msg.DelegatorAddress, msg.ValidatorSrcAddress, msg.ValidatorDstAddress, msg.Amount)
+	case *distrtypes.MsgWithdrawDelegatorReward:
+		delegator = msg.DelegatorAddress
+	case *distrtypes.MsgWithdrawValidatorCommission:
+		for _, fields := range eventFields(events, distrtypes.EventTypeWithdrawCommission) {
+			b.addWithdrawal(txHash, WithdrawalCommission, "", msg.ValidatorAddress, fields[sdk.AttributeKeyAmount])
+		}
+		return
+	default:
+		return
+	}
+	for _, fields := range eventFields(events, distrtypes.EventTypeWithdrawRewards) {
+		b.addWithdrawal(txHash, WithdrawalRewards, delegator, fields[distrtypes.AttributeKeyValidator], fields[sdk.AttributeKeyAmount])
+	}
+}
+
+func (b *Block) addDelegation(txHash, typ, delegator, validator, dstValidator string, amount sdk.Coin) {
+	b.Delegations = append(b.Delegations, Delegation{
+		Height:       b.Height,
+		Time:         b.Time,
+		TxHash:       txHash,
+		Type:         typ,
+		Delegator:    delegator,
+		Validator:    validator,
+		DstValidator: dstValidator,
+		Amount:       amount,
+	})
+}
+
+func (b *Block) addWithdrawal(txHash, typ, delegator, validator, amount string) {
+	// nothing to withdraw is an empty amount
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		logging.L().Warn().Err(err).Int64("height", b.Height).Str("tx", txHash).Msg("parse withdrawal amount")
+		return
+	}
+	b.Withdrawals = append(b.Withdrawals, Withdrawal{
+		Height:    b.Height,
+		Time:      b.Time,
+		TxHash:    txHash,
+		Type:      typ,
+		Delegator: delegator,
+		Validator: validator,
+		Amount:    coins,
+	})
+}
+
+// eventFields returns the occurrences of an event type in a message log.
+func eventFields(events sdk.StringEvents, typ string) []map[string]string {
+	for _, event := range events {
+		if event.Type != typ {
+			continue
+		}
+		attrs := make([]attribute, 0, len(event.Attributes))
+		for _, attr := range event.Attributes {
+			attrs = append(attrs, attribute{key: attr.Key, value: attr.Value})
+		}
+		return split(attrs)
+	}
+	return nil
+}
+
+// split separates occurrences of an event, the logs merge the attributes of
+// every occurrence of a type into one event. A key seen again starts the
+// next occurrence.
+func split(attrs []attribute) []map[string]string {
+	var occurrences []map[string]string
+	var fields map[string]string
+	for _, attr := range attrs {
+		if _, seen := fields[attr.key]; fields == nil || seen {
+			fields = make(map[string]string)
+			occurrences = append(occurrences, fields)
+		}
+		fields[attr.key] = attr.value
+	}
+	return occurrences
+}
@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	IntervalBlock = "block"
+	IntervalDay   = "day"
+)
+
+var (
+	// ErrTooManyPoints is returned when a history has more points than asked
+	// for.
+	ErrTooManyPoints = errors.New("too many balance points, narrow the range or sample per day")
+	// ErrUnaccounted is returned when undoing the indexed changes leaves a
+	// negative balance.
+	ErrUnaccounted = errors.New("balance changes of the address are missing from the index")
+)
+
+// BalancePoint is the balance of an address at the end of a block. Sampled
+// per day, Time is the start of the day and Height its last block with a
+// balance change, or the first block of the range.
+type BalancePoint struct {
+	Height  int64     `json:"height"`
+	Time    time.Time `json:"time"`
+	Balance sdk.Coin  `json:"balance"`
+}
+
+// BalanceHistory walks the balance of address in the denom of anchor back
+// from anchor, its balance at the end of anchorHeight, undoing the indexed
+// transfers and the delegations that move coins without one. from and to must
+// be indexed and not above anchorHeight.
+func (s *Store) BalanceHistory(address string, anchor sdk.Coin, anchorHeight, from, to int64, interval string, limit int) ([]BalancePoint, error) {
+	denom := anchor.Denom
+	deltas := map[int64]sdk.Int{}
+	times := map[int64]time.Time{}
+	change := func(height int64, t time.Time, amount sdk.Int) {
+		if amount.IsZero() {
+			return
+		}
+		if _, ok := deltas[height]; !ok {
+			deltas[height] = sdk.ZeroInt()
+		}
+		deltas[height] = deltas[height].Add(amount)
+		times[height] = t
+	}
+
+	transfers, err := s.Transfers(address, from+1, anchorHeight, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		amount := t.Amount.AmountOf(denom)
+		if t.Recipient == address {
+			change(t.Height, t.Time, amount)
+		}
+		if t.Sender == address {
+			change(t.Height, t.Time, amount.Neg())
+		}
+	}
+	// delegating and unbonding move the coins without a transfer event
+	delegations, err := s.Delegations(address, from+1, anchorHeight, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range delegations {
+		if d.Delegator != address || d.Amount.Denom != denom {
+			continue
+		}
+		switch d.Type {
+		case DelegationDelegate:
+			change(d.Height, d.Time, d.Amount.Amount.Neg())
+		case DelegationCompleteUnbonding:
+			change(d.Height, d.Time, d.Amount.Amount)
+		}
+	}
+
+	heights := make([]int64, 0, len(deltas))
+	for height := range deltas {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	balances := make(map[int64]sdk.Int, len(heights))
+	balance := anchor.Amount
+	for i := len(heights) - 1; i >= 0; i-- {
+		balances[heights[i]] = balance
+		balance = balance.Sub(deltas[heights[i]])
+	}
+	balances[from] = balance
+	for _, balance := range balances {
+		// coins moved in a way the index doesn't see, as vesting
+		if balance.IsNegative() {
+			return nil, ErrUnaccounted
+		}
+	}
+	if times[from], err = s.BlockTime(from); err != nil {
+		return nil, err
+	}
+
+	// the heights of the range with a balance change, after from
+	changes := []int64{from}
+	for _, height := range heights {
+		if height <= to {
+			changes = append(changes, height)
+		}
+	}
+
+	var points []BalancePoint
+	switch interval {
+	case IntervalDay:
+		last, err := s.BlockTime(to)
+		if err != nil {
+			return nil, err
+		}
+		i := 0
+		for day := startOfDay(times[from]); !day.After(last); day = day.AddDate(0, 0, 1) {
+			next := day.AddDate(0, 0, 1)
+			for i+1 < len(changes) && times[changes[i+1]].Before(next) {
+				i++
+			}
+			if len(points) == limit {
+				return nil, ErrTooManyPoints
+			}
+			points = append(points, BalancePoint{Height: changes[i], Time: day, Balance: sdk.NewCoin(denom, balances[changes[i]])})
+		}
+	default:
+		if changes[len(changes)-1] != to {
+			balances[to] = balances[changes[len(changes)-1]]
+			if times[to], err = s.BlockTime(to); err != nil {
+				return nil, err
+			}
+			changes = append(changes, to)
+		}
+		if len(changes) > limit {
+			return nil, ErrTooManyPoints
+		}
+		for _, height := range changes {
+			points = append(points, BalancePoint{Height: height, Time: times[height], Balance: sdk.NewCoin(denom, balances[height])})
+		}
+	}
+	return points, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,109 @@
+package indexer
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	ibctransfertypes "github.com/functionx/fx-core/x/ibc/applications/transfer/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+var day = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func coins(s string) sdk.Coins {
+	c, err := sdk.ParseCoinsNormalized(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func Test_BalanceHistory(t *testing.T) {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	blocks := []*Block{
+		{Height: 10, Time: day.Add(time.Hour)},
+		{Height: 11, Time: day.Add(2 * time.Hour), Transfers: []Transfer{{Kind: TransferSend, Sender: receiver, Recipient: delegator, Amount: coins("100FX")}}},
+		{Height: 12, Time: day.Add(26 * time.Hour), Delegations: []Delegation{{Type: DelegationDelegate, Delegator: delegator, Validator: validator, Amount: sdk.NewInt64Coin("FX", 40)}}},
+		{Height: 13, Time: day.Add(27 * time.Hour), Transfers: []Transfer{{Kind: TransferSend, Sender: delegator, Recipient: receiver, Amount: coins("5FX,3ibc/abc")}}},
+		{Height: 14, Time: day.Add(50 * time.Hour), Delegations: []Delegation{{Type: DelegationCompleteUnbonding, Delegator: delegator, Validator: validator, Amount: sdk.NewInt64Coin("FX", 10)}}},
+		{Height: 15, Time: day.Add(73 * time.Hour)},
+	}
+	for _, b := range blocks {
+		for i := range b.Transfers {
+			b.Transfers[i].Height, b.Transfers[i].Time = b.Height, b.Time
+		}
+		for i := range b.Delegations {
+			b.Delegations[i].Height, b.Delegations[i].Time = b.Height, b.Time
+		}
+		require.NoError(t, store.Put(b))
+	}
+	first, err := store.FirstHeight()
+	require.NoError(t, err)
+	require.Equal(t, int64(10), first)
+
+	// 1 + 100 - 40 - 5 + 10
+	anchor := sdk.NewInt64Coin("FX", 66)
+	points, err := store.BalanceHistory(delegator, anchor, 15, 10, 15, IntervalBlock, 100)
+	require.NoError(t, err)
+	var heights, balances []int64
+	for _, p := range points {
+		heights = append(heights, p.Height)
+		balances = append(balances, p.Balance.Amount.Int64())
+	}
+	require.Equal(t, []int64{10, 11, 12, 13, 14, 15}, heights)
+	require.Equal(t, []int64{1, 101, 61, 56, 66, 66}, balances)
+	require.Equal(t, day.Add(time.Hour), points[0].Time.UTC())
+
+	points, err = store.BalanceHistory(delegator, anchor, 15, 11, 13, IntervalBlock, 100)
+	require.NoError(t, err)
+	require.Len(t, points, 3)
+	require.Equal(t, int64(56), points[2].Balance.Amount.Int64())
+
+	// the end of every day, the last day is the one of to
+	points, err = store.BalanceHistory(delegator, anchor, 15, 10, 15, IntervalDay, 100)
+	require.NoError(t, err)
+	require.Len(t, points, 4)
+	balances = nil
+	for _, p := range points {
+		balances = append(balances, p.Balance.Amount.Int64())
+	}
+	require.Equal(t, []int64{101, 56, 66, 66}, balances)
+	require.Equal(t, day.AddDate(0, 0, 1), points[1].Time)
+	require.Equal(t, int64(13), points[1].Height)
+
+	_, err = store.BalanceHistory(delegator, anchor, 15, 10, 15, IntervalBlock, 3)
+	require.ErrorIs(t, err, ErrTooManyPoints)
+	_, err = store.BalanceHistory(delegator, sdk.NewInt64Coin("FX", 50), 15, 10, 15, IntervalBlock, 100)
+	require.ErrorIs(t, err, ErrUnaccounted)
+}
+
+func Test_TransferKinds(t *testing.T) {
+	gravity := authtypes.NewModuleAddress(gravitytypes.ModuleName).String()
+	block := &ctypes.ResultBlock{Block: &types.Block{Header: types.Header{Height: 1}}}
+	results := &ctypes.ResultBlockResults{EndBlockEvents: []abci.Event{
+		event("transfer", "recipient", delegator, "sender", gravity, "amount", "5FX"),
+		event("transfer", "recipient", delegator, "sender", receiver, "amount", "1FX"),
+	}}
+	b, err := decodeBlock(block, results, nil)
+	require.NoError(t, err)
+	require.Equal(t, TransferBridge, b.Transfers[0].Kind)
+	require.Equal(t, TransferModule, b.Transfers[1].Kind)
+
+	kind, external := transferKind(&ibctransfertypes.MsgTransfer{Receiver: "cosmos1receiver"}, nil)
+	require.Equal(t, TransferIBC, kind)
+	require.Equal(t, "cosmos1receiver", external)
+	kind, _ = transferKind(&gravitytypes.MsgSendToEth{}, nil)
+	require.Equal(t, TransferBridge, kind)
+	kind, _ = transferKind(nil, sdk.StringEvents{{Type: ibctransfertypes.EventTypePacket}})
+	require.Equal(t, TransferIBC, kind)
+}
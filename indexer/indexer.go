@@ -0,0 +1,225 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"pundix-homework/logging"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	// bbolt file of the index, the indexer is off when unset
+	PathEnvKey = "INDEXER_PATH"
+	// first block indexed on an empty store, the earliest block the node has
+	// when unset
+	StartHeightEnvKey = "INDEXER_START_HEIGHT"
+	// blocks between two supply snapshots
+	SupplyIntervalEnvKey = "INDEXER_SUPPLY_INTERVAL"
+	// blocks the indexer stays behind the head of the node
+	ConfirmationsEnvKey = "INDEXER_CONFIRMATIONS"
+
+	defaultSupplyInterval = 100
+	defaultConfirmations  = 1
+	// maxRollback bounds how far a diverging node can unwind the index
+	maxRollback = 100
+)
+
+// Config of the indexer, it is enabled when Path is set.
+type Config struct {
+	Path           string
+	StartHeight    int64
+	SupplyInterval int64
+	Confirmations  int64
+}
+
+// ConfigFromEnv reads INDEXER_PATH, INDEXER_START_HEIGHT,
+// INDEXER_SUPPLY_INTERVAL and INDEXER_CONFIRMATIONS.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Path:           os.Getenv(PathEnvKey),
+		SupplyInterval: defaultSupplyInterval,
+		Confirmations:  defaultConfirmations,
+	}
+	for key, value := range map[string]*int64{StartHeightEnvKey: &cfg.StartHeight, SupplyIntervalEnvKey: &cfg.SupplyInterval, ConfirmationsEnvKey: &cfg.Confirmations} {
+		if s := os.Getenv(key); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return Config{}, fmt.Errorf("%s must be a positive integer", key)
+			}
+			*value = n
+		}
+	}
+	return cfg, nil
+}
+
+// Node is the part of rpchttp.HTTP the indexer follows the chain with.
+type Node interface {
+	Status(ctx context.Context) (*ctypes.ResultStatus, error)
+	Block(ctx context.Context, height *int64) (*ctypes.ResultBlock, error)
+	BlockResults(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error)
+}
+
+// SupplySource returns the total supply of FX at the end of a block.
+type SupplySource interface {
+	SupplyAt(ctx context.Context, height int64) (sdk.Coin, error)
+}
+
+// Status is the progress of the indexer.
+type Status struct {
+	Height     int64     `json:"height"`
+	Hash       string    `json:"hash,omitempty"`
+	NodeHeight int64     `json:"node_height"`
+	Error      string    `json:"error,omitempty"`
+	SyncedAt   time.Time `json:"synced_at,omitempty"`
+}
+
+// Indexer follows the blocks of the node into the store. It resumes after
+// the checkpoint of the store and unwinds the blocks the node no longer has.
+type Indexer struct {
+	cfg     Config
+	chainID string
+	store   *Store
+	node    Node
+	supply  SupplySource
+	decode  sdk.TxDecoder
+
+	mtx    sync.RWMutex
+	status Status
+}
+
+func New(cfg Config, chainID string, store *Store, node Node, supply SupplySource, decode sdk.TxDecoder) *Indexer {
+	return &Indexer{cfg: cfg, chainID: chainID, store: store, node: node, supply: supply, decode: decode}
+}
+
+func (ix *Indexer) Status() Status {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	return ix.status
+}
+
+// Run syncs with the node every interval until ctx is done.
+func (ix *Indexer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ix.Sync(ctx); err != nil && ctx.Err() == nil {
+			logging.L().Error().Err(err).Msg("index blocks")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync indexes the blocks up to the head of the node minus the confirmations.
+func (ix *Indexer) Sync(ctx context.Context) error {
+	err := ix.sync(ctx)
+	ix.mtx.Lock()
+	ix.status.Error = ""
+	if err != nil {
+		ix.status.Error = err.Error()
+	} else {
+		ix.status.SyncedAt = time.Now()
+	}
+	ix.mtx.Unlock()
+	return err
+}
+
+func (ix *Indexer) sync(ctx context.Context) error {
+	status, err := ix.node.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if status.NodeInfo.Network != ix.chainID {
+		return fmt.Errorf("node is on %s, not %s", status.NodeInfo.Network, ix.chainID)
+	}
+	head := status.SyncInfo.LatestBlockHeight - ix.cfg.Confirmations
+	ix.mtx.Lock()
+	ix.status.NodeHeight = status.SyncInfo.LatestBlockHeight
+	ix.mtx.Unlock()
+
+	rolledBack := 0
+	for ctx.Err() == nil {
+		cp, err := ix.store.Checkpoint()
+		if err != nil {
+			return err
+		}
+		ix.setIndexed(cp)
+		height := cp.Height + 1
+		if cp.Height == 0 {
+			height = ix.startHeight(status)
+		}
+		if height > head {
+			return nil
+		}
+
+		block, err := ix.node.Block(ctx, &height)
+		if err != nil {
+			return err
+		}
+		if cp.Height != 0 && !bytes.Equal(block.Block.LastBlockID.Hash, cp.Hash) {
+			// the node replaced the block at the checkpoint, unwind it and
+			// check the one before
+			if rolledBack++; rolledBack > maxRollback {
+				return fmt.Errorf("node diverged from the index more than %d blocks back", maxRollback)
+			}
+			logging.L().Warn().Int64("height", cp.Height).Msg("indexed block no longer on the chain, rolling back")
+			if err = ix.store.Rollback(cp.Height); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = ix.index(ctx, block); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+func (ix *Indexer) startHeight(status *ctypes.ResultStatus) int64 {
+	if ix.cfg.StartHeight > 0 {
+		return ix.cfg.StartHeight
+	}
+	if status.SyncInfo.EarliestBlockHeight > 0 {
+		return status.SyncInfo.EarliestBlockHeight
+	}
+	return 1
+}
+
+func (ix *Indexer) index(ctx context.Context, block *ctypes.ResultBlock) error {
+	height := block.Block.Height
+	results, err := ix.node.BlockResults(ctx, &height)
+	if err != nil {
+		return err
+	}
+	b, err := decodeBlock(block, results, ix.decode)
+	if err != nil {
+		return err
+	}
+	if ix.cfg.SupplyInterval > 0 && height%ix.cfg.SupplyInterval == 0 {
+		// pruned nodes can't answer for old heights, the snapshot is skipped
+		if amount, err := ix.supply.SupplyAt(ctx, height); err != nil {
+			logging.L().Warn().Err(err).Int64("height", height).Msg("supply snapshot")
+		} else {
+			b.Supply = &Supply{Height: height, Time: b.Time, Amount: amount}
+		}
+	}
+	return ix.store.Put(b)
+}
+
+func (ix *Indexer) setIndexed(cp Checkpoint) {
+	ix.mtx.Lock()
+	ix.status.Height = cp.Height
+	ix.status.Hash = fmt.Sprintf("%X", cp.Hash)
+	ix.mtx.Unlock()
+}
@@ -0,0 +1,185 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/functionx/fx-core/app"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/p2p"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+var (
+	delegatorAddr = sdk.AccAddress("delegator___________")
+	validatorAddr = sdk.ValAddress("validator___________")
+
+	delegator = delegatorAddr.String()
+	validator = validatorAddr.String()
+	receiver  = sdk.AccAddress("receiver____________").String()
+)
+
+// chainNode serves a chain whose blocks can be replaced to fake a fork.
+type chainNode struct {
+	blocks  []*ctypes.ResultBlock
+	results []*ctypes.ResultBlockResults
+}
+
+func (n *chainNode) Status(context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{
+		NodeInfo: p2p.DefaultNodeInfo{Network: "fxcore"},
+		SyncInfo: ctypes.SyncInfo{LatestBlockHeight: int64(len(n.blocks)), EarliestBlockHeight: 1},
+	}, nil
+}
+
+func (n *chainNode) Block(_ context.Context, height *int64) (*ctypes.ResultBlock, error) {
+	return n.blocks[*height-1], nil
+}
+
+func (n *chainNode) BlockResults(_ context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+	return n.results[*height-1], nil
+}
+
+// add appends a block on top of the chain, fork marks its hash apart from a
+// block at the same height on another branch.
+func (n *chainNode) add(fork byte, txs []types.Tx, results []*abci.ResponseDeliverTx, endBlock ...abci.Event) {
+	height := int64(len(n.blocks)) + 1
+	block := &ctypes.ResultBlock{
+		BlockID: types.BlockID{Hash: []byte{byte(height), fork}},
+		Block:   &types.Block{Header: types.Header{Height: height}, Data: types.Data{Txs: txs}},
+	}
+	if height > 1 {
+		block.Block.LastBlockID = n.blocks[height-2].BlockID
+	}
+	n.blocks = append(n.blocks, block)
+	n.results = append(n.results, &ctypes.ResultBlockResults{Height: height, TxsResults: results, EndBlockEvents: endBlock})
+}
+
+type fixedSupply struct{}
+
+func (fixedSupply) SupplyAt(_ context.Context, height int64) (sdk.Coin, error) {
+	if height > 2 {
+		return sdk.Coin{}, errors.New("pruned")
+	}
+	return sdk.NewInt64Coin("FX", 1000+height), nil
+}
+
+func event(typ string, attrs ...string) abci.Event {
+	e := abci.Event{Type: typ}
+	for i := 0; i < len(attrs); i += 2 {
+		e.Attributes = append(e.Attributes, abci.EventAttribute{Key: []byte(attrs[i]), Value: []byte(attrs[i+1])})
+	}
+	return e
+}
+
+func delegateTx(t *testing.T) types.Tx {
+	txConfig := app.MakeEncodingConfig().TxConfig
+	builder := txConfig.NewTxBuilder()
+	require.NoError(t, builder.SetMsgs(stakingtypes.NewMsgDelegate(delegatorAddr, validatorAddr, sdk.NewInt64Coin("FX", 50))))
+	builder.SetFeeAmount(sdk.NewCoins(sdk.NewInt64Coin("FX", 2)))
+	bz, err := txConfig.TxEncoder()(builder.GetTx())
+	require.NoError(t, err)
+	return bz
+}
+
+func delegateLog(t *testing.T) string {
+	bz, err := json.Marshal(sdk.ABCIMessageLogs{{MsgIndex: 0, Events: sdk.StringEvents{{
+		Type:       distrtypes.EventTypeWithdrawRewards,
+		Attributes: []sdk.Attribute{{Key: "amount", Value: "7FX"}, {Key: "validator", Value: validator}},
+	}}}})
+	require.NoError(t, err)
+	return string(bz)
+}
+
+func Test_IndexerSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	store, err := OpenStore(path)
+	require.NoError(t, err)
+
+	tx := delegateTx(t)
+	node := &chainNode{}
+	node.add(0, nil, nil, event("transfer", "recipient", receiver, "sender", delegator, "amount", "5FX", "recipient", delegator, "sender", receiver, "amount", "1FX"))
+	node.add(0, []types.Tx{tx}, []*abci.ResponseDeliverTx{{Log: delegateLog(t), Events: []abci.Event{event("transfer", "recipient", delegator, "sender", validator, "amount", "7FX")}}})
+	node.add(0, []types.Tx{tx}, []*abci.ResponseDeliverTx{{Code: 5}})
+	node.add(0, nil, nil)
+
+	ix := New(Config{SupplyInterval: 1, Confirmations: 1}, "fxcore", store, node, fixedSupply{}, app.MakeEncodingConfig().TxConfig.TxDecoder())
+	require.NoError(t, ix.Sync(context.Background()))
+	require.Equal(t, int64(3), ix.Status().Height)
+
+	transfers, err := store.Transfers(delegator, 0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, transfers, 4)
+	require.Equal(t, receiver, transfers[0].Recipient)
+	require.Equal(t, TransferModule, transfers[0].Kind)
+	require.Equal(t, delegator, transfers[1].Recipient)
+	require.NotEmpty(t, transfers[2].TxHash)
+	require.Equal(t, TransferSend, transfers[2].Kind)
+	// a failed tx still pays its fee
+	require.Equal(t, feeCollector, transfers[3].Recipient)
+	require.Equal(t, TransferFee, transfers[3].Kind)
+	require.Equal(t, "2FX", transfers[3].Amount.String())
+
+	transfers, err = store.Transfers(delegator, 2, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	transfers, err = store.Transfers(delegator, 0, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+
+	delegations, err := store.Delegations(validator, 0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, delegations, 1)
+	require.Equal(t, DelegationDelegate, delegations[0].Type)
+	require.Equal(t, delegator, delegations[0].Delegator)
+	require.Equal(t, "50FX", delegations[0].Amount.String())
+
+	withdrawals, err := store.Withdrawals(delegator, 0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, withdrawals, 1)
+	require.Equal(t, WithdrawalRewards, withdrawals[0].Type)
+	require.Equal(t, "7FX", withdrawals[0].Amount.String())
+
+	// the snapshot of the pruned height is skipped
+	supplies, err := store.Supplies(0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, supplies, 2)
+	require.Equal(t, int64(1002), supplies[1].Amount.Amount.Int64())
+
+	// the node forks at height 3, the failed tx is no longer on the chain
+	node.blocks, node.results = node.blocks[:2], node.results[:2]
+	node.add(1, nil, nil)
+	node.add(1, nil, nil)
+	node.add(1, nil, nil)
+	require.NoError(t, ix.Sync(context.Background()))
+	require.Equal(t, int64(4), ix.Status().Height)
+
+	transfers, err = store.Transfers(delegator, 0, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, transfers, 3)
+	hash, err := store.BlockHash(3)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 1}, hash)
+
+	// resumes from the checkpoint after a restart
+	require.NoError(t, store.Close())
+	store, err = OpenStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	cp, err := store.Checkpoint()
+	require.NoError(t, err)
+	require.Equal(t, int64(4), cp.Height)
+}
+
+func Test_Split(t *testing.T) {
+	occurrences := split([]attribute{{"amount", "1FX"}, {"validator", "a"}, {"amount", ""}, {"validator", "b"}})
+	require.Equal(t, []map[string]string{{"amount": "1FX", "validator": "a"}, {"amount": "", "validator": "b"}}, occurrences)
+}
@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	TransferSend = "send"
+	TransferFee  = "fee"
+	// IBC transfers and bridge deposits or withdrawals have the address on
+	// the other chain as ExternalAddress when the message names it
+	TransferIBC    = "ibc"
+	TransferBridge = "bridge"
+	// made by BeginBlock or EndBlock, as rewards
+	TransferModule = "module"
+
+	DelegationDelegate   = "delegate"
+	DelegationUndelegate = "undelegate"
+	DelegationRedelegate = "redelegate"
+	// an unbonding that matured, the tokens are back in the delegator account
+	DelegationCompleteUnbonding = "complete_unbonding"
+
+	WithdrawalRewards    = "rewards"
+	WithdrawalCommission = "commission"
+)
+
+// Block is everything indexed from one block, it is stored at once.
+type Block struct {
+	Height      int64
+	Hash        []byte
+	Time        time.Time
+	Transfers   []Transfer
+	Delegations []Delegation
+	Withdrawals []Withdrawal
+	// Supply is only set every supply interval
+	Supply *Supply
+}
+
+// Transfer is a bank transfer. TxHash is empty for the ones made in
+// BeginBlock or EndBlock, as rewards and gravity deposits.
+type Transfer struct {
+	Height          int64     `json:"height"`
+	Time            time.Time `json:"time"`
+	TxHash          string    `json:"tx_hash,omitempty"`
+	Kind            string    `json:"kind"`
+	Sender          string    `json:"sender"`
+	Recipient       string    `json:"recipient"`
+	ExternalAddress string    `json:"external_address,omitempty"`
+	Amount          sdk.Coins `json:"amount"`
+}
+
+// Delegation is a delegate, undelegate or redelegate message or a completed
+// unbonding, which has no TxHash. DstValidator is only set on redelegations.
+type Delegation struct {
+	Height       int64     `json:"height"`
+	Time         time.Time `json:"time"`
+	TxHash       string    `json:"tx_hash,omitempty"`
+	Type         string    `json:"type"`
+	Delegator    string    `json:"delegator"`
+	Validator    string    `json:"validator"`
+	DstValidator string    `json:"dst_validator,omitempty"`
+	Amount       sdk.Coin  `json:"amount"`
+}
+
+// Withdrawal is a reward withdrawal of a delegator or a commission withdrawal
+// of a validator, which has no Delegator.
+type Withdrawal struct {
+	Height    int64     `json:"height"`
+	Time      time.Time `json:"time"`
+	TxHash    string    `json:"tx_hash"`
+	Type      string    `json:"type"`
+	Delegator string    `json:"delegator,omitempty"`
+	Validator string    `json:"validator"`
+	Amount    sdk.Coins `json:"amount"`
+}
+
+// Supply is the total supply of FX at the end of a block.
+type Supply struct {
+	Height int64     `json:"height"`
+	Time   time.Time `json:"time"`
+	Amount sdk.Coin  `json:"amount"`
+}
+
+// Checkpoint is the last indexed block, indexing resumes after it.
+type Checkpoint struct {
+	Height int64  `json:"height"`
+	Hash   []byte `json:"hash"`
+}
@@ -0,0 +1,402 @@
+package indexer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket   = []byte("meta")
+	blocksBucket = []byte("blocks")
+	supplyBucket = []byte("supply")
+	timesBucket  = []byte("times")
+
+	checkpointKey = []byte("checkpoint")
+)
+
+// collection is a bucket of records keyed by height and position in the
+// block, with an index bucket keyed by every address a record involves.
+type collection struct {
+	bucket    []byte
+	index     []byte
+	addresses func(v []byte) ([]string, error)
+}
+
+var (
+	transfers = collection{
+		bucket: []byte("transfers"),
+		index:  []byte("transfers_by_address"),
+		addresses: func(v []byte) ([]string, error) {
+			var t Transfer
+			err := json.Unmarshal(v, &t)
+			return []string{t.Sender, t.Recipient}, err
+		},
+	}
+	delegations = collection{
+		bucket: []byte("delegations"),
+		index:  []byte("delegations_by_address"),
+		addresses: func(v []byte) ([]string, error) {
+			var d Delegation
+			err := json.Unmarshal(v, &d)
+			return []string{d.Delegator, d.Validator, d.DstValidator}, err
+		},
+	}
+	withdrawals = collection{
+		bucket: []byte("withdrawals"),
+		index:  []byte("withdrawals_by_address"),
+		addresses: func(v []byte) ([]string, error) {
+			var w Withdrawal
+			err := json.Unmarshal(v, &w)
+			return []string{w.Delegator, w.Validator}, err
+		},
+	}
+	collections = []collection{transfers, delegations, withdrawals}
+)
+
+// Store keeps the indexed records in a bbolt file. Every block is written in
+// one transaction together with the checkpoint, so a crash never leaves a
+// half indexed block behind.
+type Store struct {
+	db *bolt.DB
+}
+
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metaBucket, blocksBucket, supplyBucket, timesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		for _, c := range collections {
+			if _, err := tx.CreateBucketIfNotExists(c.bucket); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucketIfNotExists(c.index); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Checkpoint returns the last indexed block, a zero height when nothing was
+// indexed yet.
+func (s *Store) Checkpoint() (Checkpoint, error) {
+	var cp Checkpoint
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		cp, err = checkpoint(tx)
+		return err
+	})
+	return cp, err
+}
+
+func checkpoint(tx *bolt.Tx) (Checkpoint, error) {
+	var cp Checkpoint
+	bz := tx.Bucket(metaBucket).Get(checkpointKey)
+	if bz == nil {
+		return cp, nil
+	}
+	err := json.Unmarshal(bz, &cp)
+	return cp, err
+}
+
+func setCheckpoint(tx *bolt.Tx, cp Checkpoint) error {
+	bz, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(metaBucket).Put(checkpointKey, bz)
+}
+
+// Put stores b and moves the checkpoint to it, b must follow the checkpoint.
+func (s *Store) Put(b *Block) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		cp, err := checkpoint(tx)
+		if err != nil {
+			return err
+		}
+		if cp.Height != 0 && b.Height != cp.Height+1 {
+			return fmt.Errorf("block %d doesn't follow the checkpoint at %d", b.Height, cp.Height)
+		}
+
+		for i, t := range b.Transfers {
+			if err = put(tx, transfers, recordKey(b.Height, i), t, t.Sender, t.Recipient); err != nil {
+				return err
+			}
+		}
+		for i, d := range b.Delegations {
+			if err = put(tx, delegations, recordKey(b.Height, i), d, d.Delegator, d.Validator, d.DstValidator); err != nil {
+				return err
+			}
+		}
+		for i, w := range b.Withdrawals {
+			if err = put(tx, withdrawals, recordKey(b.Height, i), w, w.Delegator, w.Validator); err != nil {
+				return err
+			}
+		}
+		if b.Supply != nil {
+			bz, err := json.Marshal(b.Supply)
+			if err != nil {
+				return err
+			}
+			if err = tx.Bucket(supplyBucket).Put(heightKey(b.Height), bz); err != nil {
+				return err
+			}
+		}
+
+		if err = tx.Bucket(blocksBucket).Put(heightKey(b.Height), b.Hash); err != nil {
+			return err
+		}
+		bz, err := b.Time.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err = tx.Bucket(timesBucket).Put(heightKey(b.Height), bz); err != nil {
+			return err
+		}
+		return setCheckpoint(tx, Checkpoint{Height: b.Height, Hash: b.Hash})
+	})
+}
+
+func put(tx *bolt.Tx, c collection, key []byte, record interface{}, addresses ...string) error {
+	bz, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = tx.Bucket(c.bucket).Put(key, bz); err != nil {
+		return err
+	}
+	index := tx.Bucket(c.index)
+	for _, address := range addresses {
+		if address == "" {
+			continue
+		}
+		if err = index.Put(indexKey(address, key), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback removes every block from height on and moves the checkpoint back
+// to the block before it.
+func (s *Store) Rollback(height int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		from := heightKey(height)
+		for _, c := range collections {
+			index := tx.Bucket(c.index)
+			cursor := tx.Bucket(c.bucket).Cursor()
+			for k, v := cursor.Seek(from); k != nil; k, v = cursor.Seek(from) {
+				addresses, err := c.addresses(v)
+				if err != nil {
+					return err
+				}
+				for _, address := range addresses {
+					if address == "" {
+						continue
+					}
+					if err = index.Delete(indexKey(address, k)); err != nil {
+						return err
+					}
+				}
+				if err = cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range [][]byte{supplyBucket, timesBucket, blocksBucket} {
+			cursor := tx.Bucket(name).Cursor()
+			for k, _ := cursor.Seek(from); k != nil; k, _ = cursor.Seek(from) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+
+		cp := Checkpoint{}
+		if hash := tx.Bucket(blocksBucket).Get(heightKey(height - 1)); hash != nil {
+			cp = Checkpoint{Height: height - 1, Hash: append([]byte(nil), hash...)}
+		}
+		return setCheckpoint(tx, cp)
+	})
+}
+
+// BlockHash returns the hash of an indexed block, nil when it wasn't indexed.
+func (s *Store) BlockHash(height int64) ([]byte, error) {
+	var hash []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(blocksBucket).Get(heightKey(height)); v != nil {
+			hash = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return hash, err
+}
+
+// FirstHeight returns the first indexed block, zero when nothing was indexed
+// yet.
+func (s *Store) FirstHeight() (int64, error) {
+	var height int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(blocksBucket).Cursor().First(); k != nil {
+			height = keyHeight(k)
+		}
+		return nil
+	})
+	return height, err
+}
+
+// BlockTime returns the time of an indexed block, the zero time when it
+// wasn't indexed.
+func (s *Store) BlockTime(height int64) (time.Time, error) {
+	var t time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(timesBucket).Get(heightKey(height)); v != nil {
+			return t.UnmarshalBinary(v)
+		}
+		return nil
+	})
+	return t, err
+}
+
+// Transfers returns the transfers from or to address between the heights,
+// oldest first. A zero to or limit is unbounded.
+func (s *Store) Transfers(address string, from, to int64, limit int) ([]Transfer, error) {
+	return s.TransfersWhere(address, from, to, limit, nil)
+}
+
+// TransfersWhere is Transfers keeping only the transfers keep accepts, limit
+// counts the kept ones. A nil keep accepts every transfer.
+func (s *Store) TransfersWhere(address string, from, to int64, limit int, keep func(Transfer) bool) ([]Transfer, error) {
+	var records []Transfer
+	err := s.scan(transfers, address, from, to, limit, func(v []byte) (bool, error) {
+		var t Transfer
+		if err := json.Unmarshal(v, &t); err != nil {
+			return false, err
+		}
+		if keep != nil && !keep(t) {
+			return false, nil
+		}
+		records = append(records, t)
+		return true, nil
+	})
+	return records, err
+}
+
+// Delegations returns the delegation messages of a delegator or involving a
+// validator between the heights, oldest first.
+func (s *Store) Delegations(address string, from, to int64, limit int) ([]Delegation, error) {
+	var records []Delegation
+	err := s.scan(delegations, address, from, to, limit, func(v []byte) (bool, error) {
+		var d Delegation
+		err := json.Unmarshal(v, &d)
+		records = append(records, d)
+		return true, err
+	})
+	return records, err
+}
+
+// Withdrawals returns the withdrawals of a delegator or from a validator
+// between the heights, oldest first.
+func (s *Store) Withdrawals(address string, from, to int64, limit int) ([]Withdrawal, error) {
+	var records []Withdrawal
+	err := s.scan(withdrawals, address, from, to, limit, func(v []byte) (bool, error) {
+		var w Withdrawal
+		err := json.Unmarshal(v, &w)
+		records = append(records, w)
+		return true, err
+	})
+	return records, err
+}
+
+// Supplies returns the supply snapshots between the heights, oldest first.
+func (s *Store) Supplies(from, to int64, limit int) ([]Supply, error) {
+	var records []Supply
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(supplyBucket).Cursor()
+		for k, v := cursor.Seek(heightKey(from)); k != nil; k, v = cursor.Next() {
+			if to > 0 && keyHeight(k) > to || limit > 0 && len(records) == limit {
+				return nil
+			}
+			var supply Supply
+			if err := json.Unmarshal(v, &supply); err != nil {
+				return err
+			}
+			records = append(records, supply)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// scan calls fn on the records of address between the heights until limit of
+// them were kept.
+func (s *Store) scan(c collection, address string, from, to int64, limit int, fn func(v []byte) (bool, error)) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(c.bucket)
+		prefix := indexKey(address, nil)
+		cursor := tx.Bucket(c.index).Cursor()
+		n := 0
+		for k, _ := cursor.Seek(indexKey(address, heightKey(from))); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			key := k[len(prefix):]
+			if to > 0 && keyHeight(key) > to || limit > 0 && n == limit {
+				return nil
+			}
+			v := records.Get(key)
+			if v == nil {
+				return fmt.Errorf("dangling index entry %x", k)
+			}
+			kept, err := fn(v)
+			if err != nil {
+				return err
+			}
+			if kept {
+				n++
+			}
+		}
+		return nil
+	})
+}
+
+func heightKey(height int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	return key
+}
+
+func keyHeight(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[:8]))
+}
+
+// recordKey orders records by height then by their position in the block.
+func recordKey(height int64, i int) []byte {
+	key := make([]byte, 12)
+	binary.BigEndian.PutUint64(key, uint64(height))
+	binary.BigEndian.PutUint32(key[8:], uint32(i))
+	return key
+}
+
+// indexKey is the address, a separator bech32 never contains, then the key
+// of the record.
+func indexKey(address string, key []byte) []byte {
+	return append(append([]byte(address), '/'), key...)
+}
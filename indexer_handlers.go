@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/indexer"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+)
+
+const indexerPollInterval = 2 * time.Second
+
+var (
+	chainIndexer *indexer.Indexer
+	indexStore   *indexer.Store
+	indexerDone  = make(chan struct{})
+)
+
+// startIndexer follows the chain into INDEXER_PATH, it does nothing when the
+// path is unset.
+func startIndexer(ctx context.Context) {
+	cfg, err := indexer.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Path == "" {
+		close(indexerDone)
+		return
+	}
+	if indexStore, err = indexer.OpenStore(cfg.Path); err != nil {
+		panic(err)
+	}
+	chainIndexer = indexer.New(cfg, clients.ChainID, indexStore, clients.RPCClientInstance, nodeSupply{},
+		clients.EncodingConfig().TxConfig.TxDecoder())
+	go func() {
+		defer close(indexerDone)
+		chainIndexer.Run(ctx, indexerPollInterval)
+	}()
+}
+
+// stopIndexer waits for the block being indexed and closes the store.
+func stopIndexer() error {
+	<-indexerDone
+	if indexStore == nil {
+		return nil
+	}
+	return indexStore.Close()
+}
+
+// nodeSupply reads the supply snapshots of the indexer from the node.
+type nodeSupply struct{}
+
+func (nodeSupply) SupplyAt(ctx context.Context, height int64) (sdk.Coin, error) {
+	res, err := clients.BankQueryClientInstance.TotalSupply(clients.WithHeight(ctx, height))
+	if err != nil {
+		return sdk.Coin{}, err
+	}
+	return res.Amount, nil
+}
+
+// IndexerStatusHandler reports how far the indexer got, 404 when it's off.
+func IndexerStatusHandler(c *gin.Context) {
+	if chainIndexer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "indexer disabled, set " + indexer.PathEnvKey})
+		return
+	}
+	c.JSON(http.StatusOK, chainIndexer.Status())
+}
@@ -0,0 +1,32 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	v := map[string]int{"a": 1}
+	require.NoError(t, Load(path, &v), "a missing file is empty")
+	require.Equal(t, map[string]int{"a": 1}, v)
+
+	require.NoError(t, Save(path, map[string]int{"b": 2}, 0o600))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	var loaded map[string]int
+	require.NoError(t, Load(path, &loaded))
+	require.Equal(t, map[string]int{"b": 2}, loaded)
+
+	// a failed rename leaves neither the temp file nor a broken store
+	dir := filepath.Join(t.TempDir(), "dir")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "store.json", "sub"), 0o700))
+	require.Error(t, Save(filepath.Join(dir, "store.json"), loaded, 0o600))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
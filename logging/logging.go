@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	EnvProduction  = "production"
+	EnvDevelopment = "development"
+)
+
+// Config selects the log level and output format. Production defaults to
+// info level JSON, development to debug level console output.
+type Config struct {
+	Env    string
+	Level  string
+	Format string // json or console
+}
+
+// ConfigFromEnv reads APP_ENV, LOG_LEVEL and LOG_FORMAT.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Env:    os.Getenv("APP_ENV"),
+		Level:  os.Getenv("LOG_LEVEL"),
+		Format: os.Getenv("LOG_FORMAT"),
+	}
+	if cfg.Env == "" {
+		cfg.Env = EnvDevelopment
+	}
+	if cfg.Level == "" {
+		cfg.Level = "debug"
+		if cfg.Env == EnvProduction {
+			cfg.Level = "info"
+		}
+	}
+	if cfg.Format == "" {
+		cfg.Format = "console"
+		if cfg.Env == EnvProduction {
+			cfg.Format = "json"
+		}
+	}
+	return cfg
+}
+
+// Setup installs the global logger, which is also returned by FromContext
+// for contexts that carry no request logger.
+func Setup(cfg Config) error {
+	level, err := zerolog.ParseLevel(strings.ToLower(cfg.Level))
+	if err != nil {
+		return err
+	}
+	var out io.Writer = os.Stdout
+	if cfg.Format == "console" {
+		out = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	log.Logger = zerolog.New(out).Level(level).With().Timestamp().Str("env", cfg.Env).Logger()
+	zerolog.DefaultContextLogger = &log.Logger
+	return nil
+}
+
+// L returns the global logger.
+func L() *zerolog.Logger {
+	return &log.Logger
+}
+
+// FromContext returns the request logger stored in ctx or the global one.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	return zerolog.Ctx(ctx)
+}
+
+type upstreamKey struct{}
+
+// Upstream accumulates the node calls made while serving one request.
+type Upstream struct {
+	mtx     sync.Mutex
+	Calls   int
+	Latency time.Duration
+	Height  int64
+}
+
+// WithUpstream attaches a fresh Upstream accumulator to ctx.
+func WithUpstream(ctx context.Context) (context.Context, *Upstream) {
+	u := &Upstream{}
+	return context.WithValue(ctx, upstreamKey{}, u), u
+}
+
+// RecordUpstream logs a node call and adds it to the request's accumulator.
+func RecordUpstream(ctx context.Context, method string, latency time.Duration, height int64, err error) {
+	if u, ok := ctx.Value(upstreamKey{}).(*Upstream); ok {
+		u.mtx.Lock()
+		u.Calls++
+		u.Latency += latency
+		if height > u.Height {
+			u.Height = height
+		}
+		u.mtx.Unlock()
+	}
+
+	event := FromContext(ctx).Debug()
+	if err != nil {
+		event = FromContext(ctx).Warn().Err(err)
+	}
+	event.Str("method", method).
+		Dur("upstream_latency", latency).
+		Int64("height", height).
+		Msg("upstream query")
+}
+
+type fieldsKey struct{}
+
+// requestFields are extra fields for the access log line, set by handlers
+// and middlewares that run inside Middleware.
+type requestFields struct {
+	mtx    sync.Mutex
+	fields map[string]string
+}
+
+// AddField adds key=value to the access log line of the request in ctx, it
+// is a no-op outside a request.
+func AddField(ctx context.Context, key, value string) {
+	f, ok := ctx.Value(fieldsKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.fields[key] = value
+}
+
+func (f *requestFields) apply(event *zerolog.Event) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for key, value := range f.fields {
+		event.Str(key, value)
+	}
+}
+
+func (u *Upstream) snapshot() (int, time.Duration, int64) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	return u.Calls, u.Latency, u.Height
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"pundix-homework/tracing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware replaces gin's default logger. It assigns every request an ID
+// (reusing the caller's X-Request-ID), stores a logger carrying it and the
+// trace id in the request context and writes one access log line per
+// request. It must run after tracing.Middleware.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		logCtx := L().With().Str("request_id", requestID).Str("route", c.FullPath())
+		if span := tracing.SpanFromContext(c.Request.Context()); span != nil {
+			logCtx = logCtx.Str("trace_id", span.Context().TraceID.String())
+		}
+		logger := logCtx.Logger()
+		ctx := logger.WithContext(c.Request.Context())
+		ctx, upstream := WithUpstream(ctx)
+		fields := &requestFields{fields: make(map[string]string)}
+		ctx = context.WithValue(ctx, fieldsKey{}, fields)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		var event *zerolog.Event
+		switch {
+		case status >= 500:
+			event = logger.Error()
+		case status >= 400:
+			event = logger.Warn()
+		default:
+			event = logger.Info()
+		}
+		calls, upstreamLatency, height := upstream.snapshot()
+		event.Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("client_ip", c.ClientIP()).
+			Int("upstream_calls", calls).
+			Dur("upstream_latency", upstreamLatency).
+			Int64("height", height).
+			Int("size", c.Writer.Size())
+		fields.apply(event)
+		if len(c.Errors) > 0 {
+			event.Str("errors", c.Errors.String())
+		}
+		event.Msg("request")
+	}
+}
+
+func newRequestID() string {
+	bz := make([]byte, 8)
+	if _, err := rand.Read(bz); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(bz)
+}
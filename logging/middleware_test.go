@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MiddlewareAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	log.Logger = zerolog.New(&out).Level(zerolog.InfoLevel)
+	zerolog.DefaultContextLogger = &log.Logger
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/query/bank/total", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		RecordUpstream(ctx, "/cosmos.bank.v1beta1.Query/TotalSupply", 3*time.Millisecond, 100, nil)
+		RecordUpstream(ctx, "/cosmos.bank.v1beta1.Query/TotalSupply", 2*time.Millisecond, 101, nil)
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/query/bank/total", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	engine.ServeHTTP(w, req)
+	require.Equal(t, "abc", w.Header().Get(RequestIDHeader))
+
+	// upstream debug lines are filtered at info level
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	require.Equal(t, "abc", line["request_id"])
+	require.Equal(t, "/query/bank/total", line["route"])
+	require.Equal(t, float64(200), line["status"])
+	require.Equal(t, float64(2), line["upstream_calls"])
+	require.Equal(t, float64(101), line["height"])
+}
+
+func Test_RecordUpstreamWithoutRequest(t *testing.T) {
+	require.NotPanics(t, func() {
+		RecordUpstream(context.Background(), "/m", time.Millisecond, 0, nil)
+	})
+}
+
+func Test_ConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_ENV", EnvProduction)
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_FORMAT", "")
+	require.Equal(t, Config{Env: EnvProduction, Level: "info", Format: "json"}, ConfigFromEnv())
+	require.Error(t, Setup(Config{Level: "loud"}))
+}
@@ -1,12 +1,199 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"pundix-homework/cache"
+	"pundix-homework/clients"
+	"pundix-homework/logging"
+	"pundix-homework/metrics"
+	"pundix-homework/ratelimit"
+	"pundix-homework/server"
+	"pundix-homework/tracing"
+	"pundix-homework/web"
+	"strconv"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// memory://?size=N, disk:///path/cache.db or redis://:password@host:port/db
+	cacheBackendEnvKey  = "CACHE_BACKEND"
+	defaultCacheBackend = "memory://?size=4096"
+	latestCacheTTL      = 6 * time.Second
+	historicalCacheTTL  = 24 * time.Hour
+	cacheFollowRetry    = 5 * time.Second
+	nodeCheckInterval   = 10 * time.Second
+
+	// how old the latest block may be before /readyz fails, as a duration
+	readyMaxBlockAgeEnvKey  = "READY_MAX_BLOCK_AGE"
+	defaultReadyMaxBlockAge = time.Minute
+
+	// file:///path/spans.jsonl or http://collector:4318, tracing is off when unset
+	traceExporterEnvKey = "TRACE_EXPORTER"
+	traceServiceName    = "pundix-homework"
+
+	// requests per second and burst of the token buckets
+	rateLimitIPEnvKey        = "RATE_LIMIT_IP"
+	rateLimitIPBurstEnvKey   = "RATE_LIMIT_IP_BURST"
+	rateLimitKeyEnvKey       = "RATE_LIMIT_KEY"
+	rateLimitKeyBurstEnvKey  = "RATE_LIMIT_KEY_BURST"
+	defaultRateLimitIP       = 10
+	defaultRateLimitIPBurst  = 20
+	defaultRateLimitKey      = 50
+	defaultRateLimitKeyBurst = 100
+
+	// concurrent ABCI queries per node, queries wait for a slot up to upstreamMaxWait
+	upstreamMaxInflightEnvKey  = "UPSTREAM_MAX_INFLIGHT"
+	defaultUpstreamMaxInflight = 16
+	upstreamMaxWait            = 2 * time.Second
+)
+
+var (
+	queryCache       *cache.Cache
+	readyMaxBlockAge = defaultReadyMaxBlockAge
+	ipLimiter        *ratelimit.Limiter
+	keyLimiter       *ratelimit.Limiter
+	tracer           *tracing.Tracer
+)
+
+func init() {
+	logCfg := logging.ConfigFromEnv()
+	if err := logging.Setup(logCfg); err != nil {
+		panic(err)
+	}
+	if logCfg.Env == logging.EnvProduction {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	exporter, err := tracing.OpenExporter(os.Getenv(traceExporterEnvKey))
+	if err != nil {
+		panic(err)
+	}
+	if exporter != nil {
+		tracer = tracing.NewTracer(traceServiceName, exporter)
+		tracing.SetGlobal(tracer)
+	}
+
+	if maxAge := os.Getenv(readyMaxBlockAgeEnvKey); maxAge != "" {
+		if readyMaxBlockAge, err = time.ParseDuration(maxAge); err != nil {
+			panic(err)
+		}
+	}
+
+	backendURL := os.Getenv(cacheBackendEnvKey)
+	if backendURL == "" {
+		backendURL = defaultCacheBackend
+	}
+	backend, err := cache.OpenBackend(backendURL)
+	if err != nil {
+		panic(err)
+	}
+	queryCache = cache.New(backend, latestCacheTTL, historicalCacheTTL)
+	metrics.RegisterCache(queryCache.Stats)
+
+	openAPIKeys()
+	setupGraphQL()
+	setupSupply()
+	setupSnapshots()
+	setupVerification()
+
+	ipLimiter = ratelimit.NewLimiter(envFloat(rateLimitIPEnvKey, defaultRateLimitIP), int(envFloat(rateLimitIPBurstEnvKey, defaultRateLimitIPBurst)))
+	keyLimiter = ratelimit.NewLimiter(envFloat(rateLimitKeyEnvKey, defaultRateLimitKey), int(envFloat(rateLimitKeyBurstEnvKey, defaultRateLimitKeyBurst)))
+	metrics.RegisterLimiter(ratelimit.ScopeIP, ipLimiter.Size)
+	metrics.RegisterLimiter(ratelimit.ScopeKey, keyLimiter.Size)
+	maxBatchQueries = int(envFloat(batchMaxQueriesEnvKey, defaultBatchMaxQueries))
+	maxAnonymousBatchQueries = int(envFloat(batchMaxAnonymousQueriesEnvKey, defaultBatchMaxAnonymousQueries))
+	clients.SetUpstreamConcurrency(int64(envFloat(upstreamMaxInflightEnvKey, defaultUpstreamMaxInflight)), upstreamMaxWait)
+}
+
+// envFloat reads a positive number from the environment.
+func envFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		panic(fmt.Sprintf("%s must be a positive number", key))
+	}
+	return f
+}
+
+// newEngine is a bare engine taking the client IP from X-Forwarded-For only
+// behind trustedProxies, the rate limits, logs and spans rely on it.
+func newEngine(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 func main() {
-	r := gin.Default()
+	srvCfg, err := server.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	corsCfg, err := web.CORSConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+
+	r, err := newEngine(srvCfg.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	r.Use(gin.Recovery(), tracing.Middleware(), logging.Middleware(), metrics.Middleware(), web.CORS(corsCfg), web.Render(clients.DenomRegistryInstance))
 	r.GET("/ping", rootHandler)
 	setupRoutes(r)
-	r.Run(":8989")
+	startWatches(ctx)
+	startIndexer(ctx)
+	go queryCache.Follow(ctx, streamHub, cacheFollowRetry)
+	go clients.MonitorNode(ctx, nodeCheckInterval)
+	go apiKeys.Run(ctx, usageFlushInterval)
+
+	srv, err := server.New(srvCfg, r)
+	if err != nil {
+		panic(err)
+	}
+	if err = srv.Run(ctx); err != nil {
+		logging.L().Error().Err(err).Msg("http server stopped")
+	}
+	// background work ends with ctx, also when the server failed on its own
+	stop()
+	shutdown()
+}
+
+// shutdown releases what outlives the requests once the server drained.
+func shutdown() {
+	// usage counted while draining
+	if err := apiKeys.Flush(); err != nil {
+		logging.L().Error().Err(err).Msg("flush api key usage")
+	}
+	if err := stopIndexer(); err != nil {
+		logging.L().Error().Err(err).Msg("close index")
+	}
+	if err := clients.Close(); err != nil {
+		logging.L().Error().Err(err).Msg("stop rpc client")
+	}
+	if tracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracer.Shutdown(ctx); err != nil {
+			logging.L().Error().Err(err).Msg("flush spans")
+		}
+	}
+	if err := queryCache.Close(); err != nil {
+		logging.L().Error().Err(err).Msg("close query cache")
+	}
+	logging.L().Info().Msg("shutdown complete")
 }
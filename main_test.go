@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"pundix-homework/auth"
+	"pundix-homework/ratelimit"
+	"pundix-homework/verify"
+	"pundix-homework/watch"
+	"pundix-homework/web"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SpoofedForwardedForKeepsBucket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	do := func(r *gin.Engine, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		// httptest requests come from 192.0.2.1
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	noKey := func(*gin.Context) string { return "" }
+
+	r, err := newEngine(nil)
+	require.NoError(t, err)
+	r.GET("/ping", ratelimit.Middleware(ratelimit.NewLimiter(0.001, 1), ratelimit.NewLimiter(0.001, 1), noKey), rootHandler)
+	require.Equal(t, http.StatusOK, do(r, "203.0.113.1"))
+	require.Equal(t, http.StatusTooManyRequests, do(r, "203.0.113.2"))
+
+	// behind a trusted proxy every forwarded client has its own bucket
+	r, err = newEngine([]string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	r.GET("/ping", ratelimit.Middleware(ratelimit.NewLimiter(0.001, 1), ratelimit.NewLimiter(0.001, 1), noKey), rootHandler)
+	require.Equal(t, http.StatusOK, do(r, "203.0.113.1"))
+	require.Equal(t, http.StatusOK, do(r, "203.0.113.2"))
+	require.Equal(t, http.StatusTooManyRequests, do(r, "203.0.113.1"))
+
+	_, err = newEngine([]string{"not an ip"})
+	require.Error(t, err)
+}
+
+func Test_RespondFlagsVerified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/query/bank/total", web.Negotiate(), verify.Middleware(), func(c *gin.Context) {
+		respond(c, &banktypes.QueryTotalSupplyResponse{Supply: sdk.NewCoins(sdk.NewInt64Coin("FX", 5))})
+	})
+	get := func(target string) string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code, target)
+		return w.Body.String()
+	}
+
+	require.JSONEq(t, `{"supply":[{"denom":"FX","amount":"5"}],"verified":true}`, get("/query/bank/total?verify=true"))
+	require.JSONEq(t, `{"supply":[{"denom":"FX","amount":"5"}]}`, get("/query/bank/total"))
+	require.JSONEq(t, `{"supply":[{"denom":"FX","amount":"5"}],"verified":true}`, get("/query/bank/total?verify=true&output=protojson"))
+	require.Contains(t, get("/query/bank/total?verify=true&output=yaml"), "verified: true")
+	require.Equal(t, "denom,amount,verified\nFX,5,true\n", get("/query/bank/total?verify=true&output=csv"))
+}
+
+func Test_WatchesScopedToKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, err := watch.OpenStore(filepath.Join(t.TempDir(), "watches.json"))
+	require.NoError(t, err)
+	savedStore, savedManager := watchStore, watchManager
+	defer func() { watchStore, watchManager = savedStore, savedManager }()
+	watchStore = store
+	watchManager = watch.NewManager(store, watch.NewDeliverer(store, 1, time.Millisecond, 1), nil, nil, nil, nil, time.Hour)
+
+	keys, err := auth.OpenStore(filepath.Join(t.TempDir(), "keys.json"))
+	require.NoError(t, err)
+	var secrets, ids []string
+	for _, name := range []string{"a", "b"} {
+		key := auth.Key{Name: name}
+		require.NoError(t, key.Validate())
+		require.NoError(t, keys.AddKey(key))
+		secrets = append(secrets, key.Secret)
+		rule := watch.Rule{Type: watch.RuleCommissionThreshold, Validator: "fxvaloper1a73plz6w7fc8ydlwxddanc7a239kk45jnl9xwj", Threshold: "10FX", WebhookURL: "http://localhost/hook"}
+		require.NoError(t, rule.Validate())
+		rule.Owner = key.ID
+		require.NoError(t, store.AddRule(rule))
+		ids = append(ids, rule.ID)
+	}
+
+	r := gin.New()
+	group := r.Group("/watches", keys.Middleware(auth.KeyRequired))
+	group.GET("", ListWatchesHandler)
+	group.GET(":id", GetWatchHandler)
+	group.DELETE(":id", DeleteWatchHandler)
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set(auth.HeaderName, secrets[0])
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/watches")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), ids[0])
+	require.NotContains(t, w.Body.String(), ids[1])
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/watches/"+ids[0]).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/watches/"+ids[1]).Code)
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/watches/"+ids[1]).Code)
+	_, err = store.Rule(ids[1])
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/watches/"+ids[0]).Code)
+}
+
+func Test_DashboardRejectsZeroHeight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/validators/leaderboard", ValidatorLeaderboardHandler)
+	r.GET("/analytics/supply", SupplyHandler)
+	for _, target := range []string{"/validators/leaderboard?height=0", "/validators/leaderboard?height=-1", "/analytics/supply?height=0"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func Test_ReadinessCached(t *testing.T) {
+	checks := 0
+	r := newReadiness(func(ctx context.Context) (int, gin.H) {
+		checks++
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+		return http.StatusServiceUnavailable, gin.H{"status": dependencyDown}
+	}, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		code, body := r.get()
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, dependencyDown, body["status"])
+	}
+	require.Equal(t, 1, checks)
+
+	time.Sleep(60 * time.Millisecond)
+	r.get()
+	require.Equal(t, 2, checks)
+}
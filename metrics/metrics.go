@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "pundix"
+
+// Registry holds every collector exposed on /metrics, it is separate from the
+// default registry so dependencies can't leak their own metrics into it.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Node gRPC query latency by node and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"node", "method"})
+
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "errors_total",
+		Help:      "Failed node gRPC queries by node, method and gRPC code.",
+	}, []string{"node", "method", "code"})
+
+	upstreamInflight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "inflight",
+		Help:      "Node gRPC queries in flight by node.",
+	}, []string{"node"})
+
+	upstreamWaiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "waiting",
+		Help:      "Node gRPC queries waiting for a concurrency slot by node.",
+	}, []string{"node"})
+
+	upstreamRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "upstream",
+		Name:      "rejected_total",
+		Help:      "Node gRPC queries rejected because the node was saturated.",
+	}, []string{"node"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ratelimit",
+		Name:      "rejected_total",
+		Help:      "Requests rejected by the rate limiter by scope (ip or key).",
+	}, []string{"scope"})
+
+	nodeUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "up",
+		Help:      "1 if the last status check of the node succeeded.",
+	}, []string{"node"})
+
+	nodeHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "node",
+		Name:      "latest_height",
+		Help:      "Latest block height reported by the node.",
+	}, []string{"node"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		upstreamDuration, upstreamErrors,
+		upstreamInflight, upstreamWaiting, upstreamRejected,
+		rateLimited,
+		nodeUp, nodeHeight,
+	)
+}
+
+// ObserveUpstream records one node query, err is classified by its gRPC code.
+func ObserveUpstream(node, method string, latency time.Duration, err error) {
+	upstreamDuration.WithLabelValues(node, method).Observe(latency.Seconds())
+	if err != nil {
+		upstreamErrors.WithLabelValues(node, method, status.Code(err).String()).Inc()
+	}
+}
+
+// UpstreamWaiting tracks queries queued for a slot on node, delta is +1 or -1.
+func UpstreamWaiting(node string, delta float64) {
+	upstreamWaiting.WithLabelValues(node).Add(delta)
+}
+
+// UpstreamInflight tracks queries running on node, delta is +1 or -1.
+func UpstreamInflight(node string, delta float64) {
+	upstreamInflight.WithLabelValues(node).Add(delta)
+}
+
+// UpstreamRejected counts a query given up on because node was saturated.
+func UpstreamRejected(node string) {
+	upstreamRejected.WithLabelValues(node).Inc()
+}
+
+// RateLimited counts a request rejected by the rate limiter.
+func RateLimited(scope string) {
+	rateLimited.WithLabelValues(scope).Inc()
+}
+
+// RegisterLimiter exposes the number of clients a rate limiter tracks.
+func RegisterLimiter(scope string, size func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   "ratelimit",
+		Name:        "tracked_clients",
+		Help:        "Clients with a token bucket by scope.",
+		ConstLabels: prometheus.Labels{"scope": scope},
+	}, func() float64 {
+		return float64(size())
+	}))
+}
+
+// SetNodeStatus records the outcome of a node status check, height is only
+// updated when the node answered.
+func SetNodeStatus(node string, up bool, height int64) {
+	if !up {
+		nodeUp.WithLabelValues(node).Set(0)
+		return
+	}
+	nodeUp.WithLabelValues(node).Set(1)
+	nodeHeight.WithLabelValues(node).Set(float64(height))
+}
+
+// RegisterCache exposes the query cache counters and its hit ratio, stats
+// is called on every scrape.
+func RegisterCache(stats func() (hits, misses, errors uint64)) {
+	counter := func(name, help string, pick func(hits, misses, errors uint64) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(pick(stats()))
+		})
+	}
+	Registry.MustRegister(
+		counter("hits_total", "Query cache hits.", func(h, _, _ uint64) uint64 { return h }),
+		counter("misses_total", "Query cache misses.", func(_, m, _ uint64) uint64 { return m }),
+		counter("errors_total", "Query cache backend errors.", func(_, _, e uint64) uint64 { return e }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hit_ratio",
+			Help:      "Query cache hits over lookups since start.",
+		}, func() float64 {
+			hits, misses, _ := stats()
+			if hits+misses == 0 {
+				return 0
+			}
+			return float64(hits) / float64(hits+misses)
+		}),
+	)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_MiddlewareLabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/query/bank/balance/:address", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	engine.GET("/metrics", Handler())
+
+	for _, path := range []string{"/query/bank/balance/a", "/query/bank/balance/b", "/nope"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	require.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues("/query/bank/balance/:address", "GET", "200")))
+	require.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues(unmatchedRoute, "GET", "404")))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `pundix_http_request_duration_seconds_count{method="GET",route="/query/bank/balance/:address"} 2`)
+}
+
+func Test_UpstreamAndNode(t *testing.T) {
+	ObserveUpstream("node", "/m", time.Millisecond, nil)
+	ObserveUpstream("node", "/m", time.Millisecond, status.Error(codes.NotFound, "x"))
+	ObserveUpstream("node", "/m", time.Millisecond, errors.New("dial"))
+	require.Equal(t, float64(1), testutil.ToFloat64(upstreamErrors.WithLabelValues("node", "/m", "NotFound")))
+	require.Equal(t, float64(1), testutil.ToFloat64(upstreamErrors.WithLabelValues("node", "/m", "Unknown")))
+
+	SetNodeStatus("node", true, 42)
+	SetNodeStatus("node", false, 0)
+	require.Equal(t, float64(0), testutil.ToFloat64(nodeUp.WithLabelValues("node")))
+	require.Equal(t, float64(42), testutil.ToFloat64(nodeHeight.WithLabelValues("node")))
+}
+
+func Test_RegisterCache(t *testing.T) {
+	RegisterCache(func() (uint64, uint64, uint64) { return 3, 1, 0 })
+	expected := `
+# HELP pundix_cache_hit_ratio Query cache hits over lookups since start.
+# TYPE pundix_cache_hit_ratio gauge
+pundix_cache_hit_ratio 0.75
+`
+	require.NoError(t, testutil.GatherAndCompare(Registry, strings.NewReader(expected), "pundix_cache_hit_ratio"))
+}
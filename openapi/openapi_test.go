@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type node struct {
+	Name     string          `json:"name"`
+	Balance  sdk.Coin        `json:"balance"`
+	Children []*node         `json:"children,omitempty"`
+	Seen     time.Time       `json:"seen"`
+	Raw      []byte          `json:"raw,omitempty"`
+	Height   uint64          `json:"height"`
+	Ignored  string          `json:"-"`
+	Key      *codectypes.Any `json:"key,omitempty"`
+}
+
+func Test_SchemaOf(t *testing.T) {
+	registry := codectypes.NewInterfaceRegistry()
+	g := newGenerator(registry)
+	ref := g.schemaOf(reflect.TypeOf(&node{}))
+	require.Equal(t, "#/components/schemas/openapi.node", ref.Ref)
+
+	s := g.components["openapi.node"]
+	require.Equal(t, []string{"balance", "height", "name", "seen"}, s.Required)
+	require.NotContains(t, s.Properties, "Ignored")
+	require.Equal(t, "#/components/schemas/openapi.node", s.Properties["children"].Items.Ref)
+	require.Equal(t, "date-time", s.Properties["seen"].Format)
+	require.Equal(t, "byte", s.Properties["raw"].Format)
+	require.Equal(t, "int64", s.Properties["height"].Format)
+	require.Equal(t, "#/components/schemas/cosmos.base.v1beta1.Coin", s.Properties["balance"].Ref)
+	require.Equal(t, "#/components/schemas/google.protobuf.Any", s.Properties["key"].Ref)
+
+	// sdk.Int marshals itself as a string
+	coin := g.components["cosmos.base.v1beta1.Coin"]
+	require.Equal(t, "string", coin.Properties["amount"].Type)
+}
+
+func Test_OpenAPIPath(t *testing.T) {
+	path, params := openAPIPath("/watches/dead-letters/:id/redeliver")
+	require.Equal(t, "/watches/dead-letters/{id}/redeliver", path)
+	require.Equal(t, []string{"id"}, params)
+	require.Equal(t, "postWatchesDeadLettersIdRedeliver", operationID(http.MethodPost, "/watches/dead-letters/:id/redeliver"))
+}
+
+func Test_Missing(t *testing.T) {
+	spec := New(Info{}, codectypes.NewInterfaceRegistry(), map[string]Route{"GET /a": {}})
+	routes := gin.RoutesInfo{{Method: http.MethodGet, Path: "/a"}, {Method: http.MethodPost, Path: "/a"}}
+	require.Equal(t, []string{"POST /a"}, spec.Missing(routes))
+
+	doc := spec.Document(routes)
+	require.Len(t, doc.Paths["/a"], 1)
+}
+
+func Test_SwaggerUI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/swagger/*filepath", SwaggerUI("api", "/openapi.json"))
+
+	for target, status := range map[string]int{
+		"/swagger/":                  http.StatusOK,
+		"/swagger/swagger-ui.css":    http.StatusOK,
+		"/swagger/swagger.yaml":      http.StatusNotFound,
+		"/swagger/favicon-32x32.png": http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, status, w.Code, target)
+		if target == "/swagger/" {
+			require.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/gogo/protobuf/proto"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	anyType           = reflect.TypeOf(codectypes.Any{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	protoMessageType  = reflect.TypeOf((*proto.Message)(nil)).Elem()
+)
+
+// generator reflects Go types into schemas the way encoding/json writes them,
+// the default output. Named structs become components, proto messages under
+// their full proto name.
+type generator struct {
+	registry   codectypes.InterfaceRegistry
+	components map[string]*Schema
+}
+
+func newGenerator(registry codectypes.InterfaceRegistry) *generator {
+	return &generator{registry: registry, components: map[string]*Schema{}}
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case rawMessageType:
+		return &Schema{}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case anyType:
+		return g.component(t, g.anySchema)
+	}
+	if t.Kind() == reflect.Ptr {
+		return g.schemaOf(t.Elem())
+	}
+	// sdk.Int, sdk.Dec and addresses write themselves as strings
+	if implements(t, jsonMarshalerType) || implements(t, textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.component(t, func() *Schema { return g.object(t) })
+	}
+	// interfaces and anything json can't tell in advance
+	return &Schema{}
+}
+
+// component registers the schema once under the type's name and refers to it,
+// the placeholder stops recursive types from looping.
+func (g *generator) component(t reflect.Type, build func() *Schema) *Schema {
+	name := componentName(t)
+	if _, ok := g.components[name]; !ok {
+		g.components[name] = &Schema{}
+		*g.components[name] = *build()
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func componentName(t reflect.Type) string {
+	if reflect.PtrTo(t).Implements(protoMessageType) {
+		if name := proto.MessageName(reflect.New(t).Interface().(proto.Message)); name != "" {
+			return name
+		}
+	}
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(t, s)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx:]
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.fields(embedded, s)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schemaOf(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// anySchema lists every implementation known to the interface registry, the
+// type a packed value can hold.
+func (g *generator) anySchema() *Schema {
+	seen := map[string]bool{}
+	var urls []string
+	for _, iface := range g.registry.ListAllInterfaces() {
+		for _, url := range g.registry.ListImplementations(iface) {
+			if !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	sort.Strings(urls)
+	return &Schema{
+		Type:        "object",
+		Description: "packed interface value, {\"type\",\"value\"} in json output and {\"@type\",...} in protojson output",
+		Properties: map[string]*Schema{
+			"@type": {Type: "string", Enum: urls},
+		},
+		AdditionalProperties: &Schema{},
+	}
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
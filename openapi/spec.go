@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "3.0.3"
+
+// Security schemes a route can require.
+const (
+	SecurityAPIKey = "apiKey"
+	SecurityAdmin  = "adminToken"
+)
+
+// Param is a query or path parameter, path parameters not listed are added
+// from the route as required strings.
+type Param struct {
+	Name        string
+	In          string // query (default) or path
+	Description string
+	Required    bool
+	Type        string // string (default), integer or boolean
+	Enum        []string
+}
+
+// Route describes the operation registered for a method and path.
+type Route struct {
+	Summary     string
+	Description string
+	Params      []Param
+	// Body and Response are values of the request and success response types,
+	// nil when there is no body.
+	Body     interface{}
+	Response interface{}
+	// Status of a successful response, 200 by default.
+	Status int
+	// ContentType of a successful response, application/json by default.
+	ContentType string
+	// Outputs lists the extra media types the response can be negotiated to.
+	Outputs  []string
+	Security []string
+}
+
+// Spec builds the document from the routes registered in the engine, keyed by
+// "METHOD /path" as gin reports them.
+type Spec struct {
+	info     Info
+	registry codectypes.InterfaceRegistry
+	routes   map[string]Route
+}
+
+func New(info Info, registry codectypes.InterfaceRegistry, routes map[string]Route) *Spec {
+	return &Spec{info: info, registry: registry, routes: routes}
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// Missing lists the registered routes without a description.
+func (s *Spec) Missing(routes gin.RoutesInfo) []string {
+	var missing []string
+	for _, r := range routes {
+		if _, ok := s.routes[routeKey(r.Method, r.Path)]; !ok {
+			missing = append(missing, routeKey(r.Method, r.Path))
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Document describes the registered routes, routes without a description
+// are left out.
+func (s *Spec) Document(routes gin.RoutesInfo) *Document {
+	g := newGenerator(s.registry)
+	g.components["Error"] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"error": {Type: "string"}},
+		Required:   []string{"error"},
+	}
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: g.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				SecurityAPIKey: {Type: "apiKey", Name: "X-API-Key", In: "header"},
+				SecurityAdmin:  {Type: "http", Scheme: "bearer"},
+			},
+		},
+	}
+	for _, r := range routes {
+		route, ok := s.routes[routeKey(r.Method, r.Path)]
+		if !ok {
+			continue
+		}
+		path, pathParams := openAPIPath(r.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = PathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(r.Method)] = g.operation(r.Method, r.Path, pathParams, route)
+	}
+	return doc
+}
+
+func (g *generator) operation(method, path string, pathParams []string, route Route) *Operation {
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		OperationID: operationID(method, path),
+		Responses:   map[string]Response{},
+	}
+	if segments := strings.Split(strings.Trim(path, "/"), "/"); segments[0] != "" {
+		op.Tags = []string{segments[0]}
+	}
+
+	declared := map[string]bool{}
+	for _, p := range route.Params {
+		in := p.In
+		if in == "" {
+			in = "query"
+		}
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		declared[p.Name] = in == "path"
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        p.Name,
+			In:          in,
+			Description: p.Description,
+			Required:    p.Required || in == "path",
+			Schema:      &Schema{Type: typ, Enum: p.Enum},
+		})
+	}
+	for _, name := range pathParams {
+		if !declared[name] {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{gin.MIMEJSON: {Schema: g.schemaOf(reflect.TypeOf(route.Body))}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = gin.MIMEJSON
+		}
+		schema := g.schemaOf(reflect.TypeOf(route.Response))
+		success.Content = map[string]MediaType{contentType: {Schema: schema}}
+		for _, output := range route.Outputs {
+			success.Content[output] = MediaType{}
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = Response{
+		Description: "error",
+		Content:     map[string]MediaType{gin.MIMEJSON: {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+	}
+
+	for _, scheme := range route.Security {
+		op.Security = append(op.Security, map[string][]string{scheme: {}})
+	}
+	return op
+}
+
+// openAPIPath turns gin's :name and *name segments into {name}.
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '*' || r == '-' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+// Handler serves the document, built on the first request once every route
+// is registered.
+func (s *Spec) Handler(routes func() gin.RoutesInfo) gin.HandlerFunc {
+	var (
+		once sync.Once
+		doc  *Document
+	)
+	return func(c *gin.Context) {
+		once.Do(func() { doc = s.Document(routes()) })
+		c.JSON(http.StatusOK, doc)
+	}
+}
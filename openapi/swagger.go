@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	// swagger ui assets bundled with the sdk
+	_ "github.com/cosmos/cosmos-sdk/client/docs/statik"
+	"github.com/gin-gonic/gin"
+	"github.com/rakyll/statik/fs"
+)
+
+var swaggerPage = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" type="text/css" href="swagger-ui.css">
+  <link rel="icon" type="image/png" href="favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="swagger-ui-bundle.js"></script>
+  <script src="swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: {{.SpecURL}},
+        dom_id: "#swagger-ui",
+        deepLinking: true,
+        presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+        layout: "StandaloneLayout"
+      })
+    }
+  </script>
+</body>
+</html>
+`))
+
+// SwaggerUI serves the Swagger UI page for the document at specURL, mounted
+// on a */filepath route. Only the ui assets are served, not the sdk's own
+// swagger.yaml.
+func SwaggerUI(title, specURL string) gin.HandlerFunc {
+	assets, err := fs.New()
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServer(assets)
+	return func(c *gin.Context) {
+		name := c.Param("filepath")
+		switch {
+		case name == "/" || name == "/index.html":
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Status(http.StatusOK)
+			_ = swaggerPage.Execute(c.Writer, struct{ Title, SpecURL string }{title, specURL})
+		case strings.HasPrefix(name, "/swagger-ui") || strings.HasPrefix(name, "/favicon"):
+			c.Request.URL.Path = name
+			files.ServeHTTP(c.Writer, c.Request)
+		default:
+			c.Status(http.StatusNotFound)
+		}
+	}
+}
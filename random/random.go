@@ -0,0 +1,17 @@
+// Package random makes the ids and secrets handed out by the service.
+package random
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Hex returns n random bytes hex encoded. It panics when the OS has no
+// entropy source, an id or secret must never be predictable.
+func Hex(n int) string {
+	bz := make([]byte, n)
+	if _, err := rand.Read(bz); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bz)
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleTTL is how long a client may stay silent before its bucket, full by
+// then, is forgotten.
+const idleTTL = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a set of token buckets keyed by client, each refilled at rate
+// tokens per second up to burst.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mtx       sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from the bucket of key. When it is empty it returns
+// how long until the next token is available.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	return l.AllowN(key, 1, now)
+}
+
+// AllowN takes n tokens at once from the bucket of key, or none. More than
+// the burst is never allowed.
+func (l *Limiter) AllowN(key string, n int, now time.Time) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		return true, 0
+	}
+	wait := time.Duration((float64(n) - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Burst is the most tokens a bucket holds.
+func (l *Limiter) Burst() int {
+	return int(l.burst)
+}
+
+// Size returns the number of clients currently tracked.
+func (l *Limiter) Size() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.buckets)
+}
+
+// sweep drops idle buckets at most once per idleTTL. Callers hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTTL {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
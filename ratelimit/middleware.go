@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"pundix-homework/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ScopeIP  = "ip"
+	ScopeKey = "key"
+)
+
+// Middleware limits requests authenticated with an API key per key and
+// anonymous requests per client IP. keyID returns the key of the request,
+// so it has to run after the auth middleware.
+func Middleware(perIP, perKey *Limiter, keyID func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if Charge(c, perIP, perKey, keyID, 1) {
+			c.Next()
+		}
+	}
+}
+
+// Charge takes n tokens for the request from the bucket Middleware uses for
+// it. When they aren't available it answers 429 and returns false.
+func Charge(c *gin.Context, perIP, perKey *Limiter, keyID func(c *gin.Context) string, n int) bool {
+	scope, limiter, client := ScopeIP, perIP, c.ClientIP()
+	if id := keyID(c); id != "" {
+		scope, limiter, client = ScopeKey, perKey, id
+	}
+
+	ok, wait := limiter.AllowN(client, n, time.Now())
+	if !ok {
+		metrics.RateLimited(scope)
+		RetryAfter(c, wait)
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+	}
+	return ok
+}
+
+// RetryAfter sets the Retry-After header to wait rounded up to a second.
+func RetryAfter(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LimiterRefills(t *testing.T) {
+	l := NewLimiter(2, 2)
+	now := time.Now()
+
+	ok, _ := l.Allow("a", now)
+	require.True(t, ok)
+	ok, _ = l.Allow("a", now)
+	require.True(t, ok)
+	ok, wait := l.Allow("a", now)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// other clients have their own bucket
+	ok, _ = l.Allow("b", now)
+	require.True(t, ok)
+
+	ok, _ = l.Allow("a", now.Add(wait))
+	require.True(t, ok)
+	require.Equal(t, 2, l.Size())
+
+	l.Allow("c", now.Add(2*idleTTL))
+	require.Equal(t, 1, l.Size())
+
+	// n tokens at once or none
+	later := now.Add(2 * idleTTL)
+	ok, wait = l.AllowN("c", 2, later)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+	ok, _ = l.AllowN("c", 1, later)
+	require.True(t, ok)
+	require.Equal(t, 2, l.Burst())
+}
+
+func Test_MiddlewareScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	perIP, perKey := NewLimiter(0.001, 1), NewLimiter(0.001, 2)
+	engine := gin.New()
+	engine.GET("/query/bank/total", Middleware(perIP, perKey, func(c *gin.Context) string {
+		return c.GetHeader("X-Test-Key")
+	}), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	do := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/query/bank/total", nil)
+		req.Header.Set("X-Test-Key", key)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, do("").Code)
+	limited := do("")
+	require.Equal(t, http.StatusTooManyRequests, limited.Code)
+	require.Equal(t, "1000", limited.Header().Get("Retry-After"))
+
+	// a key is not limited by the bucket of its IP
+	require.Equal(t, http.StatusOK, do("k1").Code)
+	require.Equal(t, http.StatusOK, do("k1").Code)
+	require.Equal(t, http.StatusTooManyRequests, do("k1").Code)
+}
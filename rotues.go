@@ -1,18 +1,35 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"pundix-homework/auth"
 	"pundix-homework/clients"
+	"pundix-homework/metrics"
+	"pundix-homework/openapi"
+	"pundix-homework/ratelimit"
+	"pundix-homework/verify"
+	"pundix-homework/web"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gogo/protobuf/proto"
 	"github.com/tendermint/tendermint/libs/math"
 )
 
 func setupRoutes(engine *gin.Engine) {
-	queryGroup := engine.Group("/query")
+	engine.GET("/metrics", metrics.Handler())
+	engine.GET("/healthz", HealthzHandler)
+	engine.GET("/readyz", ReadyzHandler)
+	engine.GET(openAPIPath, apiSpec.Handler(engine.Routes))
+	engine.GET("/swagger/*filepath", openapi.SwaggerUI(apiTitle, openAPIPath))
+
+	// the key is checked before the cache so quotas and rate limits count cached responses too
+	queryGroup := engine.Group("/query", append(guard(auth.Anonymous), web.Negotiate(), snapshots.Middleware(latestHeight), verify.Middleware(), queryCache.Middleware())...)
 	// distribution
 	distributionGroup := queryGroup.Group("/distribution")
 	{
@@ -29,6 +46,89 @@ func setupRoutes(engine *gin.Engine) {
 		bankGroup.GET("balance", BalanceHandler)
 		bankGroup.GET("total", TotalSupplyHandler)
 	}
+	// several of the routes above in one request, at one height
+	queryGroup.POST("batch", QueryBatchHandler)
+
+	// validator summaries and rankings stitched from several modules
+	validatorGroup := engine.Group("/validators", append(guard(auth.Anonymous), web.Negotiate(), snapshots.Middleware(latestHeight), queryCache.Middleware())...)
+	{
+		validatorGroup.GET("leaderboard", ValidatorLeaderboardHandler)
+		validatorGroup.GET(":valoper/summary", ValidatorSummaryHandler)
+	}
+
+	// staking yields derived from the mint, distribution and staking state
+	analyticsGroup := engine.Group("/analytics", append(guard(auth.Anonymous), web.Negotiate(), snapshots.Middleware(latestHeight), queryCache.Middleware())...)
+	{
+		analyticsGroup.GET("staking/apr", StakingAPRHandler)
+		analyticsGroup.GET("rewards/projection", RewardProjectionHandler)
+		analyticsGroup.GET("supply", SupplyHandler)
+	}
+	// plain text whatever the Accept header asks for
+	engine.GET("/analytics/supply/circulating", append(guard(auth.Anonymous), snapshots.Middleware(latestHeight), queryCache.Middleware(), CirculatingSupplyHandler)...)
+
+	// a token pinning the /query and /graphql routes to the latest height
+	engine.POST("/snapshot", append(guard(auth.Anonymous), SnapshotHandler)...)
+
+	// one query across validators, delegations and balances
+	graphQLGroup := engine.Group("/graphql", append(guard(auth.Anonymous), snapshots.Middleware(latestHeight))...)
+	{
+		graphQLGroup.GET("", GraphQLHandler)
+		graphQLGroup.POST("", GraphQLHandler)
+	}
+
+	// unsigned tx construction for offline signers
+	txGroup := engine.Group("/tx", guard(auth.Anonymous)...)
+	{
+		txGroup.POST("build/:type", TxBuildHandler)
+	}
+
+	// websocket or server-sent events, picked by the Upgrade header
+	streamGroup := engine.Group("/stream", guard(auth.Anonymous)...)
+	{
+		streamGroup.GET("blocks", StreamBlocksHandler)
+		streamGroup.GET("txs", StreamTxsHandler)
+		streamGroup.GET("events", StreamEventsHandler)
+	}
+
+	// progress of the local chain history index
+	engine.GET("/indexer/status", append(guard(auth.Anonymous), IndexerStatusHandler)...)
+
+	// balance history and transfer ledger of an address, from the index
+	accountGroup := engine.Group("/accounts/:address", append(guard(auth.Anonymous), web.Negotiate())...)
+	{
+		accountGroup.GET("history", AccountHistoryHandler)
+		accountGroup.GET("transfers", AccountTransfersHandler)
+	}
+
+	// webhook notifications, rules call out to arbitrary urls so a key is required
+	watchGroup := engine.Group("/watches", guard(auth.KeyRequired)...)
+	{
+		watchGroup.POST("", CreateWatchHandler)
+		watchGroup.GET("", ListWatchesHandler)
+		watchGroup.GET("dead-letters", ListDeadLettersHandler)
+		watchGroup.POST("dead-letters/:id/redeliver", RedeliverDeadLetterHandler)
+		watchGroup.GET(":id", GetWatchHandler)
+		watchGroup.DELETE(":id", DeleteWatchHandler)
+	}
+
+	// api key management
+	adminGroup := engine.Group("/admin", auth.Admin(os.Getenv(adminTokenEnvKey)))
+	{
+		adminGroup.POST("keys", CreateAPIKeyHandler)
+		adminGroup.GET("keys", ListAPIKeysHandler)
+		adminGroup.GET("keys/:id", GetAPIKeyHandler)
+		adminGroup.DELETE("keys/:id", DeleteAPIKeyHandler)
+		adminGroup.GET("keys/:id/usage", APIKeyUsageHandler)
+	}
+}
+
+// guard authenticates the API key of a route group, then rate limits it per
+// key or per client IP for anonymous callers.
+func guard(access auth.Access) []gin.HandlerFunc {
+	return []gin.HandlerFunc{
+		apiKeys.Middleware(access),
+		ratelimit.Middleware(ipLimiter, keyLimiter, auth.KeyID),
+	}
 }
 
 func rootHandler(c *gin.Context) {
@@ -37,45 +137,171 @@ func rootHandler(c *gin.Context) {
 	})
 }
 
-func QueryParamsHandler(c *gin.Context) {
-	res, err := clients.DistrQueryClientInstance.QueryParams()
+// queryFunc runs the node query of one /query route with its params, the GET
+// handlers and /query/batch share them.
+type queryFunc func(ctx context.Context, params url.Values) (proto.Message, error)
+
+// queryRoutes maps the routes under /query to their queries.
+var queryRoutes = map[string]queryFunc{
+	"distribution/queryParams":                 queryDistributionParams,
+	"distribution/communityPool":               queryCommunityPool,
+	"distribution/validatorCommission":         queryValidatorCommission,
+	"distribution/validatorOutstandingRewards": queryValidatorOutstandingRewards,
+	"bank/balance":                             queryBalance,
+	"bank/total":                               queryTotalSupply,
+}
+
+// serveQuery runs query at the requested height and writes its response,
+// its verified counterpart when the request asked for it.
+func serveQuery(c *gin.Context, query queryFunc) {
+	ctx, err := queryContext(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if verify.Requested(c) {
+		var ok bool
+		if query, ok = verifiedQueries[batchRoute(c.FullPath())]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this route can't be verified"})
+			return
+		}
+	}
+
+	res, err := query(ctx, c.Request.URL.Query())
+	if err != nil {
+		queryError(c, err)
+		return
+	}
+
+	respond(c, res)
+}
+
+func QueryParamsHandler(c *gin.Context) {
+	serveQuery(c, queryDistributionParams)
+}
 
-	c.JSON(http.StatusOK, res)
+func queryDistributionParams(ctx context.Context, _ url.Values) (proto.Message, error) {
+	return clients.DistrQueryClientInstance.QueryParams(ctx)
 }
 
 func ValidatorCommissionHandler(c *gin.Context) {
-	validator := c.Query("validator")
+	serveQuery(c, queryValidatorCommission)
+}
 
+func queryValidatorCommission(ctx context.Context, params url.Values) (proto.Message, error) {
+	validator := params.Get("validator")
 	if validator == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "validator empty"})
-		return
+		return nil, errors.New("validator empty")
 	}
+	return clients.DistrQueryClientInstance.ValidatorCommission(ctx, validator)
+}
 
-	res, err := clients.DistrQueryClientInstance.ValidatorCommission(validator)
-	if err != nil {
+// queryContext returns the request context pinned to the optional height
+// query param, without it queries run against the latest block.
+func queryContext(c *gin.Context) (context.Context, error) {
+	heightStr := c.Query("height")
+	if heightStr == "" {
+		return c.Request.Context(), nil
+	}
+	height, err := strconv.ParseInt(heightStr, 10, 64)
+	if err != nil || height < 0 {
+		return nil, errors.New("height must be a positive integer")
+	}
+	return clients.WithHeight(c.Request.Context(), height), nil
+}
+
+// queryError answers a failed node query, a saturated node is reported as
+// 429 so clients back off. Verified queries fail closed, a response that
+// couldn't be proven is a 502.
+func queryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, clients.ErrUpstreamBusy):
+		ratelimit.RetryAfter(c, clients.UpstreamRetryAfter())
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, verify.ErrVerification):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	case errors.Is(err, verify.ErrDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// respond writes a query response in the output negotiated by web.Negotiate.
+// Verified responses carry verify.BodyField in the JSON and YAML outputs and
+// as a CSV column, protobuf only has the header.
+func respond(c *gin.Context, res proto.Message) {
+	output := web.OutputFrom(c)
+	verified := verify.Requested(c)
+	if output == web.OutputJSON && !verified {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+	var (
+		bz          []byte
+		contentType = gin.MIMEJSON + "; charset=utf-8"
+		err         error
+	)
+	switch output {
+	case web.OutputProto:
+		bz, err = proto.Marshal(res)
+		contentType = web.MIMEProtobuf
+	case web.OutputYAML, web.OutputCSV:
+		// converted from the proto JSON
+		bz, err = marshalJSON(web.OutputProtoJSON, res)
+	default:
+		bz, err = marshalJSON(output, res)
+	}
+	if err == nil && verified && output != web.OutputProto && output != web.OutputCSV {
+		bz, err = web.AddJSONField(bz, verify.BodyField, true)
+	}
+	switch {
+	case err != nil:
+	case output == web.OutputYAML:
+		bz, err = web.JSONToYAML(bz)
+		contentType = web.MIMEYAML + "; charset=utf-8"
+	case output == web.OutputCSV:
+		if bz, err = web.JSONToCSV(bz); errors.Is(err, web.ErrNotList) {
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+			return
+		}
+		if err == nil && verified {
+			bz, err = web.AddCSVColumn(bz, verify.BodyField, "true")
+		}
+		contentType = web.MIMECSV + "; charset=utf-8"
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.Data(http.StatusOK, contentType, bz)
+}
 
-	c.JSON(http.StatusOK, res)
+// marshalJSON encodes a query response in one of the JSON outputs, json,
+// protojson or amino.
+func marshalJSON(output web.Output, res proto.Message) ([]byte, error) {
+	encoding := clients.EncodingConfig()
+	switch output {
+	case web.OutputProtoJSON:
+		return encoding.Marshaler.MarshalJSON(res)
+	case web.OutputAmino:
+		return encoding.Amino.MarshalJSON(res)
+	default:
+		return json.Marshal(res)
+	}
 }
 
-func parseParams(c *gin.Context) (string, uint64, uint64, uint64, error) {
-	validator := c.Query("validator")
+func parseParams(params url.Values) (string, uint64, uint64, uint64, error) {
+	validator := params.Get("validator")
 
-	startHeightStr := c.Query("startHeight")
-	endHeightStr := c.Query("endHeight")
-	limitStr := c.Query("limit")
+	startHeightStr := params.Get("startHeight")
+	endHeightStr := params.Get("endHeight")
+	limitStr := params.Get("limit")
 
 	startHeight, err0 := strconv.ParseInt(startHeightStr, 10, 64)
 	endHeight, err1 := strconv.ParseInt(endHeightStr, 10, 64)
 	limit, err2 := strconv.ParseInt(limitStr, 10, 64)
 	if err0 != nil || err1 != nil || err2 != nil {
-		fmt.Println(err0, err1, err2)
 		return "", 0, 0, 0, errors.New("invalid int type")
 	}
 
@@ -92,64 +318,49 @@ func parseParams(c *gin.Context) (string, uint64, uint64, uint64, error) {
 }
 
 func ValidatorSlashesHanlder(c *gin.Context) {
-	validator, startHright, endHeight, limit, err := parseParams(c)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	serveQuery(c, queryValidatorSlashes)
+}
 
-	res, err := clients.DistrQueryClientInstance.ValidatorSlashes(validator, startHright, endHeight, limit)
+func queryValidatorSlashes(ctx context.Context, params url.Values) (proto.Message, error) {
+	validator, startHeight, endHeight, limit, err := parseParams(params)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
-
-	c.JSON(http.StatusOK, res)
+	return clients.DistrQueryClientInstance.ValidatorSlashes(ctx, validator, startHeight, endHeight, limit)
 }
 
 func ValidatorOutstandingRewardsHandler(c *gin.Context) {
-	validator := c.Query("validator")
+	serveQuery(c, queryValidatorOutstandingRewards)
+}
+
+func queryValidatorOutstandingRewards(ctx context.Context, params url.Values) (proto.Message, error) {
+	validator := params.Get("validator")
 	if validator == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "validator empty"})
-		return
-	}
-	res, err := clients.DistrQueryClientInstance.ValidatorOutstandingRewards(validator)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, errors.New("validator empty")
 	}
-
-	c.JSON(http.StatusOK, res)
+	return clients.DistrQueryClientInstance.ValidatorOutstandingRewards(ctx, validator)
 }
 
 func CommunityPoolHandler(c *gin.Context) {
-	res, err := clients.DistrQueryClientInstance.CommunityPool()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	serveQuery(c, queryCommunityPool)
+}
 
-	c.JSON(http.StatusOK, res)
+func queryCommunityPool(ctx context.Context, _ url.Values) (proto.Message, error) {
+	return clients.DistrQueryClientInstance.CommunityPool(ctx)
 }
 
 func BalanceHandler(c *gin.Context) {
-	address := c.Query("address")
-
-	res, err := clients.BankQueryClientInstance.Balance(address)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	serveQuery(c, queryBalance)
+}
 
-	c.JSON(http.StatusOK, res)
+func queryBalance(ctx context.Context, params url.Values) (proto.Message, error) {
+	return clients.BankQueryClientInstance.Balance(ctx, params.Get("address"))
 }
 
 func TotalSupplyHandler(c *gin.Context) {
-	res, err := clients.BankQueryClientInstance.TotalSupply()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+	serveQuery(c, queryTotalSupply)
+}
 
-	c.JSON(http.StatusOK, res)
+func queryTotalSupply(ctx context.Context, _ url.Values) (proto.Message, error) {
+	return clients.BankQueryClientInstance.TotalSupply(ctx)
 }
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"path/filepath"
+	"pundix-homework/logging"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets a cert and key written one after the other settle before
+// they are loaded as a pair.
+const reloadDelay = 200 * time.Millisecond
+
+// CertReloader serves the certificate in certFile and keyFile and reloads it
+// when either file changes. A broken pair is logged and the previous
+// certificate is kept.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mtx     sync.RWMutex
+	cert    *tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the files again and reports whether the certificate changed.
+func (r *CertReloader) Reload() (bool, error) {
+	certPEM, err := ioutil.ReadFile(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyPEM, err := ioutil.ReadFile(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM) {
+		return false, nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, err
+	}
+	r.cert, r.certPEM, r.keyPEM = &cert, certPEM, keyPEM
+	return true, nil
+}
+
+// Watch reloads the certificate on file changes until ctx is done. The
+// directories are watched rather than the files so renames and the symlink
+// swaps of mounted secrets are seen too.
+func (r *CertReloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for _, dir := range uniqueDirs(r.certFile, r.keyFile) {
+		if err = watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			reload = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logging.L().Warn().Err(err).Msg("watch tls certificate")
+		case <-reload:
+			reload = nil
+			changed, err := r.Reload()
+			if err != nil {
+				logging.L().Error().Err(err).Str("cert", r.certFile).Msg("reload tls certificate, keeping the previous one")
+			} else if changed {
+				logging.L().Info().Str("cert", r.certFile).Msg("tls certificate reloaded")
+			}
+		}
+	}
+}
+
+func uniqueDirs(files ...string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
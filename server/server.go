@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"pundix-homework/logging"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Config configures the HTTP server. TLS is enabled when both CertFile and
+// KeyFile are set, H2C serves cleartext HTTP/2 when it is not.
+// TrustedProxies are the IPs or CIDRs whose X-Forwarded-For is believed,
+// with none the client IP is always the peer address.
+type Config struct {
+	Addr              string
+	TrustedProxies    []string
+	CertFile          string
+	KeyFile           string
+	H2C               bool
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+}
+
+// ConfigFromEnv reads HTTP_ADDR, TRUSTED_PROXIES, TLS_CERT_FILE, TLS_KEY_FILE,
+// HTTP_H2C and the HTTP_*_TIMEOUT / SHUTDOWN_TIMEOUT durations.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Addr:              ":8989",
+		CertFile:          os.Getenv("TLS_CERT_FILE"),
+		KeyFile:           os.Getenv("TLS_KEY_FILE"),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   30 * time.Second,
+	}
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
+		cfg.Addr = addr
+	}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return Config{}, fmt.Errorf("TRUSTED_PROXIES: invalid ip or cidr %q", proxy)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return Config{}, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if value := os.Getenv("HTTP_H2C"); value != "" {
+		h2c, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("HTTP_H2C: %w", err)
+		}
+		cfg.H2C = h2c
+	}
+
+	durations := map[string]*time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"HTTP_READ_TIMEOUT":        &cfg.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"SHUTDOWN_TIMEOUT":         &cfg.ShutdownTimeout,
+	}
+	for key, d := range durations {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", key, err)
+		}
+		*d = parsed
+	}
+	return cfg, nil
+}
+
+// Server is an http.Server that drains in-flight requests on shutdown.
+type Server struct {
+	cfg      Config
+	srv      *http.Server
+	certs    *CertReloader
+	draining chan struct{}
+	once     sync.Once
+}
+
+type (
+	connKey   struct{}
+	serverKey struct{}
+)
+
+func New(cfg Config, handler http.Handler) (*Server, error) {
+	s := &Server{cfg: cfg, draining: make(chan struct{})}
+	s.srv = &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			ctx = context.WithValue(ctx, serverKey{}, s)
+			return context.WithValue(ctx, connKey{}, conn)
+		},
+	}
+	s.srv.RegisterOnShutdown(s.drain)
+
+	if cfg.CertFile != "" {
+		certs, err := NewCertReloader(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = certs
+		// http.Server negotiates HTTP/2 over TLS on its own
+		s.srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	} else if cfg.H2C {
+		s.srv.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.IdleTimeout})
+	}
+	return s, nil
+}
+
+// Run serves until ctx is done, then stops accepting connections and waits
+// up to ShutdownTimeout for in-flight requests.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve is Run on an existing listener.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	if s.certs != nil {
+		go func() {
+			if err := s.certs.Watch(watchCtx); err != nil {
+				logging.L().Error().Err(err).Msg("tls certificate reload disabled")
+			}
+		}()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.certs != nil {
+			serveErr <- s.srv.ServeTLS(listener, "", "")
+		} else {
+			serveErr <- s.srv.Serve(listener)
+		}
+	}()
+	logging.L().Info().Str("addr", listener.Addr().String()).Bool("tls", s.certs != nil).Bool("h2c", s.cfg.H2C && s.certs == nil).Msg("http server started")
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logging.L().Info().Dur("timeout", s.cfg.ShutdownTimeout).Msg("draining http server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+	err := s.srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// whatever is left is cut off
+		s.srv.Close()
+	}
+	if closeErr := <-serveErr; !errors.Is(closeErr, http.ErrServerClosed) && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (s *Server) drain() {
+	s.once.Do(func() { close(s.draining) })
+}
+
+// Draining returns a channel closed when the server that accepted the request
+// in ctx starts shutting down. Long lived streams select on it so they don't
+// hold the shutdown up, outside a Server it never fires.
+func Draining(ctx context.Context) <-chan struct{} {
+	if s, ok := ctx.Value(serverKey{}).(*Server); ok {
+		return s.draining
+	}
+	return nil
+}
+
+// ExtendWriteDeadline pushes the write deadline of the connection carrying
+// r to d from now, so streaming responses are not cut by the server write
+// timeout. HTTP/2 connections are shared by many streams and left alone,
+// their streams end at the write timeout.
+func ExtendWriteDeadline(r *http.Request, d time.Duration) {
+	if r.ProtoMajor != 1 {
+		return
+	}
+	if conn, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+		_ = conn.SetWriteDeadline(time.Now().Add(d))
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{name},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func commonName(t *testing.T, r *CertReloader) string {
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func Test_CertReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "first")
+	r, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	// a half written pair is kept out
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("garbage"), 0o600))
+	time.Sleep(2 * reloadDelay)
+	require.Equal(t, "first", commonName(t, r))
+
+	writeCert(t, certFile, keyFile, "second")
+	require.Eventually(t, func() bool { return commonName(t, r) == "second" }, 2*time.Second, 20*time.Millisecond)
+}
+
+func Test_ShutdownDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	streamStarted := make(chan struct{})
+	streamEnded := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		close(streamStarted)
+		<-Draining(r.Context())
+		close(streamEnded)
+	})
+
+	cfg := Config{ShutdownTimeout: 5 * time.Second}
+	srv, err := New(cfg, mux)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, listener) }()
+
+	base := "http://" + listener.Addr().String()
+	go http.Get(base + "/stream")
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get(base + "/slow")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		bz, _ := ioutil.ReadAll(res.Body)
+		body <- string(bz)
+	}()
+
+	<-started
+	<-streamStarted
+	cancel()
+	require.Equal(t, "done", <-body)
+	<-streamEnded
+	require.NoError(t, <-done)
+}
+
+func Test_H2C(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	srv, err := New(Config{H2C: true, ShutdownTimeout: time.Second}, handler)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Serve(ctx, listener)
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	res, err := client.Get("http://" + listener.Addr().String())
+	require.NoError(t, err)
+	defer res.Body.Close()
+	bz, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/2.0", string(bz))
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HeaderName = "X-Snapshot"
+	QueryParam = "snapshot"
+
+	// hex or plain secret the tokens are signed with, replicas behind one load
+	// balancer need the same one. A random secret is used when unset, tokens
+	// then don't survive a restart.
+	SecretEnvKey = "SNAPSHOT_SECRET"
+	// the pruning-keep-recent setting of the node
+	KeepRecentEnvKey = "SNAPSHOT_KEEP_RECENT"
+	// blocks before the pruning window tokens stop being accepted
+	MarginEnvKey = "SNAPSHOT_MARGIN"
+
+	// the default pruning of the node keeps the latest 100 heights
+	defaultKeepRecent = 100
+	defaultMargin     = 10
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid snapshot token")
+	ErrExpired      = errors.New("snapshot expired, its height is about to be pruned")
+)
+
+// Config holds the signing secret and the pruning window of the node.
+type Config struct {
+	Secret     []byte
+	KeepRecent int64
+	Margin     int64
+}
+
+// ConfigFromEnv reads SNAPSHOT_SECRET, SNAPSHOT_KEEP_RECENT and
+// SNAPSHOT_MARGIN.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{KeepRecent: defaultKeepRecent, Margin: defaultMargin}
+	for key, value := range map[string]*int64{KeepRecentEnvKey: &cfg.KeepRecent, MarginEnvKey: &cfg.Margin} {
+		if s := os.Getenv(key); s != "" {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || n < 0 {
+				return Config{}, fmt.Errorf("%s must be a positive integer", key)
+			}
+			*value = n
+		}
+	}
+	if cfg.Margin >= cfg.KeepRecent {
+		return Config{}, fmt.Errorf("%s must be lower than %s", MarginEnvKey, KeepRecentEnvKey)
+	}
+
+	if secret := os.Getenv(SecretEnvKey); secret != "" {
+		cfg.Secret = []byte(secret)
+	} else {
+		cfg.Secret = make([]byte, 32)
+		if _, err := rand.Read(cfg.Secret); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// Snapshot pins reads to the height it was taken at.
+type Snapshot struct {
+	Token  string `json:"token"`
+	Height int64  `json:"height"`
+	// ExpiresAt is the latest block height the token is accepted at.
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// Issuer signs and checks snapshot tokens. Tokens are stateless, the height
+// and its signature, so any replica sharing the secret accepts them.
+type Issuer struct {
+	cfg Config
+}
+
+func NewIssuer(cfg Config) *Issuer {
+	return &Issuer{cfg: cfg}
+}
+
+// Issue returns a snapshot of height.
+func (i *Issuer) Issue(height int64) Snapshot {
+	h := strconv.FormatInt(height, 10)
+	return Snapshot{
+		Token:     h + "." + i.sign(h),
+		Height:    height,
+		ExpiresAt: i.expiresAt(height),
+	}
+}
+
+// Check returns the height of token once its signature is valid and latest
+// is still far enough from pruning it.
+func (i *Issuer) Check(token string, latest int64) (int64, error) {
+	h, sig := token, ""
+	if dot := strings.IndexByte(token, '.'); dot >= 0 {
+		h, sig = token[:dot], token[dot+1:]
+	}
+	if !hmac.Equal([]byte(sig), []byte(i.sign(h))) {
+		return 0, ErrInvalidToken
+	}
+	height, err := strconv.ParseInt(h, 10, 64)
+	if err != nil || height <= 0 {
+		return 0, ErrInvalidToken
+	}
+	if latest > i.expiresAt(height) {
+		return 0, ErrExpired
+	}
+	return height, nil
+}
+
+func (i *Issuer) expiresAt(height int64) int64 {
+	return height + i.cfg.KeepRecent - i.cfg.Margin
+}
+
+func (i *Issuer) sign(height string) string {
+	mac := hmac.New(sha256.New, i.cfg.Secret)
+	mac.Write([]byte(height))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Middleware replaces the token sent in the X-Snapshot header or the
+// snapshot query parameter with the height param it stands for, so the
+// handlers and the cache behind it see a plain height pinned request. latest
+// returns the height of the latest block.
+func (i *Issuer) Middleware(latest func(ctx context.Context) (int64, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		token := c.GetHeader(HeaderName)
+		if token == "" {
+			token = query.Get(QueryParam)
+		}
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		latestHeight, err := latest(c.Request.Context())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		height, err := i.Check(token, latestHeight)
+		switch {
+		case errors.Is(err, ErrExpired):
+			c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		pinned := strconv.FormatInt(height, 10)
+		if h := query.Get("height"); h != "" && h != pinned {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "height conflicts with the snapshot"})
+			return
+		}
+
+		query.Del(QueryParam)
+		query.Set("height", pinned)
+		c.Request.URL.RawQuery = query.Encode()
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func testIssuer() *Issuer {
+	return NewIssuer(Config{Secret: []byte("secret"), KeepRecent: 100, Margin: 10})
+}
+
+func Test_Check(t *testing.T) {
+	issuer := testIssuer()
+	s := issuer.Issue(1000)
+	require.Equal(t, int64(1090), s.ExpiresAt)
+
+	height, err := issuer.Check(s.Token, 1090)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), height)
+
+	_, err = issuer.Check(s.Token, 1091)
+	require.ErrorIs(t, err, ErrExpired)
+
+	_, err = issuer.Check("1001"+s.Token[4:], 1000)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = NewIssuer(Config{Secret: []byte("other"), KeepRecent: 100}).Check(s.Token, 1000)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	_, err = issuer.Check("1000", 1000)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	issuer := testIssuer()
+	latest := int64(1050)
+	engine := gin.New()
+	engine.GET("/query/bank/total", issuer.Middleware(func(context.Context) (int64, error) { return latest, nil }), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.URL.RawQuery)
+	})
+	get := func(target, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(HeaderName, header)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	token := issuer.Issue(1000).Token
+
+	w := get("/query/bank/total?snapshot="+token+"&output=json", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "height=1000&output=json", w.Body.String())
+
+	w = get("/query/bank/total", token)
+	require.Equal(t, "height=1000", w.Body.String())
+
+	w = get("/query/bank/total?height=999", token)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = get("/query/bank/total", "nope")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	latest = 1200
+	w = get("/query/bank/total", token)
+	require.Equal(t, http.StatusGone, w.Code)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/snapshot"
+
+	"github.com/gin-gonic/gin"
+)
+
+var snapshots *snapshot.Issuer
+
+func setupSnapshots() {
+	cfg, err := snapshot.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	snapshots = snapshot.NewIssuer(cfg)
+}
+
+// latestHeight is the height of the last node check, the node is asked when
+// no check ran yet.
+func latestHeight(ctx context.Context) (int64, error) {
+	if height := clients.CurrentNodeHealth().Height; height > 0 {
+		return height, nil
+	}
+	return clients.LatestHeight(ctx)
+}
+
+// SnapshotHandler takes a snapshot of the latest block, its token pins the
+// /query and /graphql routes to that height.
+func SnapshotHandler(c *gin.Context) {
+	height, err := clients.LatestHeight(c.Request.Context())
+	if err != nil {
+		queryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snapshots.Issue(height))
+}
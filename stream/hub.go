@@ -0,0 +1,256 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	subscriberName = "pundix-homework"
+	// upstreamCapacity is the buffer of the single upstream channel per query,
+	// WSEvents silently drops events when it is full.
+	upstreamCapacity = 1024
+	// upstreamTimeout bounds a subscribe or unsubscribe on the node, the
+	// client blocks on them while it reconnects.
+	upstreamTimeout = 10 * time.Second
+)
+
+var ErrSlowSubscriber = errors.New("subscriber too slow, events dropped")
+
+// EventsClient is the part of rpchttp.HTTP the hub depends on. Reconnects are
+// handled by the client itself: WSEvents resubscribes every active query via
+// redoSubscriptionsAfter, so the upstream channel keeps delivering afterwards.
+type EventsClient interface {
+	rpcclient.EventsClient
+	Start() error
+	IsRunning() bool
+}
+
+// Hub fans out a single upstream subscription per query to any number of
+// local subscribers. Upstream calls are made outside the hub lock, so a
+// client stuck reconnecting never holds up the other topics.
+type Hub struct {
+	client     EventsClient
+	bufferSize int
+	maxDrops   uint64
+	startMtx   sync.Mutex
+
+	mtx    sync.Mutex
+	topics map[string]*topic
+	// closing holds the queries being unsubscribed upstream, closed once done
+	closing map[string]chan struct{}
+}
+
+type topic struct {
+	query       string
+	subscribers map[*Subscriber]struct{}
+	done        chan struct{}
+	// ready is closed once the upstream subscription is made, err tells why
+	// it couldn't be
+	ready chan struct{}
+	err   error
+}
+
+// Subscriber receives events for one query. C is closed when the subscriber
+// is removed, Err tells why.
+type Subscriber struct {
+	C <-chan ctypes.ResultEvent
+
+	out     chan ctypes.ResultEvent
+	query   string
+	drops   uint64
+	dropped uint64
+	err     error
+	once    sync.Once
+}
+
+// NewHub creates a hub where every subscriber gets bufferSize events of
+// buffering and is evicted after maxDrops consecutive dropped events.
+func NewHub(client EventsClient, bufferSize int, maxDrops uint64) *Hub {
+	return &Hub{
+		client:     client,
+		bufferSize: bufferSize,
+		maxDrops:   maxDrops,
+		topics:     make(map[string]*topic),
+		closing:    make(map[string]chan struct{}),
+	}
+}
+
+// Subscribe adds a subscriber to query, the first one of a query subscribes
+// upstream while the next ones wait for it. ctx only bounds the wait.
+func (h *Hub) Subscribe(ctx context.Context, query string) (*Subscriber, error) {
+	for {
+		h.mtx.Lock()
+		if t, ok := h.topics[query]; ok {
+			h.mtx.Unlock()
+			select {
+			case <-t.ready:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if t.err != nil {
+				return nil, t.err
+			}
+			h.mtx.Lock()
+			// the topic may have lost its last subscriber meanwhile
+			if h.topics[query] == t {
+				s := h.add(t)
+				h.mtx.Unlock()
+				return s, nil
+			}
+			h.mtx.Unlock()
+			continue
+		}
+		// a new upstream subscription must not race the removal of the old one
+		if closing, ok := h.closing[query]; ok {
+			h.mtx.Unlock()
+			select {
+			case <-closing:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		t := &topic{
+			query:       query,
+			subscribers: make(map[*Subscriber]struct{}),
+			done:        make(chan struct{}),
+			ready:       make(chan struct{}),
+		}
+		h.topics[query] = t
+		h.mtx.Unlock()
+
+		upstream, err := h.subscribeUpstream(query)
+		h.mtx.Lock()
+		defer h.mtx.Unlock()
+		defer close(t.ready)
+		if err != nil {
+			t.err = err
+			delete(h.topics, query)
+			return nil, err
+		}
+		go h.fanout(t, upstream)
+		return h.add(t), nil
+	}
+}
+
+// add registers a new subscriber of t. Callers hold the lock.
+func (h *Hub) add(t *topic) *Subscriber {
+	out := make(chan ctypes.ResultEvent, h.bufferSize)
+	s := &Subscriber{C: out, out: out, query: t.query}
+	t.subscribers[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) subscribeUpstream(query string) (<-chan ctypes.ResultEvent, error) {
+	h.startMtx.Lock()
+	if !h.client.IsRunning() {
+		if err := h.client.Start(); err != nil {
+			h.startMtx.Unlock()
+			return nil, err
+		}
+	}
+	h.startMtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+	return h.client.Subscribe(ctx, subscriberName, query, upstreamCapacity)
+}
+
+// Unsubscribe removes the subscriber and drops the upstream subscription once
+// the last subscriber of the query is gone.
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.remove(s, nil)
+}
+
+func (h *Hub) remove(s *Subscriber, reason error) {
+	h.mtx.Lock()
+	t, ok := h.topics[s.query]
+	if !ok {
+		h.mtx.Unlock()
+		return
+	}
+	if _, ok := t.subscribers[s]; !ok {
+		h.mtx.Unlock()
+		return
+	}
+	delete(t.subscribers, s)
+	s.close(reason)
+	if len(t.subscribers) > 0 {
+		h.mtx.Unlock()
+		return
+	}
+	delete(h.topics, s.query)
+	close(t.done)
+	closed := make(chan struct{})
+	h.closing[s.query] = closed
+	h.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+	// the upstream error is not actionable here, the query is gone locally
+	_ = h.client.Unsubscribe(ctx, subscriberName, s.query)
+
+	h.mtx.Lock()
+	delete(h.closing, s.query)
+	h.mtx.Unlock()
+	close(closed)
+}
+
+func (h *Hub) fanout(t *topic, upstream <-chan ctypes.ResultEvent) {
+	for {
+		select {
+		case <-t.done:
+			return
+		case event := <-upstream:
+			var slow []*Subscriber
+			h.mtx.Lock()
+			for s := range t.subscribers {
+				select {
+				case s.out <- event:
+					atomic.StoreUint64(&s.drops, 0)
+				default:
+					atomic.AddUint64(&s.dropped, 1)
+					if atomic.AddUint64(&s.drops, 1) >= h.maxDrops {
+						slow = append(slow, s)
+					}
+				}
+			}
+			h.mtx.Unlock()
+			for _, s := range slow {
+				h.remove(s, ErrSlowSubscriber)
+			}
+		}
+	}
+}
+
+// Topics returns the number of live upstream subscriptions.
+func (h *Hub) Topics() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return len(h.topics)
+}
+
+// Dropped returns the total number of events this subscriber missed because
+// its buffer was full.
+func (s *Subscriber) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Err returns the reason the subscriber was closed, nil on a normal close.
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+func (s *Subscriber) close(reason error) {
+	s.once.Do(func() {
+		s.err = reason
+		close(s.out)
+	})
+}
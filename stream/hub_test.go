@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+type fakeEventsClient struct {
+	mtx          sync.Mutex
+	running      bool
+	upstream     map[string]chan ctypes.ResultEvent
+	unsubscribed []string
+	// unsubscribing, when set, holds Unsubscribe as a reconnecting client does
+	unsubscribing chan struct{}
+}
+
+func newFakeEventsClient() *fakeEventsClient {
+	return &fakeEventsClient{upstream: make(map[string]chan ctypes.ResultEvent)}
+}
+
+func (f *fakeEventsClient) Start() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.running = true
+	return nil
+}
+
+func (f *fakeEventsClient) IsRunning() bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.running
+}
+
+func (f *fakeEventsClient) Subscribe(_ context.Context, _, query string, outCapacity ...int) (<-chan ctypes.ResultEvent, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	ch := make(chan ctypes.ResultEvent, outCapacity[0])
+	f.upstream[query] = ch
+	return ch, nil
+}
+
+func (f *fakeEventsClient) Unsubscribe(_ context.Context, _, query string) error {
+	if f.unsubscribing != nil {
+		<-f.unsubscribing
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	delete(f.upstream, query)
+	f.unsubscribed = append(f.unsubscribed, query)
+	return nil
+}
+
+func (f *fakeEventsClient) UnsubscribeAll(context.Context, string) error {
+	return nil
+}
+
+func (f *fakeEventsClient) publish(query string) {
+	f.mtx.Lock()
+	ch := f.upstream[query]
+	f.mtx.Unlock()
+	ch <- ctypes.ResultEvent{Query: query}
+}
+
+func Test_HubFanout(t *testing.T) {
+	client := newFakeEventsClient()
+	hub := NewHub(client, 4, 2)
+
+	a, err := hub.Subscribe(context.Background(), "q")
+	require.NoError(t, err)
+	b, err := hub.Subscribe(context.Background(), "q")
+	require.NoError(t, err)
+	require.True(t, client.IsRunning())
+	require.Equal(t, 1, hub.Topics())
+
+	client.publish("q")
+	require.Equal(t, "q", (<-a.C).Query)
+	require.Equal(t, "q", (<-b.C).Query)
+
+	hub.Unsubscribe(a)
+	_, ok := <-a.C
+	require.False(t, ok)
+	require.NoError(t, a.Err())
+	require.Empty(t, client.unsubscribed)
+
+	hub.Unsubscribe(b)
+	require.Equal(t, 0, hub.Topics())
+	require.Equal(t, []string{"q"}, client.unsubscribed)
+}
+
+func Test_HubEvictsSlowSubscriber(t *testing.T) {
+	client := newFakeEventsClient()
+	hub := NewHub(client, 1, 2)
+
+	slow, err := hub.Subscribe(context.Background(), "q")
+	require.NoError(t, err)
+
+	// first event fills the buffer, the next two are dropped and evict
+	for i := 0; i < 3; i++ {
+		client.publish("q")
+	}
+
+	require.Eventually(t, func() bool { return hub.Topics() == 0 }, time.Second, time.Millisecond)
+	<-slow.C
+	_, ok := <-slow.C
+	require.False(t, ok)
+	require.ErrorIs(t, slow.Err(), ErrSlowSubscriber)
+	require.Equal(t, uint64(2), slow.Dropped())
+}
+
+func Test_HubUpstreamOutsideLock(t *testing.T) {
+	client := newFakeEventsClient()
+	client.unsubscribing = make(chan struct{})
+	hub := NewHub(client, 4, 2)
+
+	a, err := hub.Subscribe(context.Background(), "a")
+	require.NoError(t, err)
+	b, err := hub.Subscribe(context.Background(), "b")
+	require.NoError(t, err)
+	removed := make(chan struct{})
+	go func() {
+		hub.Unsubscribe(a)
+		close(removed)
+	}()
+	require.Eventually(t, func() bool { return hub.Topics() == 1 }, time.Second, time.Millisecond)
+
+	// the other topics keep flowing while the upstream unsubscribe hangs
+	client.publish("b")
+	require.Equal(t, "b", (<-b.C).Query)
+	c, err := hub.Subscribe(context.Background(), "c")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	// the same query waits for its removal upstream, or gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = hub.Subscribe(ctx, "a")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	resubscribed := make(chan *Subscriber)
+	go func() {
+		s, _ := hub.Subscribe(context.Background(), "a")
+		resubscribed <- s
+	}()
+	close(client.unsubscribing)
+	<-removed
+	a = <-resubscribed
+	require.NotNil(t, a)
+	client.publish("a")
+	require.Equal(t, "a", (<-a.C).Query)
+}
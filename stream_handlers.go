@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/server"
+	"pundix-homework/stream"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	streamBufferSize  = 64
+	streamMaxDrops    = 32
+	streamPingPeriod  = 30 * time.Second
+	streamWriteWait   = 10 * time.Second
+	newBlockQuery     = "tm.event='NewBlock'"
+	txQuery           = "tm.event='Tx'"
+	moduleEventsQuery = txQuery + " AND message.module='%s'"
+)
+
+var (
+	streamHub   = stream.NewHub(clients.RPCClientInstance, streamBufferSize, streamMaxDrops)
+	moduleRegex = regexp.MustCompile(`^[a-z0-9_]+$`)
+	upgrader    = websocket.Upgrader{
+		// read only public data, any origin may subscribe
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+func StreamBlocksHandler(c *gin.Context) {
+	serveStream(c, newBlockQuery)
+}
+
+func StreamTxsHandler(c *gin.Context) {
+	query := txQuery
+	if extra := c.Query("query"); extra != "" {
+		query = fmt.Sprintf("%s AND %s", txQuery, extra)
+	}
+	if _, err := tmquery.New(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	serveStream(c, query)
+}
+
+func StreamEventsHandler(c *gin.Context) {
+	module := c.Query("module")
+	if !moduleRegex.MatchString(module) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid module"})
+		return
+	}
+	serveStream(c, fmt.Sprintf(moduleEventsQuery, module))
+}
+
+func serveStream(c *gin.Context, query string) {
+	sub, err := streamHub.Subscribe(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer streamHub.Unsubscribe(sub)
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		serveWebSocket(c, sub)
+		return
+	}
+	serveSSE(c, sub)
+}
+
+func serveWebSocket(c *gin.Context, sub *stream.Subscriber) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	// the client never sends anything useful, reading only detects the close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingPeriod)
+	defer ping.Stop()
+	draining := server.Draining(c.Request.Context())
+	for {
+		select {
+		case <-closed:
+			return
+		case <-draining:
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteWait))
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, errString(sub.Err()))
+				_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(streamWriteWait))
+				return
+			}
+			bz, err := encodeEvent(event)
+			if err != nil {
+				continue
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, bz); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func serveSSE(c *gin.Context, sub *stream.Subscriber) {
+	// the stream outlives the server write timeout, every write gets its own deadline
+	server.ExtendWriteDeadline(c.Request, streamPingPeriod+streamWriteWait)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	ping := time.NewTicker(streamPingPeriod)
+	defer ping.Stop()
+	ctx := c.Request.Context()
+	draining := server.Draining(ctx)
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-draining:
+			return false
+		case <-ping.C:
+			server.ExtendWriteDeadline(c.Request, streamWriteWait)
+			c.SSEvent("ping", "")
+			return true
+		case event, ok := <-sub.C:
+			if !ok {
+				server.ExtendWriteDeadline(c.Request, streamWriteWait)
+				c.SSEvent("error", errString(sub.Err()))
+				return false
+			}
+			bz, err := encodeEvent(event)
+			if err != nil {
+				return true
+			}
+			server.ExtendWriteDeadline(c.Request, streamWriteWait)
+			c.SSEvent("message", string(bz))
+			return true
+		}
+	})
+}
+
+func encodeEvent(event ctypes.ResultEvent) ([]byte, error) {
+	return tmjson.Marshal(event)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "closed"
+	}
+	return err.Error()
+}
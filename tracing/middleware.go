@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretParams are query params whose value is never exported, as
+// auth.QueryParam, the api key.
+var secretParams = []string{"api_key"}
+
+// Middleware starts a server span per request, continuing the trace of an
+// inbound traceparent header.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if parent, ok := ParseTraceparent(c.GetHeader(TraceparentHeader)); ok {
+			ctx = ContextWithRemoteParent(ctx, parent)
+		}
+
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := Start(ctx, name, KindServer)
+		defer span.End()
+		span.SetAttr("http.method", c.Request.Method)
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.target", redactedTarget(c.Request.URL))
+		span.SetAttr("http.client_ip", c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttr("http.status_code", status)
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		} else if status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", status, http.StatusText(status)))
+		}
+	}
+}
+
+// redactedTarget is the request URI with the values of secretParams redacted.
+func redactedTarget(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.RequestURI()
+	}
+	target := *u
+	target.RawQuery = query.Encode()
+	return target.RequestURI()
+}
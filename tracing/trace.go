@@ -0,0 +1,199 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const TraceparentHeader = "traceparent"
+
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent parses a W3C traceparent header value, ok is false for
+// malformed or all-zero ids.
+func ParseTraceparent(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+	// version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+	return sc, sc.IsValid()
+}
+
+type Kind string
+
+const (
+	KindInternal Kind = "internal"
+	KindServer   Kind = "server"
+	KindClient   Kind = "client"
+)
+
+// SpanData is a finished span as handed to the exporter.
+type SpanData struct {
+	Name         string                 `json:"name"`
+	Kind         Kind                   `json:"kind"`
+	TraceID      string                 `json:"trace_id"`
+	SpanID       string                 `json:"span_id"`
+	ParentSpanID string                 `json:"parent_span_id,omitempty"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// Span is an operation in progress, all its methods are safe on a nil span.
+type Span struct {
+	mtx    sync.Mutex
+	tracer *Tracer
+	sc     SpanContext
+	data   SpanData
+	ended  bool
+}
+
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End finishes the span and queues it for export if it is sampled.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mtx.Unlock()
+
+	if s.sc.Sampled && s.tracer != nil {
+		s.tracer.enqueue(data)
+	}
+}
+
+type (
+	spanKey   struct{}
+	remoteKey struct{}
+)
+
+// SpanFromContext returns the active span of ctx or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent makes the next span started from ctx a child of
+// a span in another process.
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// Start begins a span as a child of the active or remote span in ctx, or as
+// a new trace root. Spans are always created so trace ids show up in logs,
+// they are only exported once a tracer is installed.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	tracer := global()
+	span := &Span{
+		tracer: tracer,
+		data:   SpanData{Name: name, Kind: kind, Start: time.Now()},
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.sc.TraceID = parent.sc.TraceID
+		span.sc.Sampled = parent.sc.Sampled
+		span.data.ParentSpanID = parent.sc.SpanID.String()
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok && remote.IsValid() {
+		span.sc.TraceID = remote.TraceID
+		span.sc.Sampled = remote.Sampled
+		span.data.ParentSpanID = remote.SpanID.String()
+	} else {
+		randomID(span.sc.TraceID[:])
+		span.sc.Sampled = true
+	}
+	randomID(span.sc.SpanID[:])
+	span.data.TraceID = span.sc.TraceID.String()
+	span.data.SpanID = span.sc.SpanID.String()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func randomID(bz []byte) {
+	// crypto/rand only fails when the OS has no entropy source, a zero id
+	// is then treated as invalid by consumers
+	_, _ = rand.Read(bz)
+}
@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	queueSize     = 2048
+	batchSize     = 256
+	flushInterval = 2 * time.Second
+)
+
+// Tracer batches finished spans and hands them to an exporter in the
+// background, spans are dropped rather than blocking requests when the
+// exporter falls behind.
+type Tracer struct {
+	service  string
+	exporter Exporter
+	queue    chan SpanData
+	flush    chan chan struct{}
+	done     chan struct{}
+	dropped  uint64
+	stopOnce sync.Once
+}
+
+func NewTracer(service string, exporter Exporter) *Tracer {
+	t := &Tracer{
+		service:  service,
+		exporter: exporter,
+		queue:    make(chan SpanData, queueSize),
+		flush:    make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+var (
+	globalMtx    sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetGlobal installs the tracer used by Start, nil disables export.
+func SetGlobal(t *Tracer) {
+	globalMtx.Lock()
+	defer globalMtx.Unlock()
+	globalTracer = t
+}
+
+func global() *Tracer {
+	globalMtx.RLock()
+	defer globalMtx.RUnlock()
+	return globalTracer
+}
+
+// Dropped returns the number of spans lost to a full queue.
+func (t *Tracer) Dropped() uint64 {
+	return atomic.LoadUint64(&t.dropped)
+}
+
+func (t *Tracer) enqueue(span SpanData) {
+	select {
+	case t.queue <- span:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// Flush exports every span queued so far.
+func (t *Tracer) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flush <- ack:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown flushes the queue and closes the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	err := t.Flush(ctx)
+	t.stopOnce.Do(func() {
+		close(t.done)
+		if closeErr := t.exporter.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.Export(context.Background(), t.service, batch); err != nil {
+			log.Warn().Err(err).Int("spans", len(batch)).Msg("span export failed")
+		}
+		batch = make([]SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) == batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(ack)
+		case <-t.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseTraceparent(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(header)
+	require.True(t, ok)
+	require.True(t, sc.Sampled)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	require.Equal(t, header, sc.Traceparent())
+
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		_, ok := ParseTraceparent(bad)
+		require.False(t, ok, bad)
+	}
+}
+
+func readSpans(t *testing.T, path string) map[string]fileSpan {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	spans := make(map[string]fileSpan)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span fileSpan
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func Test_MiddlewareContinuesTrace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := OpenExporter("file://" + path)
+	require.NoError(t, err)
+	tracer := NewTracer("test", exporter)
+	SetGlobal(tracer)
+	defer SetGlobal(nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/query/bank/total", func(c *gin.Context) {
+		_, span := Start(c.Request.Context(), "/cosmos.bank.v1beta1.Query/TotalSupply", KindClient)
+		span.SetAttr("block.height", int64(7))
+		span.End()
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/query/bank/total", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	spans := readSpans(t, path)
+	server := spans["GET /query/bank/total"]
+	client := spans["/cosmos.bank.v1beta1.Query/TotalSupply"]
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.TraceID)
+	require.Equal(t, "00f067aa0ba902b7", server.ParentSpanID)
+	require.Equal(t, float64(200), server.Attributes["http.status_code"])
+	require.Equal(t, server.TraceID, client.TraceID)
+	require.Equal(t, server.SpanID, client.ParentSpanID)
+	require.Equal(t, "test", client.Service)
+}
+
+func Test_MiddlewareRedactsAPIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+	tracer := NewTracer("test", exporter)
+	SetGlobal(tracer)
+	defer SetGlobal(nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(Middleware())
+	engine.GET("/query/bank/total", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+
+	req := httptest.NewRequest(http.MethodGet, "/query/bank/total?api_key=s3cret&height=7", nil)
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(bz), "s3cret")
+	server := readSpans(t, path)["GET /query/bank/total"]
+	require.Equal(t, "/query/bank/total?api_key=REDACTED&height=7", server.Attributes["http.target"])
+}
+
+func Test_UnsampledParentNotExported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+	tracer := NewTracer("test", exporter)
+	SetGlobal(tracer)
+	defer SetGlobal(nil)
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := Start(ContextWithRemoteParent(context.Background(), parent), "skipped", KindInternal)
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	require.Empty(t, readSpans(t, path))
+}
+
+func Test_CollectorExporter(t *testing.T) {
+	received := make(chan otlpTraces, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		var body otlpTraces
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received <- body
+	}))
+	defer server.Close()
+
+	exporter, err := OpenExporter(server.URL)
+	require.NoError(t, err)
+	_, span := Start(context.Background(), "op", KindClient)
+	span.SetAttr("block.height", int64(7))
+	span.End()
+	require.NoError(t, exporter.Export(context.Background(), "svc", []SpanData{span.data}))
+
+	body := <-received
+	require.Equal(t, "service.name", body.ResourceSpans[0].Resource.Attributes[0].Key)
+	got := body.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	require.Equal(t, 3, got.Kind)
+	require.Equal(t, span.Context().TraceID.String(), got.TraceID)
+	require.Equal(t, "7", got.Attributes[0].Value["intValue"])
+}
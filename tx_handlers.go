@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"pundix-homework/clients"
+
+	"github.com/gin-gonic/gin"
+)
+
+// txBuildRequest is the body accepted by every /tx/build endpoint, fields not
+// used by a given message type are ignored.
+type txBuildRequest struct {
+	clients.TxOptions
+	ToAddress           string `json:"to_address"`
+	ValidatorAddress    string `json:"validator_address"`
+	ValidatorDstAddress string `json:"validator_dst_address"`
+	Amount              string `json:"amount"`
+}
+
+func TxBuildHandler(c *gin.Context) {
+	var req txBuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Signer == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "signer empty"})
+		return
+	}
+
+	var (
+		res *clients.UnsignedTx
+		err error
+	)
+	builder := clients.TxBuilderClientInstance
+	switch msgType := c.Param("type"); msgType {
+	case "bank-send":
+		res, err = builder.BankSend(req.TxOptions, req.ToAddress, req.Amount)
+	case "delegate":
+		res, err = builder.Delegate(req.TxOptions, req.ValidatorAddress, req.Amount)
+	case "undelegate":
+		res, err = builder.Undelegate(req.TxOptions, req.ValidatorAddress, req.Amount)
+	case "redelegate":
+		res, err = builder.Redelegate(req.TxOptions, req.ValidatorAddress, req.ValidatorDstAddress, req.Amount)
+	case "withdraw-rewards":
+		res, err = builder.WithdrawRewards(req.TxOptions, req.ValidatorAddress)
+	case "withdraw-commission":
+		res, err = builder.WithdrawCommission(req.TxOptions)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown tx type %s", msgType)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,347 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	SortVotingPower = "voting_power"
+	SortCommission  = "commission"
+	SortUptime      = "uptime"
+	SortSlashes     = "slashes"
+
+	// maxSlashes bounds the slash events read per validator
+	maxSlashes = 100
+	bondDenom  = "FX"
+	// parallel is how many validators the leaderboard queries at once
+	parallel = 8
+)
+
+var ErrUnknownSort = fmt.Errorf("sort must be %s, %s, %s or %s", SortVotingPower, SortCommission, SortUptime, SortSlashes)
+
+// Distribution is satisfied by *clients.DistributionQueryClient.
+type Distribution interface {
+	ValidatorCommission(ctx context.Context, validator string) (*distrtypes.QueryValidatorCommissionResponse, error)
+	ValidatorOutstandingRewards(ctx context.Context, validator string) (*distrtypes.QueryValidatorOutstandingRewardsResponse, error)
+	ValidatorSlashes(ctx context.Context, validator string, startHeight, endHeight, limit uint64) (*distrtypes.QueryValidatorSlashesResponse, error)
+}
+
+// Staking is satisfied by *clients.StakingQueryClient.
+type Staking interface {
+	Validator(ctx context.Context, validator string) (*stakingtypes.QueryValidatorResponse, error)
+	Validators(ctx context.Context, status string) ([]stakingtypes.Validator, error)
+	Delegation(ctx context.Context, delegator, validator string) (*stakingtypes.QueryDelegationResponse, error)
+	Pool(ctx context.Context) (*stakingtypes.QueryPoolResponse, error)
+}
+
+// Slashing is satisfied by *clients.SlashingQueryClient.
+type Slashing interface {
+	Params(ctx context.Context) (*slashingtypes.QueryParamsResponse, error)
+	SigningInfo(ctx context.Context, consAddress string) (*slashingtypes.QuerySigningInfoResponse, error)
+	SigningInfos(ctx context.Context) ([]slashingtypes.ValidatorSigningInfo, error)
+}
+
+// Uptime is the liveness of a validator over the signed blocks window.
+// Uptime is 1 - MissedBlocks / SignedBlocksWindow.
+type Uptime struct {
+	SignedBlocksWindow int64     `json:"signed_blocks_window"`
+	MissedBlocks       int64     `json:"missed_blocks"`
+	Uptime             sdk.Dec   `json:"uptime"`
+	StartHeight        int64     `json:"start_height"`
+	JailedUntil        time.Time `json:"jailed_until"`
+	Tombstoned         bool      `json:"tombstoned"`
+}
+
+// Slash is a slash event of a validator.
+type Slash struct {
+	Period   uint64  `json:"period"`
+	Fraction sdk.Dec `json:"fraction"`
+}
+
+// Summary is everything about one validator a delegator decides on.
+// VotingPowerShare is the share of the bonded tokens, zero when the validator
+// isn't bonded.
+type Summary struct {
+	OperatorAddress    string       `json:"operator_address"`
+	ConsensusAddress   string       `json:"consensus_address"`
+	Moniker            string       `json:"moniker"`
+	Website            string       `json:"website,omitempty"`
+	Status             string       `json:"status"`
+	Jailed             bool         `json:"jailed"`
+	Tokens             sdk.Int      `json:"tokens"`
+	VotingPower        int64        `json:"voting_power"`
+	VotingPowerShare   sdk.Dec      `json:"voting_power_share"`
+	CommissionRate     sdk.Dec      `json:"commission_rate"`
+	MaxCommissionRate  sdk.Dec      `json:"max_commission_rate"`
+	MaxChangeRate      sdk.Dec      `json:"max_change_rate"`
+	MinSelfDelegation  sdk.Int      `json:"min_self_delegation"`
+	SelfDelegation     sdk.Coin     `json:"self_delegation"`
+	Commission         sdk.DecCoins `json:"commission"`
+	OutstandingRewards sdk.DecCoins `json:"outstanding_rewards"`
+	Uptime             *Uptime      `json:"uptime"`
+	Slashes            []Slash      `json:"slashes"`
+}
+
+// Entry is a validator of the leaderboard, ranked from 1.
+type Entry struct {
+	Rank             int     `json:"rank"`
+	OperatorAddress  string  `json:"operator_address"`
+	Moniker          string  `json:"moniker"`
+	Status           string  `json:"status"`
+	Jailed           bool    `json:"jailed"`
+	VotingPower      int64   `json:"voting_power"`
+	VotingPowerShare sdk.Dec `json:"voting_power_share"`
+	CommissionRate   sdk.Dec `json:"commission_rate"`
+	Uptime           sdk.Dec `json:"uptime"`
+	MissedBlocks     int64   `json:"missed_blocks"`
+	Slashes          int     `json:"slashes"`
+}
+
+// Dashboard combines staking, distribution and slashing queries per
+// validator. Queries run at the height of their context.
+type Dashboard struct {
+	distr    Distribution
+	staking  Staking
+	slashing Slashing
+}
+
+func New(distr Distribution, staking Staking, slashing Slashing) *Dashboard {
+	return &Dashboard{distr: distr, staking: staking, slashing: slashing}
+}
+
+// Summary of a validator, height bounds the slash events read and is the
+// height the context queries at.
+func (d *Dashboard) Summary(ctx context.Context, valoper string, height int64) (*Summary, error) {
+	res, err := d.staking.Validator(ctx, valoper)
+	if err != nil {
+		return nil, err
+	}
+	v := res.Validator
+	consAddr, err := v.GetConsAddr()
+	if err != nil {
+		return nil, err
+	}
+	valAddr, err := sdk.ValAddressFromBech32(v.OperatorAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// the queries only depend on the validator, they run at once
+	var (
+		wg             sync.WaitGroup
+		mtx            sync.Mutex
+		firstErr       error
+		pool           *stakingtypes.QueryPoolResponse
+		params         *slashingtypes.QueryParamsResponse
+		info           *slashingtypes.QuerySigningInfoResponse
+		commission     *distrtypes.QueryValidatorCommissionResponse
+		rewards        *distrtypes.QueryValidatorOutstandingRewardsResponse
+		slashes        *distrtypes.QueryValidatorSlashesResponse
+		selfDelegation *stakingtypes.QueryDelegationResponse
+	)
+	run := func(fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mtx.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+	run(func() (err error) { pool, err = d.staking.Pool(ctx); return })
+	run(func() (err error) { params, err = d.slashing.Params(ctx); return })
+	run(func() (err error) { commission, err = d.distr.ValidatorCommission(ctx, valoper); return })
+	run(func() (err error) { rewards, err = d.distr.ValidatorOutstandingRewards(ctx, valoper); return })
+	run(func() (err error) {
+		slashes, err = d.distr.ValidatorSlashes(ctx, valoper, 0, uint64(height), maxSlashes)
+		return
+	})
+	// a validator that never signed has no signing info, one whose operator
+	// unbonded everything has no self delegation
+	run(func() (err error) {
+		if info, err = d.slashing.SigningInfo(ctx, consAddr.String()); IsNotFound(err) {
+			err = nil
+		}
+		return
+	})
+	run(func() (err error) {
+		if selfDelegation, err = d.staking.Delegation(ctx, sdk.AccAddress(valAddr).String(), valoper); IsNotFound(err) {
+			err = nil
+		}
+		return
+	})
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	s := &Summary{
+		OperatorAddress:    v.OperatorAddress,
+		ConsensusAddress:   consAddr.String(),
+		Moniker:            v.Description.Moniker,
+		Website:            v.Description.Website,
+		Status:             v.Status.String(),
+		Jailed:             v.Jailed,
+		Tokens:             v.Tokens,
+		VotingPower:        v.ConsensusPower(),
+		VotingPowerShare:   votingPowerShare(v, pool.Pool),
+		CommissionRate:     v.Commission.Rate,
+		MaxCommissionRate:  v.Commission.MaxRate,
+		MaxChangeRate:      v.Commission.MaxChangeRate,
+		MinSelfDelegation:  v.MinSelfDelegation,
+		SelfDelegation:     sdk.NewCoin(bondDenom, sdk.ZeroInt()),
+		Commission:         commission.Commission.Commission,
+		OutstandingRewards: rewards.Rewards.Rewards,
+		Slashes:            []Slash{},
+	}
+	if selfDelegation != nil && selfDelegation.DelegationResponse != nil {
+		s.SelfDelegation = selfDelegation.DelegationResponse.Balance
+	}
+	if info != nil {
+		s.Uptime = uptime(info.ValSigningInfo, params.Params.SignedBlocksWindow)
+	}
+	for _, slash := range slashes.Slashes {
+		s.Slashes = append(s.Slashes, Slash{Period: slash.ValidatorPeriod, Fraction: slash.Fraction})
+	}
+	return s, nil
+}
+
+// Leaderboard ranks the validators of a status, every one when it's empty,
+// by sortBy. Voting power and uptime rank the highest first, commission and
+// slashes the lowest first, ties go to the higher voting power.
+func (d *Dashboard) Leaderboard(ctx context.Context, status, sortBy string, height int64) ([]Entry, error) {
+	less, ok := orders[sortBy]
+	if !ok {
+		return nil, ErrUnknownSort
+	}
+	validators, err := d.staking.Validators(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := d.staking.Pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := d.slashing.Params(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := d.slashing.SigningInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byConsAddr := make(map[string]slashingtypes.ValidatorSigningInfo, len(infos))
+	for _, info := range infos {
+		byConsAddr[info.Address] = info
+	}
+
+	entries := make([]Entry, len(validators))
+	errs := make([]error, len(validators))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, v := range validators {
+		entries[i] = Entry{
+			OperatorAddress:  v.OperatorAddress,
+			Moniker:          v.Description.Moniker,
+			Status:           v.Status.String(),
+			Jailed:           v.Jailed,
+			VotingPower:      v.ConsensusPower(),
+			VotingPowerShare: votingPowerShare(v, pool.Pool),
+			CommissionRate:   v.Commission.Rate,
+			Uptime:           sdk.ZeroDec(),
+		}
+		if consAddr, err := v.GetConsAddr(); err == nil {
+			if info, ok := byConsAddr[consAddr.String()]; ok {
+				u := uptime(info, params.Params.SignedBlocksWindow)
+				entries[i].Uptime, entries[i].MissedBlocks = u.Uptime, u.MissedBlocks
+			}
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, valoper string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := d.distr.ValidatorSlashes(ctx, valoper, 0, uint64(height), maxSlashes)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			entries[i].Slashes = len(res.Slashes)
+		}(i, v.OperatorAddress)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if less(entries[i], entries[j]) {
+			return true
+		}
+		if less(entries[j], entries[i]) {
+			return false
+		}
+		return entries[i].VotingPower > entries[j].VotingPower
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+var orders = map[string]func(a, b Entry) bool{
+	SortVotingPower: func(a, b Entry) bool { return a.VotingPower > b.VotingPower },
+	SortCommission:  func(a, b Entry) bool { return a.CommissionRate.LT(b.CommissionRate) },
+	SortUptime:      func(a, b Entry) bool { return a.Uptime.GT(b.Uptime) },
+	SortSlashes:     func(a, b Entry) bool { return a.Slashes < b.Slashes },
+}
+
+func votingPowerShare(v stakingtypes.Validator, pool stakingtypes.Pool) sdk.Dec {
+	if !v.IsBonded() || !pool.BondedTokens.IsPositive() {
+		return sdk.ZeroDec()
+	}
+	return v.Tokens.ToDec().QuoInt(pool.BondedTokens)
+}
+
+func uptime(info slashingtypes.ValidatorSigningInfo, window int64) *Uptime {
+	u := &Uptime{
+		SignedBlocksWindow: window,
+		MissedBlocks:       info.MissedBlocksCounter,
+		Uptime:             sdk.OneDec(),
+		StartHeight:        info.StartHeight,
+		JailedUntil:        info.JailedUntil,
+		Tombstoned:         info.Tombstoned,
+	}
+	if window > 0 {
+		u.Uptime = sdk.OneDec().Sub(sdk.NewDec(info.MissedBlocksCounter).QuoInt64(window))
+	}
+	return u
+}
+
+// IsNotFound tells a missing validator, signing info or self delegation
+// apart from a failed query.
+func IsNotFound(err error) bool {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == codes.NotFound
+}
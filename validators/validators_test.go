@@ -0,0 +1,180 @@
+package validators
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	slashingtypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	// fx address prefixes and denoms
+	_ "github.com/functionx/fx-core/app"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeChain has three bonded validators, b is the largest, a was slashed
+// twice and c never signed.
+type fakeChain struct {
+	validators []stakingtypes.Validator
+	slashes    map[string]int
+	missed     map[string]int64
+}
+
+func newFakeChain(t *testing.T) *fakeChain {
+	f := &fakeChain{slashes: map[string]int{}, missed: map[string]int64{}}
+	for i, spec := range []struct {
+		moniker    string
+		tokens     int64
+		commission string
+	}{{"a", 300, "0.05"}, {"b", 600, "0.10"}, {"c", 100, "0.05"}} {
+		valAddr := sdk.ValAddress([]byte("validator" + spec.moniker + "__________"))
+		v, err := stakingtypes.NewValidator(valAddr, ed25519.GenPrivKeyFromSecret([]byte{byte(i)}).PubKey(), stakingtypes.Description{Moniker: spec.moniker})
+		require.NoError(t, err)
+		v.Status = stakingtypes.Bonded
+		v.Tokens = sdk.TokensFromConsensusPower(spec.tokens)
+		v.Commission.Rate = sdk.MustNewDecFromStr(spec.commission)
+		f.validators = append(f.validators, v)
+		consAddr, err := v.GetConsAddr()
+		require.NoError(t, err)
+		switch spec.moniker {
+		case "a":
+			f.slashes[v.OperatorAddress] = 2
+			f.missed[consAddr.String()] = 10
+		case "b":
+			f.missed[consAddr.String()] = 50
+		}
+	}
+	return f
+}
+
+func (f *fakeChain) get(valoper string) (stakingtypes.Validator, bool) {
+	for _, v := range f.validators {
+		if v.OperatorAddress == valoper {
+			return v, true
+		}
+	}
+	return stakingtypes.Validator{}, false
+}
+
+func (f *fakeChain) ValidatorCommission(_ context.Context, _ string) (*distrtypes.QueryValidatorCommissionResponse, error) {
+	return &distrtypes.QueryValidatorCommissionResponse{Commission: distrtypes.ValidatorAccumulatedCommission{
+		Commission: sdk.NewDecCoins(sdk.NewDecCoinFromDec("FX", sdk.MustNewDecFromStr("1.5"))),
+	}}, nil
+}
+
+func (f *fakeChain) ValidatorOutstandingRewards(_ context.Context, _ string) (*distrtypes.QueryValidatorOutstandingRewardsResponse, error) {
+	return &distrtypes.QueryValidatorOutstandingRewardsResponse{}, nil
+}
+
+func (f *fakeChain) ValidatorSlashes(_ context.Context, validator string, _, _, _ uint64) (*distrtypes.QueryValidatorSlashesResponse, error) {
+	res := &distrtypes.QueryValidatorSlashesResponse{}
+	for i := 0; i < f.slashes[validator]; i++ {
+		res.Slashes = append(res.Slashes, distrtypes.ValidatorSlashEvent{ValidatorPeriod: uint64(i + 1), Fraction: sdk.MustNewDecFromStr("0.01")})
+	}
+	return res, nil
+}
+
+func (f *fakeChain) Validator(_ context.Context, validator string) (*stakingtypes.QueryValidatorResponse, error) {
+	v, ok := f.get(validator)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "validator not found")
+	}
+	return &stakingtypes.QueryValidatorResponse{Validator: v}, nil
+}
+
+func (f *fakeChain) Validators(_ context.Context, _ string) ([]stakingtypes.Validator, error) {
+	return f.validators, nil
+}
+
+func (f *fakeChain) Delegation(_ context.Context, delegator, validator string) (*stakingtypes.QueryDelegationResponse, error) {
+	if v, _ := f.get(validator); v.Description.Moniker != "a" {
+		return nil, status.Error(codes.NotFound, "delegation not found")
+	}
+	return &stakingtypes.QueryDelegationResponse{DelegationResponse: &stakingtypes.DelegationResponse{
+		Delegation: stakingtypes.Delegation{DelegatorAddress: delegator, ValidatorAddress: validator},
+		Balance:    sdk.NewInt64Coin("FX", 20),
+	}}, nil
+}
+
+func (f *fakeChain) Pool(_ context.Context) (*stakingtypes.QueryPoolResponse, error) {
+	return &stakingtypes.QueryPoolResponse{Pool: stakingtypes.NewPool(sdk.ZeroInt(), sdk.TokensFromConsensusPower(1000))}, nil
+}
+
+func (f *fakeChain) Params(_ context.Context) (*slashingtypes.QueryParamsResponse, error) {
+	return &slashingtypes.QueryParamsResponse{Params: slashingtypes.Params{SignedBlocksWindow: 100}}, nil
+}
+
+func (f *fakeChain) SigningInfo(_ context.Context, consAddress string) (*slashingtypes.QuerySigningInfoResponse, error) {
+	missed, ok := f.missed[consAddress]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "signing info not found")
+	}
+	return &slashingtypes.QuerySigningInfoResponse{ValSigningInfo: slashingtypes.ValidatorSigningInfo{Address: consAddress, MissedBlocksCounter: missed}}, nil
+}
+
+func (f *fakeChain) SigningInfos(_ context.Context) ([]slashingtypes.ValidatorSigningInfo, error) {
+	var infos []slashingtypes.ValidatorSigningInfo
+	for address, missed := range f.missed {
+		infos = append(infos, slashingtypes.ValidatorSigningInfo{Address: address, MissedBlocksCounter: missed})
+	}
+	return infos, nil
+}
+
+func Test_Summary(t *testing.T) {
+	chain := newFakeChain(t)
+	d := New(chain, chain, chain)
+
+	s, err := d.Summary(context.Background(), chain.validators[0].OperatorAddress, 100)
+	require.NoError(t, err)
+	require.Equal(t, "a", s.Moniker)
+	require.Equal(t, int64(300), s.VotingPower)
+	require.Equal(t, "0.300000000000000000", s.VotingPowerShare.String())
+	require.Equal(t, "20FX", s.SelfDelegation.String())
+	require.Equal(t, "0.900000000000000000", s.Uptime.Uptime.String())
+	require.Len(t, s.Slashes, 2)
+	_, err = json.Marshal(s)
+	require.NoError(t, err)
+
+	// never signed and no self delegation left
+	s, err = d.Summary(context.Background(), chain.validators[2].OperatorAddress, 100)
+	require.NoError(t, err)
+	require.Nil(t, s.Uptime)
+	require.True(t, s.SelfDelegation.IsZero())
+	require.Empty(t, s.Slashes)
+
+	_, err = d.Summary(context.Background(), sdk.ValAddress([]byte("unknown_____________")).String(), 100)
+	require.True(t, IsNotFound(err))
+}
+
+func Test_Leaderboard(t *testing.T) {
+	chain := newFakeChain(t)
+	d := New(chain, chain, chain)
+
+	monikers := func(entries []Entry) []string {
+		var names []string
+		for i, e := range entries {
+			require.Equal(t, i+1, e.Rank)
+			names = append(names, e.Moniker)
+		}
+		return names
+	}
+	for sortBy, want := range map[string][]string{
+		SortVotingPower: {"b", "a", "c"},
+		// a and c tie, a has more voting power
+		SortCommission: {"a", "c", "b"},
+		SortUptime:     {"a", "b", "c"},
+		SortSlashes:    {"b", "c", "a"},
+	} {
+		entries, err := d.Leaderboard(context.Background(), "", sortBy, 100)
+		require.NoError(t, err)
+		require.Equal(t, want, monikers(entries), sortBy)
+	}
+
+	_, err := d.Leaderboard(context.Background(), "", "moniker", 100)
+	require.ErrorIs(t, err, ErrUnknownSort)
+}
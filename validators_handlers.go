@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"pundix-homework/clients"
+	"pundix-homework/validators"
+	"strconv"
+
+	stakingtypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/gin-gonic/gin"
+)
+
+var dashboard = validators.New(clients.DistrQueryClientInstance, clients.StakingQueryClientInstance, clients.SlashingQueryClientInstance)
+
+// leaderboardStatuses maps the status param to the staking bond statuses.
+var leaderboardStatuses = map[string]string{
+	"bonded":    stakingtypes.BondStatusBonded,
+	"unbonding": stakingtypes.BondStatusUnbonding,
+	"unbonded":  stakingtypes.BondStatusUnbonded,
+	"all":       "",
+}
+
+type leaderboardResponse struct {
+	Height     int64              `json:"height"`
+	Sort       string             `json:"sort"`
+	Validators []validators.Entry `json:"validators"`
+}
+
+// dashboardHeight pins the request to ?height= or the latest block and
+// returns the height, the slash events and the supply are read at it.
+func dashboardHeight(c *gin.Context) (context.Context, int64, bool) {
+	ctx := c.Request.Context()
+	if s := c.Query("height"); s != "" {
+		height, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || height <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "height must be a positive integer"})
+			return nil, 0, false
+		}
+		return clients.WithHeight(ctx, height), height, true
+	}
+	height, err := latestHeight(ctx)
+	if err != nil {
+		queryError(c, err)
+		return nil, 0, false
+	}
+	return clients.WithHeight(ctx, height), height, true
+}
+
+// ValidatorSummaryHandler combines the staking, distribution and slashing
+// state of a validator.
+func ValidatorSummaryHandler(c *gin.Context) {
+	ctx, height, ok := dashboardHeight(c)
+	if !ok {
+		return
+	}
+	summary, err := dashboard.Summary(ctx, c.Param("valoper"), height)
+	if validators.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		queryError(c, err)
+		return
+	}
+	respondRecords(c, summary)
+}
+
+// ValidatorLeaderboardHandler ranks the validators by ?sort=, the bonded ones
+// unless ?status= says otherwise.
+func ValidatorLeaderboardHandler(c *gin.Context) {
+	status, ok := leaderboardStatuses[c.DefaultQuery("status", "bonded")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be bonded, unbonding, unbonded or all"})
+		return
+	}
+	sortBy := c.DefaultQuery("sort", validators.SortVotingPower)
+	ctx, height, ok := dashboardHeight(c)
+	if !ok {
+		return
+	}
+	entries, err := dashboard.Leaderboard(ctx, status, sortBy, height)
+	if errors.Is(err, validators.ErrUnknownSort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		queryError(c, err)
+		return
+	}
+	respondRecords(c, leaderboardResponse{Height: height, Sort: sortBy, Validators: entries})
+}
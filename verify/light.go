@@ -0,0 +1,80 @@
+package verify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/light"
+	"github.com/tendermint/tendermint/light/provider"
+	httpprovider "github.com/tendermint/tendermint/light/provider/http"
+	dbs "github.com/tendermint/tendermint/light/store/db"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// LightClient verifies headers from the trusted one in Config on, skipping
+// ahead when enough of the trusted validators signed. It connects on first
+// use and retries on the next call when that fails.
+type LightClient struct {
+	cfg     Config
+	chainID string
+	primary string
+
+	mtx    sync.Mutex
+	client *light.Client
+}
+
+func NewLightClient(cfg Config, chainID, primary string) *LightClient {
+	return &LightClient{cfg: cfg, chainID: chainID, primary: primary}
+}
+
+func (l *LightClient) LightBlock(ctx context.Context, height int64) (*types.LightBlock, error) {
+	// the client isn't safe for concurrent use
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !l.cfg.Enabled() {
+		return nil, ErrDisabled
+	}
+	if l.client == nil {
+		client, err := l.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		l.client = client
+	}
+
+	if height > 0 {
+		return l.client.VerifyLightBlockAtHeight(ctx, height, time.Now())
+	}
+	if _, err := l.client.Update(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+	return l.client.TrustedLightBlock(0)
+}
+
+func (l *LightClient) connect(ctx context.Context) (*light.Client, error) {
+	primary, err := httpprovider.New(l.chainID, l.primary)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.cfg.CheckWitnesses(l.primary); err != nil {
+		return nil, err
+	}
+	witnessURLs := l.cfg.witnesses(l.primary)
+	witnesses := make([]provider.Provider, 0, len(witnessURLs))
+	for _, url := range witnessURLs {
+		witness, err := httpprovider.New(l.chainID, url)
+		if err != nil {
+			return nil, err
+		}
+		witnesses = append(witnesses, witness)
+	}
+
+	return light.NewClient(ctx, l.chainID, light.TrustOptions{
+		Period: l.cfg.TrustingPeriod,
+		Height: l.cfg.TrustedHeight,
+		Hash:   l.cfg.TrustedHash,
+	}, primary, witnesses, dbs.New(dbm.NewMemDB(), ""))
+}
@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestedKey = "verify.requested"
+
+// Middleware marks GET requests sent with ?verify=true, the handlers behind
+// it then answer from proven store reads. Their 200 and 304 responses, cached
+// ones included, carry the X-Verified header. The param is normalized so the
+// cache keeps one entry for its spellings.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		value := query.Get(QueryParam)
+		if c.Request.Method != http.MethodGet || value == "" {
+			c.Next()
+			return
+		}
+
+		requested, err := strconv.ParseBool(value)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": QueryParam + " must be a boolean"})
+			return
+		}
+		if !requested {
+			query.Del(QueryParam)
+			c.Request.URL.RawQuery = query.Encode()
+			c.Next()
+			return
+		}
+
+		query.Set(QueryParam, "true")
+		c.Request.URL.RawQuery = query.Encode()
+		c.Set(requestedKey, true)
+		c.Writer = &verifiedWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// Requested reports whether the request asked for a verified response.
+func Requested(c *gin.Context) bool {
+	return c.GetBool(requestedKey)
+}
+
+// verifiedWriter flags successful responses, verification failures are
+// answered with an error so they never get the header.
+type verifiedWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *verifiedWriter) WriteHeader(code int) {
+	if code == http.StatusOK || code == http.StatusNotModified {
+		w.Header().Set(HeaderName, "true")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,191 @@
+package verify
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+	"github.com/tendermint/tendermint/crypto/merkle"
+	"github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	HeaderName = "X-Verified"
+	QueryParam = "verify"
+	// BodyField flags verified responses in their body, for clients that
+	// don't see the headers.
+	BodyField = "verified"
+
+	// the header every later one is verified from, its hash as hex. Verified
+	// queries are refused while they are unset.
+	TrustedHeightEnvKey = "LIGHT_TRUSTED_HEIGHT"
+	TrustedHashEnvKey   = "LIGHT_TRUSTED_HASH"
+	// how long the trusted header is trusted, as a duration
+	TrustingPeriodEnvKey = "LIGHT_TRUSTING_PERIOD"
+	// comma separated rpc urls headers are cross-checked against, at least one
+	// other than the primary node
+	WitnessesEnvKey = "LIGHT_WITNESSES"
+
+	// two thirds of the 21 days unbonding period
+	defaultTrustingPeriod = 14 * 24 * time.Hour
+)
+
+var (
+	ErrDisabled     = fmt.Errorf("verified queries need %s and %s", TrustedHeightEnvKey, TrustedHashEnvKey)
+	ErrVerification = errors.New("proof verification failed")
+	ErrNoWitness    = fmt.Errorf("verified queries need %s listing a node other than the primary", WitnessesEnvKey)
+)
+
+// Config is the trust root of the light client.
+type Config struct {
+	TrustedHeight  int64
+	TrustedHash    []byte
+	TrustingPeriod time.Duration
+	Witnesses      []string
+}
+
+func (c Config) Enabled() bool {
+	return c.TrustedHeight > 0
+}
+
+// CheckWitnesses fails when no witness but primary would cross-check the
+// headers, a node witnessing itself can't catch a forged header.
+func (c Config) CheckWitnesses(primary string) error {
+	if !c.Enabled() || len(c.witnesses(primary)) > 0 {
+		return nil
+	}
+	return ErrNoWitness
+}
+
+// witnesses are the witnesses other than primary.
+func (c Config) witnesses(primary string) []string {
+	var witnesses []string
+	for _, witness := range c.Witnesses {
+		if !strings.EqualFold(strings.TrimRight(witness, "/"), strings.TrimRight(primary, "/")) {
+			witnesses = append(witnesses, witness)
+		}
+	}
+	return witnesses
+}
+
+// ConfigFromEnv reads LIGHT_TRUSTED_HEIGHT, LIGHT_TRUSTED_HASH,
+// LIGHT_TRUSTING_PERIOD and LIGHT_WITNESSES.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{TrustingPeriod: defaultTrustingPeriod}
+	if s := os.Getenv(TrustedHeightEnvKey); s != "" {
+		height, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || height <= 0 {
+			return Config{}, fmt.Errorf("%s must be a positive integer", TrustedHeightEnvKey)
+		}
+		cfg.TrustedHeight = height
+	}
+	if s := os.Getenv(TrustedHashEnvKey); s != "" {
+		hash, err := hex.DecodeString(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s must be hex: %w", TrustedHashEnvKey, err)
+		}
+		cfg.TrustedHash = hash
+	}
+	if (cfg.TrustedHeight > 0) != (len(cfg.TrustedHash) > 0) {
+		return Config{}, fmt.Errorf("set both %s and %s", TrustedHeightEnvKey, TrustedHashEnvKey)
+	}
+	if s := os.Getenv(TrustingPeriodEnvKey); s != "" {
+		period, err := time.ParseDuration(s)
+		if err != nil || period <= 0 {
+			return Config{}, fmt.Errorf("%s must be a positive duration", TrustingPeriodEnvKey)
+		}
+		cfg.TrustingPeriod = period
+	}
+	for _, witness := range strings.Split(os.Getenv(WitnessesEnvKey), ",") {
+		if witness = strings.TrimSpace(witness); witness != "" {
+			cfg.Witnesses = append(cfg.Witnesses, witness)
+		}
+	}
+	return cfg, nil
+}
+
+// Node runs store queries, it is satisfied by *rpchttp.HTTP.
+type Node interface {
+	ABCIQueryWithOptions(ctx context.Context, path string, data bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error)
+}
+
+// Headers returns light blocks whose validator signatures were verified, it
+// is satisfied by *LightClient.
+type Headers interface {
+	// LightBlock returns the block at height, the latest one when height is 0.
+	LightBlock(ctx context.Context, height int64) (*types.LightBlock, error)
+}
+
+// Verifier reads module stores through the node and checks every value, or
+// its absence, against the app hash of a verified header.
+type Verifier struct {
+	node    Node
+	headers Headers
+	runtime *merkle.ProofRuntime
+}
+
+func NewVerifier(node Node, headers Headers) *Verifier {
+	return &Verifier{node: node, headers: headers, runtime: rootmulti.DefaultProofRuntime()}
+}
+
+// Get reads key from the store of a module at height, the latest provable one
+// when it's 0, and returns the value with the height it was read at. A nil
+// value was proven absent.
+func (v *Verifier) Get(ctx context.Context, storeKey string, key []byte, height int64) ([]byte, int64, error) {
+	// the app hash of a block is in the header of the next one
+	var (
+		header *types.LightBlock
+		err    error
+	)
+	if height == 0 {
+		header, err = v.headers.LightBlock(ctx, 0)
+		if err == nil {
+			height = header.Height - 1
+		}
+	} else {
+		header, err = v.headers.LightBlock(ctx, height+1)
+	}
+	if errors.Is(err, ErrDisabled) {
+		return nil, 0, err
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: header: %v", ErrVerification, err)
+	}
+
+	res, err := v.node.ABCIQueryWithOptions(ctx, "/store/"+storeKey+"/key", key, rpcclient.ABCIQueryOptions{Height: height, Prove: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	if !res.Response.IsOK() {
+		return nil, 0, fmt.Errorf("store query failed: %s", res.Response.Log)
+	}
+	if res.Response.Height != height {
+		return nil, 0, fmt.Errorf("%w: answered at height %d, asked %d", ErrVerification, res.Response.Height, height)
+	}
+	if res.Response.ProofOps == nil {
+		return nil, 0, fmt.Errorf("%w: no proof", ErrVerification)
+	}
+
+	keyPath := merkle.KeyPath{}.AppendKey([]byte(storeKey), merkle.KeyEncodingURL).AppendKey(key, merkle.KeyEncodingURL).String()
+	if len(res.Response.Value) == 0 {
+		err = v.runtime.VerifyAbsence(res.Response.ProofOps, header.AppHash, keyPath)
+	} else {
+		err = v.runtime.VerifyValue(res.Response.ProofOps, header.AppHash, keyPath, res.Response.Value)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrVerification, err)
+	}
+	if len(res.Response.Value) == 0 {
+		return nil, height, nil
+	}
+	return res.Response.Value, height, nil
+}
@@ -0,0 +1,163 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/store/rootmulti"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// storeNode answers store queries from a multistore, appHashes holds the
+// commit hash of every version.
+type storeNode struct {
+	store     *rootmulti.Store
+	appHashes map[int64][]byte
+	tamper    bool
+}
+
+func (n *storeNode) ABCIQueryWithOptions(_ context.Context, path string, data bytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	// baseapp routes /store queries to the multistore without the prefix
+	res := n.store.Query(abci.RequestQuery{Path: strings.TrimPrefix(path, "/store"), Data: data, Height: opts.Height, Prove: opts.Prove})
+	if n.tamper && len(res.Value) > 0 {
+		res.Value = append([]byte("x"), res.Value...)
+	}
+	return &ctypes.ResultABCIQuery{Response: res}, nil
+}
+
+// LightBlock returns the header of the block after every committed version,
+// the app hash of a version is in the header of the next block.
+func (n *storeNode) LightBlock(_ context.Context, height int64) (*types.LightBlock, error) {
+	if height == 0 {
+		height = int64(len(n.appHashes)) + 1
+	}
+	appHash, ok := n.appHashes[height-1]
+	if !ok {
+		return nil, errors.New("no header")
+	}
+	return &types.LightBlock{SignedHeader: &types.SignedHeader{Header: &types.Header{Height: height, AppHash: appHash}}}, nil
+}
+
+func newStoreNode(t *testing.T) *storeNode {
+	key := sdk.NewKVStoreKey("bank")
+	store := rootmulti.NewStore(dbm.NewMemDB())
+	store.MountStoreWithDB(key, sdk.StoreTypeIAVL, nil)
+	require.NoError(t, store.LoadLatestVersion())
+
+	n := &storeNode{store: store, appHashes: map[int64][]byte{}}
+	for _, value := range []string{"one", "two"} {
+		store.GetCommitKVStore(key).Set([]byte("balance"), []byte(value))
+		id := store.Commit()
+		n.appHashes[id.Version] = id.Hash
+	}
+	return n
+}
+
+func Test_VerifierGet(t *testing.T) {
+	node := newStoreNode(t)
+	verifier := NewVerifier(node, node)
+
+	value, height, err := verifier.Get(context.Background(), "bank", []byte("balance"), 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), height)
+	require.Equal(t, []byte("two"), value)
+
+	value, _, err = verifier.Get(context.Background(), "bank", []byte("balance"), 1)
+	require.NoError(t, err)
+	require.Equal(t, []byte("one"), value)
+
+	// absence is proven too
+	value, _, err = verifier.Get(context.Background(), "bank", []byte("missing"), 0)
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	node.tamper = true
+	_, _, err = verifier.Get(context.Background(), "bank", []byte("balance"), 0)
+	require.ErrorIs(t, err, ErrVerification)
+
+	// no header to check against
+	_, _, err = verifier.Get(context.Background(), "bank", []byte("balance"), 5)
+	require.ErrorIs(t, err, ErrVerification)
+
+	_, _, err = NewVerifier(node, NewLightClient(Config{}, "fxcore", "")).Get(context.Background(), "bank", []byte("balance"), 0)
+	require.ErrorIs(t, err, ErrDisabled)
+}
+
+func Test_ConfigFromEnv(t *testing.T) {
+	t.Setenv(TrustedHeightEnvKey, "")
+	t.Setenv(TrustedHashEnvKey, "")
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	require.False(t, cfg.Enabled())
+
+	t.Setenv(TrustedHeightEnvKey, "100")
+	_, err = ConfigFromEnv()
+	require.Error(t, err)
+
+	t.Setenv(TrustedHashEnvKey, "abcd")
+	t.Setenv(WitnessesEnvKey, "http://a:26657, http://b:26657")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.True(t, cfg.Enabled())
+	require.Equal(t, []byte{0xab, 0xcd}, cfg.TrustedHash)
+	require.Equal(t, []string{"http://a:26657", "http://b:26657"}, cfg.Witnesses)
+	require.Equal(t, defaultTrustingPeriod, cfg.TrustingPeriod)
+}
+
+func Test_CheckWitnesses(t *testing.T) {
+	const primary = "https://node:26657"
+	require.NoError(t, Config{}.CheckWitnesses(primary))
+
+	cfg := Config{TrustedHeight: 100, TrustedHash: []byte{0xab}}
+	require.ErrorIs(t, cfg.CheckWitnesses(primary), ErrNoWitness)
+	// the primary doesn't witness itself
+	cfg.Witnesses = []string{"https://NODE:26657/"}
+	require.ErrorIs(t, cfg.CheckWitnesses(primary), ErrNoWitness)
+	cfg.Witnesses = append(cfg.Witnesses, "https://other:26657")
+	require.NoError(t, cfg.CheckWitnesses(primary))
+	require.Equal(t, []string{"https://other:26657"}, cfg.witnesses(primary))
+}
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", Middleware(), func(c *gin.Context) {
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusBadGateway, gin.H{"error": ErrVerification.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"requested": Requested(c), "query": c.Request.URL.RawQuery})
+	})
+	serve := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := serve("/?verify=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "true", w.Header().Get(HeaderName))
+	require.JSONEq(t, `{"requested":true,"query":"verify=true"}`, w.Body.String())
+
+	w = serve("/?verify=false")
+	require.Empty(t, w.Header().Get(HeaderName))
+	require.JSONEq(t, `{"requested":false,"query":""}`, w.Body.String())
+
+	w = serve("/?verify=true&fail=1")
+	require.Equal(t, http.StatusBadGateway, w.Code)
+	require.Empty(t, w.Header().Get(HeaderName))
+
+	require.Equal(t, http.StatusBadRequest, serve("/?verify=maybe").Code)
+}
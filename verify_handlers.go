@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"pundix-homework/clients"
+	"pundix-homework/verify"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+func setupVerification() {
+	cfg, err := verify.ConfigFromEnv()
+	if err != nil {
+		panic(err)
+	}
+	if err = clients.EnableVerification(cfg); err != nil {
+		panic(err)
+	}
+}
+
+// verifiedQueries answer the /query routes whose data can be proven against
+// the app hash, from store reads instead of the node's query handlers.
+var verifiedQueries = map[string]queryFunc{
+	"distribution/communityPool":               verifiedCommunityPool,
+	"distribution/validatorCommission":         verifiedValidatorCommission,
+	"distribution/validatorOutstandingRewards": verifiedValidatorOutstandingRewards,
+	"bank/balance":                             verifiedBalance,
+	"bank/total":                               verifiedTotalSupply,
+}
+
+func verifiedCommunityPool(ctx context.Context, _ url.Values) (proto.Message, error) {
+	return clients.VerifiedQueryClientInstance.CommunityPool(ctx)
+}
+
+func verifiedValidatorCommission(ctx context.Context, params url.Values) (proto.Message, error) {
+	validator := params.Get("validator")
+	if validator == "" {
+		return nil, errors.New("validator empty")
+	}
+	return clients.VerifiedQueryClientInstance.ValidatorCommission(ctx, validator)
+}
+
+func verifiedValidatorOutstandingRewards(ctx context.Context, params url.Values) (proto.Message, error) {
+	validator := params.Get("validator")
+	if validator == "" {
+		return nil, errors.New("validator empty")
+	}
+	return clients.VerifiedQueryClientInstance.ValidatorOutstandingRewards(ctx, validator)
+}
+
+func verifiedBalance(ctx context.Context, params url.Values) (proto.Message, error) {
+	return clients.VerifiedQueryClientInstance.Balance(ctx, params.Get("address"))
+}
+
+func verifiedTotalSupply(ctx context.Context, _ url.Values) (proto.Message, error) {
+	return clients.VerifiedQueryClientInstance.TotalSupply(ctx)
+}
@@ -0,0 +1,468 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"pundix-homework/random"
+	"pundix-homework/stream"
+)
+
+const (
+	addressReceiveQuery = "tm.event='Tx' AND transfer.recipient='%s'"
+	// batch executions are attested in EndBlock, so they show up on NewBlock
+	withdrawObservedQuery = "tm.event='NewBlock' AND observation.claim_type='CLAIM_TYPE_WITHDRAW' AND observation.state_success='true'"
+	withdrawSubscription  = "gravity"
+	// the observation only has the event nonce, the claims name the token
+	withdrawClaimQuery        = "tm.event='Tx' AND message.module='gravity'"
+	withdrawClaimSubscription = "gravity_claims"
+)
+
+// EventSource is satisfied by *stream.Hub.
+type EventSource interface {
+	Subscribe(ctx context.Context, query string) (*stream.Subscriber, error)
+	Unsubscribe(s *stream.Subscriber)
+}
+
+// CommissionSource is satisfied by *clients.DistributionQueryClient.
+type CommissionSource interface {
+	ValidatorCommission(ctx context.Context, validator string) (*distrtypes.QueryValidatorCommissionResponse, error)
+}
+
+// BatchSource is satisfied by *clients.GravityQueryClient.
+type BatchSource interface {
+	OutgoingTxBatches(ctx context.Context) (*gravitytypes.QueryOutgoingTxBatchesResponse, error)
+}
+
+// Manager evaluates the stored rules: address rules against the event stream,
+// commission and gravity rules by polling.
+type Manager struct {
+	store      *Store
+	deliverer  *Deliverer
+	events     EventSource
+	decode     sdk.TxDecoder
+	commission CommissionSource
+	batches    BatchSource
+	interval   time.Duration
+
+	mtx  sync.Mutex
+	subs map[string]*stream.Subscriber // rule id -> event subscription
+	// withdrawClaims maps the event nonce of withdraw claims to their token
+	// contract until the claim is observed, withdrawPending holds observed
+	// nonces whose claim tx hasn't been seen yet, tendermint publishes the
+	// block before its txs.
+	withdrawClaims    map[uint64]string
+	withdrawPending   map[uint64]time.Time
+	lastWithdrawNonce uint64
+	// withdrawnTokens are the token contracts with a successful withdraw
+	// claim observed since the last poll.
+	withdrawnTokens map[string]bool
+}
+
+func NewManager(store *Store, deliverer *Deliverer, events EventSource, decode sdk.TxDecoder, commission CommissionSource, batches BatchSource, interval time.Duration) *Manager {
+	return &Manager{
+		store:           store,
+		deliverer:       deliverer,
+		events:          events,
+		decode:          decode,
+		commission:      commission,
+		batches:         batches,
+		interval:        interval,
+		subs:            make(map[string]*stream.Subscriber),
+		withdrawClaims:  make(map[uint64]string),
+		withdrawPending: make(map[uint64]time.Time),
+		withdrawnTokens: make(map[string]bool),
+	}
+}
+
+// Start resumes the stored rules and pending deliveries and polls until ctx
+// is done.
+func (m *Manager) Start(ctx context.Context) {
+	for _, rule := range m.store.ListRules() {
+		m.activate(rule)
+	}
+	m.deliverer.Resume()
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.poll()
+			}
+		}
+	}()
+}
+
+func (m *Manager) AddRule(rule Rule) error {
+	if err := m.store.AddRule(rule); err != nil {
+		return err
+	}
+	m.activate(rule)
+	return nil
+}
+
+// DeleteRule deletes rule id of owner, the rules of other owners are not
+// found.
+func (m *Manager) DeleteRule(owner, id string) error {
+	rule, err := m.store.Rule(id)
+	if err != nil {
+		return err
+	}
+	if rule.Owner != owner {
+		return ErrNotFound
+	}
+	if err = m.store.DeleteRule(id); err != nil {
+		return err
+	}
+	keys := []string{id}
+	if rule.Type == RuleGravityBatchExecuted {
+		// the shared subscriptions go with the last gravity rule
+		if m.countRules(RuleGravityBatchExecuted) > 0 {
+			return nil
+		}
+		keys = []string{withdrawSubscription, withdrawClaimSubscription}
+	}
+	for _, key := range keys {
+		m.mtx.Lock()
+		sub, ok := m.subs[key]
+		delete(m.subs, key)
+		m.mtx.Unlock()
+		if ok {
+			m.events.Unsubscribe(sub)
+		}
+	}
+	return nil
+}
+
+// countRules counts the stored rules of type ruleType.
+func (m *Manager) countRules(ruleType string) int {
+	n := 0
+	for _, rule := range m.store.ListRules() {
+		if rule.Type == ruleType {
+			n++
+		}
+	}
+	return n
+}
+
+// Redeliver moves a dead letter of owner back to the delivery queue.
+func (m *Manager) Redeliver(owner, id string) error {
+	delivery, err := m.store.TakeDeadLetter(owner, id)
+	if err != nil {
+		return err
+	}
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.FailedAt = time.Time{}
+	m.deliverer.Enqueue(delivery)
+	return nil
+}
+
+func (m *Manager) activate(rule Rule) {
+	switch rule.Type {
+	case RuleAddressReceive:
+		m.subscribe(rule.ID, fmt.Sprintf(addressReceiveQuery, rule.Address), func(event ctypes.ResultEvent) {
+			if transfers := receivedTransfers(event, rule.Address); len(transfers) > 0 {
+				m.notify(rule, map[string]interface{}{
+					"address":   rule.Address,
+					"tx_hash":   first(event.Events["tx.hash"]),
+					"height":    first(event.Events["tx.height"]),
+					"transfers": transfers,
+				})
+			}
+		})
+	case RuleGravityBatchExecuted:
+		// the subscriptions serve every gravity rule
+		m.subscribe(withdrawSubscription, withdrawObservedQuery, m.observeWithdraws)
+		m.subscribe(withdrawClaimSubscription, withdrawClaimQuery, m.recordWithdrawClaims)
+	}
+}
+
+// observeWithdraws marks the token of every successful withdraw observation
+// of a block as withdrawn, or waits for its claim.
+func (m *Manager) observeWithdraws(event ctypes.ResultEvent) {
+	claimTypes := event.Events["observation.claim_type"]
+	nonces := event.Events["observation.event_nonce"]
+	successes := event.Events["observation.state_success"]
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for i, claimType := range claimTypes {
+		if claimType != gravitytypes.CLAIM_TYPE_WITHDRAW.String() || i >= len(nonces) || i >= len(successes) || successes[i] != "true" {
+			continue
+		}
+		nonce, err := strconv.ParseUint(nonces[i], 10, 64)
+		if err != nil {
+			continue
+		}
+		if token, ok := m.withdrawClaims[nonce]; ok {
+			m.withdrawnTokens[token] = true
+		} else {
+			m.withdrawPending[nonce] = time.Now()
+		}
+		if nonce > m.lastWithdrawNonce {
+			m.lastWithdrawNonce = nonce
+		}
+	}
+	// event nonces are observed in order, older claims never will be
+	for nonce := range m.withdrawClaims {
+		if nonce <= m.lastWithdrawNonce {
+			delete(m.withdrawClaims, nonce)
+		}
+	}
+}
+
+// recordWithdrawClaims remembers the token contract of the withdraw claims of
+// a gravity tx.
+func (m *Manager) recordWithdrawClaims(event ctypes.ResultEvent) {
+	data, ok := event.Data.(tmtypes.EventDataTx)
+	if !ok || data.Result.Code != 0 {
+		return
+	}
+	tx, err := m.decode(data.Tx)
+	if err != nil {
+		return
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for _, msg := range tx.GetMsgs() {
+		claim, ok := msg.(*gravitytypes.MsgWithdrawClaim)
+		if !ok {
+			continue
+		}
+		if _, pending := m.withdrawPending[claim.EventNonce]; pending {
+			m.withdrawnTokens[claim.TokenContract] = true
+			delete(m.withdrawPending, claim.EventNonce)
+		} else if claim.EventNonce > m.lastWithdrawNonce {
+			m.withdrawClaims[claim.EventNonce] = claim.TokenContract
+		}
+	}
+}
+
+// subscribe starts consuming query unless key already has a subscription.
+// Failed or evicted subscriptions are retried on the next poll.
+func (m *Manager) subscribe(key, query string, handle func(ctypes.ResultEvent)) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.subs[key]; ok {
+		return
+	}
+	sub, err := m.events.Subscribe(context.Background(), query)
+	if err != nil {
+		return
+	}
+	m.subs[key] = sub
+	go func() {
+		for event := range sub.C {
+			handle(event)
+		}
+		m.mtx.Lock()
+		if m.subs[key] == sub {
+			delete(m.subs, key)
+		}
+		m.mtx.Unlock()
+	}()
+}
+
+type transfer struct {
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount    string `json:"amount"`
+}
+
+// receivedTransfers pairs the flattened transfer attributes of a tx event and
+// keeps the ones paying address.
+func receivedTransfers(event ctypes.ResultEvent, address string) []transfer {
+	recipients := event.Events["transfer.recipient"]
+	senders := event.Events["transfer.sender"]
+	amounts := event.Events["transfer.amount"]
+	var transfers []transfer
+	for i, recipient := range recipients {
+		if recipient != address || i >= len(amounts) {
+			continue
+		}
+		t := transfer{Recipient: recipient, Amount: amounts[i]}
+		if i < len(senders) {
+			t.Sender = senders[i]
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers
+}
+
+func (m *Manager) poll() {
+	// a hung node call gives up before the next poll
+	ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+	defer cancel()
+
+	m.mtx.Lock()
+	// an observation whose claim tx never came is given up on
+	for nonce, observedAt := range m.withdrawPending {
+		if time.Since(observedAt) > m.interval {
+			delete(m.withdrawPending, nonce)
+		}
+	}
+	m.mtx.Unlock()
+
+	var (
+		batches   *gravitytypes.QueryOutgoingTxBatchesResponse
+		withdrawn map[string]bool
+	)
+	for _, rule := range m.store.ListRules() {
+		m.activate(rule)
+		switch rule.Type {
+		case RuleCommissionThreshold:
+			m.evaluateCommission(ctx, rule)
+		case RuleGravityBatchExecuted:
+			if batches == nil {
+				withdrawn = m.takeWithdrawnTokens()
+				res, err := m.batches.OutgoingTxBatches(ctx)
+				if err != nil {
+					// the withdraws are matched against the batches of the next poll
+					m.restoreWithdrawnTokens(withdrawn)
+					continue
+				}
+				batches = res
+			}
+			m.evaluateBatches(rule, batches.Batches, withdrawn)
+		}
+	}
+}
+
+// takeWithdrawnTokens returns the tokens withdrawn since the last call and
+// starts over.
+func (m *Manager) takeWithdrawnTokens() map[string]bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	withdrawn := m.withdrawnTokens
+	m.withdrawnTokens = make(map[string]bool)
+	return withdrawn
+}
+
+// restoreWithdrawnTokens puts back tokens taken by a poll that failed.
+func (m *Manager) restoreWithdrawnTokens(withdrawn map[string]bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for token := range withdrawn {
+		m.withdrawnTokens[token] = true
+	}
+}
+
+func (m *Manager) evaluateCommission(ctx context.Context, rule Rule) {
+	res, err := m.commission.ValidatorCommission(ctx, rule.Validator)
+	if err != nil {
+		return
+	}
+	threshold, err := sdk.ParseDecCoin(rule.Threshold)
+	if err != nil {
+		return
+	}
+	current := res.Commission.Commission.AmountOf(threshold.Denom)
+	above := current.GTE(threshold.Amount)
+
+	previous := m.store.state(rule.ID).AboveThreshold
+	if err = m.store.updateState(rule.ID, func(state *ruleState) { state.AboveThreshold = &above }); err != nil {
+		return
+	}
+	// the first observation only records the side of the threshold we are on
+	if previous == nil || *previous == above {
+		return
+	}
+	direction := "below"
+	if above {
+		direction = "above"
+	}
+	m.notify(rule, map[string]interface{}{
+		"validator":  rule.Validator,
+		"threshold":  rule.Threshold,
+		"commission": current.String() + threshold.Denom,
+		"direction":  direction,
+	})
+}
+
+// evaluateBatches diffs the pending batches of the rule's token against the
+// previous poll. Batches also disappear when they time out, so a vanished
+// batch only counts as executed when a successful withdraw claim of its token
+// was observed since the last poll. Executing a batch cancels every older
+// batch of the token, so only the newest vanished nonce is reported.
+func (m *Manager) evaluateBatches(rule Rule, batches []*gravitytypes.OutgoingTxBatch, withdrawn map[string]bool) {
+	var pending []uint64
+	for _, batch := range batches {
+		if batch.TokenContract == rule.TokenContract {
+			pending = append(pending, batch.BatchNonce)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+
+	previous := m.store.state(rule.ID).PendingBatches
+	if err := m.store.updateState(rule.ID, func(state *ruleState) { state.PendingBatches = pending }); err != nil {
+		return
+	}
+	if !withdrawn[rule.TokenContract] {
+		return
+	}
+
+	var executed uint64
+	for _, nonce := range previous {
+		if !containsNonce(pending, nonce) && nonce > executed {
+			executed = nonce
+		}
+	}
+	if executed == 0 {
+		return
+	}
+	m.notify(rule, map[string]interface{}{
+		"token_contract": rule.TokenContract,
+		"batch_nonce":    executed,
+	})
+}
+
+func containsNonce(nonces []uint64, nonce uint64) bool {
+	for _, n := range nonces {
+		if n == nonce {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) notify(rule Rule, data interface{}) {
+	bz, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	id := random.Hex(8)
+	m.deliverer.Enqueue(Delivery{
+		ID:     id,
+		Owner:  rule.Owner,
+		URL:    rule.WebhookURL,
+		Secret: rule.Secret,
+		Notification: Notification{
+			ID:        id,
+			RuleID:    rule.ID,
+			Type:      rule.Type,
+			CreatedAt: time.Now().UTC(),
+			Data:      bz,
+		},
+	})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,296 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
+	gravitytypes "github.com/functionx/fx-core/x/gravity/types"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	"pundix-homework/stream"
+
+	// also sets the fx bech32 prefixes
+	"github.com/functionx/fx-core/app"
+)
+
+type fakeCommission struct {
+	amount int64
+}
+
+func (f *fakeCommission) ValidatorCommission(context.Context, string) (*distrtypes.QueryValidatorCommissionResponse, error) {
+	coins := sdk.NewDecCoins(sdk.NewDecCoin("FX", sdk.NewInt(f.amount)))
+	return &distrtypes.QueryValidatorCommissionResponse{
+		Commission: distrtypes.ValidatorAccumulatedCommission{Commission: coins},
+	}, nil
+}
+
+type fakeBatches struct {
+	batches []*gravitytypes.OutgoingTxBatch
+	err     error
+}
+
+func (f *fakeBatches) OutgoingTxBatches(ctx context.Context) (*gravitytypes.QueryOutgoingTxBatchesResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("no deadline")
+	}
+	return &gravitytypes.QueryOutgoingTxBatchesResponse{Batches: f.batches}, nil
+}
+
+// fakeEvents hands out one channel per query.
+type fakeEvents struct {
+	mtx    sync.Mutex
+	topics map[string]chan ctypes.ResultEvent
+}
+
+func newFakeEvents() *fakeEvents {
+	return &fakeEvents{topics: make(map[string]chan ctypes.ResultEvent)}
+}
+
+func (f *fakeEvents) Subscribe(_ context.Context, query string) (*stream.Subscriber, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	ch := make(chan ctypes.ResultEvent, 10)
+	f.topics[query] = ch
+	return &stream.Subscriber{C: ch}, nil
+}
+
+func (f *fakeEvents) Unsubscribe(s *stream.Subscriber) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for query, ch := range f.topics {
+		if s.C == (<-chan ctypes.ResultEvent)(ch) {
+			close(ch)
+			delete(f.topics, query)
+		}
+	}
+}
+
+func (f *fakeEvents) subscribed(query string) bool {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	_, ok := f.topics[query]
+	return ok
+}
+
+func newTestStore(t *testing.T) *Store {
+	store, err := OpenStore(filepath.Join(t.TempDir(), "watches.json"))
+	require.NoError(t, err)
+	return store
+}
+
+func Test_WebhookSignedAndDeadLettered(t *testing.T) {
+	received := make(chan *http.Request, 10)
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := newTestStore(t)
+	deliverer := NewDeliverer(store, 2, time.Millisecond, 1)
+	deliverer.Enqueue(Delivery{ID: "d1", Owner: "k1", URL: server.URL, Secret: "s3cret", Notification: Notification{ID: "d1"}})
+
+	r, body := <-received, <-bodies
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, "sha256="+Sign("s3cret", timestamp, body), r.Header.Get(SignatureHeader))
+
+	require.Eventually(t, func() bool { return len(store.ListDeadLetters()) == 1 }, time.Second, time.Millisecond)
+	dead := store.ListDeadLetters()[0]
+	require.Equal(t, 2, dead.Attempts)
+	require.Contains(t, dead.LastError, "500")
+
+	// dead letters survive a restart
+	reopened, err := OpenStore(store.path)
+	require.NoError(t, err)
+	require.Equal(t, "s3cret", reopened.ListDeadLetters()[0].Secret)
+	require.Empty(t, reopened.ListPending())
+
+	// only the key that created the rule takes its dead letters back
+	_, err = reopened.TakeDeadLetter("k2", "d1")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = reopened.TakeDeadLetter("k1", "d1")
+	require.NoError(t, err)
+}
+
+func Test_PendingDeliveriesResumed(t *testing.T) {
+	store := newTestStore(t)
+	// no workers, the process stops before delivering
+	(&Deliverer{store: store, queue: make(chan Delivery, 10)}).Enqueue(Delivery{ID: "d1", Notification: Notification{ID: "d1"}})
+
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		_ = json.NewDecoder(r.Body).Decode(&n)
+		received <- n.ID
+	}))
+	defer server.Close()
+
+	reopened, err := OpenStore(store.path)
+	require.NoError(t, err)
+	require.Len(t, reopened.ListPending(), 1)
+	reopened.Pending[0].URL = server.URL
+	deliverer := NewDeliverer(reopened, 2, time.Millisecond, 1)
+	deliverer.Resume()
+	require.Equal(t, "d1", <-received)
+	require.Eventually(t, func() bool { return len(reopened.ListPending()) == 0 }, time.Second, time.Millisecond)
+	require.Empty(t, reopened.ListDeadLetters())
+}
+
+func Test_CommissionThresholdCrossing(t *testing.T) {
+	store := newTestStore(t)
+	deliverer := &Deliverer{store: store, queue: make(chan Delivery, 10)}
+	commission := &fakeCommission{amount: 5}
+	m := NewManager(store, deliverer, nil, nil, commission, nil, time.Hour)
+
+	rule := Rule{Type: RuleCommissionThreshold, Validator: "fxvaloper1a73plz6w7fc8ydlwxddanc7a239kk45jnl9xwj", Threshold: "10FX", WebhookURL: "http://localhost/hook"}
+	require.NoError(t, rule.Validate())
+	require.NoError(t, m.AddRule(rule))
+
+	m.poll()
+	require.Len(t, deliverer.queue, 0, "first observation only records state")
+
+	commission.amount = 12
+	m.poll()
+	require.Len(t, deliverer.queue, 1)
+	var data map[string]string
+	require.NoError(t, json.Unmarshal((<-deliverer.queue).Notification.Data, &data))
+	require.Equal(t, "above", data["direction"])
+
+	m.poll()
+	require.Len(t, deliverer.queue, 0, "no notification while staying above")
+}
+
+func Test_GravityBatchExecuted(t *testing.T) {
+	store := newTestStore(t)
+	deliverer := &Deliverer{store: store, queue: make(chan Delivery, 10)}
+	batches := &fakeBatches{batches: []*gravitytypes.OutgoingTxBatch{
+		{TokenContract: "0xtoken", BatchNonce: 3},
+		{TokenContract: "0xtoken", BatchNonce: 4},
+		{TokenContract: "0xother", BatchNonce: 5},
+	}}
+	m := NewManager(store, deliverer, nil, nil, nil, batches, time.Hour)
+	rule := Rule{Type: RuleGravityBatchExecuted, TokenContract: "0xtoken", WebhookURL: "http://localhost/hook"}
+	require.NoError(t, rule.Validate())
+	require.NoError(t, store.AddRule(rule))
+
+	m.evaluateBatches(rule, batches.batches, nil)
+	// a withdraw of another token doesn't tell whether the batches timed out
+	m.evaluateBatches(rule, batches.batches[2:], map[string]bool{"0xother": true})
+	require.Len(t, deliverer.queue, 0)
+
+	m.evaluateBatches(rule, batches.batches, nil)
+	// nonce 4 executed, which cancelled 3
+	m.evaluateBatches(rule, batches.batches[2:], map[string]bool{"0xtoken": true})
+
+	require.Len(t, deliverer.queue, 1)
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal((<-deliverer.queue).Notification.Data, &data))
+	require.Equal(t, float64(4), data["batch_nonce"])
+}
+
+func Test_WithdrawKeptWhenBatchesFail(t *testing.T) {
+	store := newTestStore(t)
+	deliverer := &Deliverer{store: store, queue: make(chan Delivery, 10)}
+	batches := &fakeBatches{batches: []*gravitytypes.OutgoingTxBatch{{TokenContract: "0xtoken", BatchNonce: 3}}}
+	m := NewManager(store, deliverer, newFakeEvents(), nil, nil, batches, time.Hour)
+	rule := Rule{Type: RuleGravityBatchExecuted, TokenContract: "0xtoken", WebhookURL: "http://localhost/hook"}
+	require.NoError(t, rule.Validate())
+	require.NoError(t, m.AddRule(rule))
+	m.poll()
+
+	// the batch executes while the node can't be queried
+	m.withdrawnTokens["0xtoken"] = true
+	batches.batches, batches.err = nil, errors.New("node down")
+	m.poll()
+	require.Len(t, deliverer.queue, 0)
+	require.True(t, m.withdrawnTokens["0xtoken"])
+
+	batches.err = nil
+	m.poll()
+	require.Len(t, deliverer.queue, 1)
+	require.Empty(t, m.withdrawnTokens)
+}
+
+func Test_WithdrawSubscriptionSharedByGravityRules(t *testing.T) {
+	store := newTestStore(t)
+	events := newFakeEvents()
+	m := NewManager(store, &Deliverer{store: store}, events, nil, nil, nil, time.Hour)
+
+	var ids []string
+	for _, token := range []string{"0xtoken", "0xother"} {
+		rule := Rule{Type: RuleGravityBatchExecuted, TokenContract: token, WebhookURL: "http://localhost/hook"}
+		require.NoError(t, rule.Validate())
+		rule.Owner = "k1"
+		require.NoError(t, m.AddRule(rule))
+		ids = append(ids, rule.ID)
+	}
+	require.True(t, events.subscribed(withdrawObservedQuery))
+	require.True(t, events.subscribed(withdrawClaimQuery))
+
+	require.ErrorIs(t, m.DeleteRule("k2", ids[0]), ErrNotFound)
+	require.NoError(t, m.DeleteRule("k1", ids[0]))
+	require.True(t, events.subscribed(withdrawObservedQuery), "still used by the other rule")
+	require.NoError(t, m.DeleteRule("k1", ids[1]))
+	require.False(t, events.subscribed(withdrawObservedQuery))
+	require.False(t, events.subscribed(withdrawClaimQuery))
+}
+
+func Test_WithdrawClaimsMatchObservations(t *testing.T) {
+	txConfig := app.MakeEncodingConfig().TxConfig
+	m := NewManager(newTestStore(t), nil, nil, txConfig.TxDecoder(), nil, nil, time.Hour)
+	claimTx := func(nonce uint64, token string) ctypes.ResultEvent {
+		builder := txConfig.NewTxBuilder()
+		require.NoError(t, builder.SetMsgs(&gravitytypes.MsgWithdrawClaim{EventNonce: nonce, TokenContract: token}))
+		bz, err := txConfig.TxEncoder()(builder.GetTx())
+		require.NoError(t, err)
+		return ctypes.ResultEvent{Data: tmtypes.EventDataTx{TxResult: abci.TxResult{Tx: bz}}}
+	}
+	observed := func(nonces ...string) ctypes.ResultEvent {
+		event := ctypes.ResultEvent{Events: map[string][]string{}}
+		for _, nonce := range nonces {
+			event.Events["observation.claim_type"] = append(event.Events["observation.claim_type"], gravitytypes.CLAIM_TYPE_WITHDRAW.String())
+			event.Events["observation.event_nonce"] = append(event.Events["observation.event_nonce"], nonce)
+			event.Events["observation.state_success"] = append(event.Events["observation.state_success"], "true")
+		}
+		return event
+	}
+
+	// the claim comes before the block observing it
+	m.recordWithdrawClaims(claimTx(7, "0xtoken"))
+	m.recordWithdrawClaims(claimTx(8, "0xother"))
+	m.observeWithdraws(observed("7"))
+	require.Equal(t, map[string]bool{"0xtoken": true}, m.withdrawnTokens)
+
+	// or within the observing block, which is published first
+	m.withdrawnTokens = map[string]bool{}
+	m.observeWithdraws(observed("8", "9"))
+	m.recordWithdrawClaims(claimTx(9, "0xthird"))
+	require.Equal(t, map[string]bool{"0xother": true, "0xthird": true}, m.withdrawnTokens)
+	require.Empty(t, m.withdrawClaims)
+	require.Empty(t, m.withdrawPending)
+
+	// late votes on observed claims are ignored
+	m.recordWithdrawClaims(claimTx(9, "0xthird"))
+	require.Empty(t, m.withdrawClaims)
+}
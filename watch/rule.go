@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"pundix-homework/random"
+)
+
+const (
+	// RuleAddressReceive fires for every tx that transfers funds to Address.
+	RuleAddressReceive = "address_receive"
+	// RuleCommissionThreshold fires when the commission of Validator crosses
+	// Threshold in either direction.
+	RuleCommissionThreshold = "commission_threshold"
+	// RuleGravityBatchExecuted fires when an outgoing gravity batch of
+	// TokenContract is executed on Ethereum.
+	RuleGravityBatchExecuted = "gravity_batch_executed"
+)
+
+// Rule is a watch registered by a partner, Owner is the id of the API key
+// that created it and the only one that sees it.
+type Rule struct {
+	ID            string    `json:"id"`
+	Owner         string    `json:"owner"`
+	Type          string    `json:"type"`
+	Address       string    `json:"address,omitempty"`
+	Validator     string    `json:"validator,omitempty"`
+	Threshold     string    `json:"threshold,omitempty"`
+	TokenContract string    `json:"token_contract,omitempty"`
+	WebhookURL    string    `json:"webhook_url"`
+	Secret        string    `json:"secret,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ruleState is what a rule remembers between two evaluations.
+type ruleState struct {
+	AboveThreshold *bool    `json:"above_threshold,omitempty"`
+	PendingBatches []uint64 `json:"pending_batches,omitempty"`
+}
+
+// Validate checks the user supplied fields and fills ID, Secret and CreatedAt.
+func (r *Rule) Validate() error {
+	u, err := url.Parse(r.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid webhook_url")
+	}
+
+	switch r.Type {
+	case RuleAddressReceive:
+		if _, err := sdk.AccAddressFromBech32(r.Address); err != nil {
+			return err
+		}
+	case RuleCommissionThreshold:
+		if _, err := sdk.ValAddressFromBech32(r.Validator); err != nil {
+			return err
+		}
+		if _, err := sdk.ParseDecCoin(r.Threshold); err != nil {
+			return fmt.Errorf("invalid threshold: %w", err)
+		}
+	case RuleGravityBatchExecuted:
+		if r.TokenContract == "" {
+			return errors.New("token_contract empty")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %s", r.Type)
+	}
+
+	if r.Secret == "" {
+		r.Secret = random.Hex(32)
+	}
+	r.ID = random.Hex(8)
+	r.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// Public hides the signing secret.
+func (r Rule) Public() Rule {
+	r.Secret = ""
+	return r
+}
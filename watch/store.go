@@ -0,0 +1,175 @@
+package watch
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"pundix-homework/jsonfile"
+)
+
+const maxDeadLetters = 1000
+
+var ErrNotFound = errors.New("not found")
+
+// Store keeps rules, their evaluation state, queued and undeliverable
+// webhooks in a JSON file that is rewritten on every change.
+type Store struct {
+	path string
+
+	mtx         sync.RWMutex
+	Rules       map[string]Rule       `json:"rules"`
+	States      map[string]*ruleState `json:"states"`
+	Pending     []Delivery            `json:"pending"`
+	DeadLetters []Delivery            `json:"dead_letters"`
+}
+
+// OpenStore loads the rules, their state and the deliveries kept at path.
+func OpenStore(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		Rules:  make(map[string]Rule),
+		States: make(map[string]*ruleState),
+	}
+	if err := jsonfile.Load(path, s); err != nil {
+		return nil, err
+	}
+	if s.States == nil {
+		s.States = make(map[string]*ruleState)
+	}
+	return s, nil
+}
+
+func (s *Store) AddRule(rule Rule) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.Rules[rule.ID] = rule
+	return s.save()
+}
+
+func (s *Store) DeleteRule(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.Rules[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.Rules, id)
+	delete(s.States, id)
+	return s.save()
+}
+
+func (s *Store) Rule(id string) (Rule, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	rule, ok := s.Rules[id]
+	if !ok {
+		return Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+// ListRules returns the rules ordered by creation time.
+func (s *Store) ListRules() []Rule {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	rules := make([]Rule, 0, len(s.Rules))
+	for _, rule := range s.Rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules
+}
+
+// updateState applies fn to the state of rule id and persists the result.
+func (s *Store) updateState(id string, fn func(state *ruleState)) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.Rules[id]; !ok {
+		return ErrNotFound
+	}
+	state, ok := s.States[id]
+	if !ok {
+		state = &ruleState{}
+		s.States[id] = state
+	}
+	fn(state)
+	return s.save()
+}
+
+func (s *Store) state(id string) ruleState {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	if state, ok := s.States[id]; ok {
+		return *state
+	}
+	return ruleState{}
+}
+
+// AddPending records a queued delivery until it is done.
+func (s *Store) AddPending(d Delivery) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.Pending = append(s.Pending, d)
+	return s.save()
+}
+
+// RemovePending forgets the delivery id once it succeeded.
+func (s *Store) RemovePending(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.removePending(id)
+	return s.save()
+}
+
+func (s *Store) ListPending() []Delivery {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return append([]Delivery(nil), s.Pending...)
+}
+
+func (s *Store) removePending(id string) {
+	for i, d := range s.Pending {
+		if d.ID == id {
+			s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddDeadLetter moves d from the pending deliveries to the dead letters.
+func (s *Store) AddDeadLetter(d Delivery) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.removePending(d.ID)
+	s.DeadLetters = append(s.DeadLetters, d)
+	if len(s.DeadLetters) > maxDeadLetters {
+		s.DeadLetters = s.DeadLetters[len(s.DeadLetters)-maxDeadLetters:]
+	}
+	return s.save()
+}
+
+// TakeDeadLetter removes and returns the dead letter of owner with the given
+// id.
+func (s *Store) TakeDeadLetter(owner, id string) (Delivery, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i, d := range s.DeadLetters {
+		if d.ID == id && d.Owner == owner {
+			s.DeadLetters = append(s.DeadLetters[:i], s.DeadLetters[i+1:]...)
+			return d, s.save()
+		}
+	}
+	return Delivery{}, ErrNotFound
+}
+
+func (s *Store) ListDeadLetters() []Delivery {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return append([]Delivery(nil), s.DeadLetters...)
+}
+
+// save persists the whole store. Callers hold the lock.
+func (s *Store) save() error {
+	// rules carry the webhook signing secrets
+	return jsonfile.Save(s.path, s, 0o600)
+}
@@ -0,0 +1,155 @@
+package watch
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Watch-Signature"
+	TimestampHeader = "X-Watch-Timestamp"
+)
+
+// Notification is the JSON body posted to the webhook.
+type Notification struct {
+	ID        string          `json:"id"`
+	RuleID    string          `json:"rule_id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Delivery is a notification bound to its destination, kept in the dead
+// letter list once every attempt failed.
+type Delivery struct {
+	ID           string       `json:"id"`
+	Owner        string       `json:"owner"`
+	URL          string       `json:"url"`
+	Secret       string       `json:"secret,omitempty"`
+	Notification Notification `json:"notification"`
+	Attempts     int          `json:"attempts"`
+	LastError    string       `json:"last_error,omitempty"`
+	FailedAt     time.Time    `json:"failed_at,omitempty"`
+}
+
+// Public hides the signing secret.
+func (d Delivery) Public() Delivery {
+	d.Secret = ""
+	return d
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" keyed with secret,
+// receivers recompute it to authenticate the webhook.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliverer posts notifications, retrying with exponential backoff and
+// moving them to the store's dead letter list when all attempts fail. Queued
+// deliveries are kept in the store until they are done, so a restart resumes
+// them.
+type Deliverer struct {
+	client      *http.Client
+	store       *Store
+	maxAttempts int
+	backoff     time.Duration
+	queue       chan Delivery
+}
+
+func NewDeliverer(store *Store, maxAttempts int, backoff time.Duration, workers int) *Deliverer {
+	d := &Deliverer{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		store:       store,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		queue:       make(chan Delivery, 1024),
+	}
+	for i := 0; i < workers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+// Enqueue schedules a delivery, a full queue sends it straight to the dead
+// letter list instead of blocking the evaluator.
+func (d *Deliverer) Enqueue(delivery Delivery) {
+	_ = d.store.AddPending(delivery)
+	d.push(delivery)
+}
+
+// Resume queues the deliveries left pending by the previous process.
+func (d *Deliverer) Resume() {
+	for _, delivery := range d.store.ListPending() {
+		d.push(delivery)
+	}
+}
+
+func (d *Deliverer) push(delivery Delivery) {
+	select {
+	case d.queue <- delivery:
+	default:
+		delivery.LastError = "delivery queue full"
+		delivery.FailedAt = time.Now().UTC()
+		_ = d.store.AddDeadLetter(delivery)
+	}
+}
+
+func (d *Deliverer) worker() {
+	for delivery := range d.queue {
+		d.deliver(delivery)
+	}
+}
+
+func (d *Deliverer) deliver(delivery Delivery) {
+	backoff := d.backoff
+	for delivery.Attempts < d.maxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		delivery.Attempts++
+		err := d.post(delivery)
+		if err == nil {
+			_ = d.store.RemovePending(delivery.ID)
+			return
+		}
+		delivery.LastError = err.Error()
+	}
+	delivery.FailedAt = time.Now().UTC()
+	_ = d.store.AddDeadLetter(delivery)
+}
+
+func (d *Deliverer) post(delivery Delivery) error {
+	body, err := json.Marshal(delivery.Notification)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
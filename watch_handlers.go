@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"pundix-homework/auth"
+	"pundix-homework/clients"
+	"pundix-homework/watch"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	watchPollInterval    = 30 * time.Second
+	webhookMaxAttempts   = 5
+	webhookBackoff       = 2 * time.Second
+	webhookWorkers       = 4
+	defaultWatchStore    = "watches.json"
+	watchStorePathEnvKey = "WATCH_STORE_PATH"
+)
+
+var watchManager *watch.Manager
+var watchStore *watch.Store
+
+// startWatches loads the persisted rules and starts evaluating them.
+func startWatches(ctx context.Context) {
+	path := os.Getenv(watchStorePathEnvKey)
+	if path == "" {
+		path = defaultWatchStore
+	}
+	store, err := watch.OpenStore(path)
+	if err != nil {
+		panic(err)
+	}
+	deliverer := watch.NewDeliverer(store, webhookMaxAttempts, webhookBackoff, webhookWorkers)
+	watchStore = store
+	watchManager = watch.NewManager(store, deliverer, streamHub, clients.EncodingConfig().TxConfig.TxDecoder(),
+		clients.DistrQueryClientInstance, clients.GravityQueryClientInstance, watchPollInterval)
+	watchManager.Start(ctx)
+}
+
+func CreateWatchHandler(c *gin.Context) {
+	var rule watch.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.Owner = auth.KeyID(c)
+	if err := watchManager.AddRule(rule); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the only response that carries the webhook signing secret
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListWatchesHandler lists the rules of the key of the request, the rules
+// and dead letters of other keys are never shown.
+func ListWatchesHandler(c *gin.Context) {
+	owner := auth.KeyID(c)
+	rules := []watch.Rule{}
+	for _, rule := range watchStore.ListRules() {
+		if rule.Owner == owner {
+			rules = append(rules, rule.Public())
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"watches": rules})
+}
+
+func GetWatchHandler(c *gin.Context) {
+	rule, err := watchStore.Rule(c.Param("id"))
+	if err == nil && rule.Owner != auth.KeyID(c) {
+		err = watch.ErrNotFound
+	}
+	if err != nil {
+		watchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule.Public())
+}
+
+func DeleteWatchHandler(c *gin.Context) {
+	if err := watchManager.DeleteRule(auth.KeyID(c), c.Param("id")); err != nil {
+		watchError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func ListDeadLettersHandler(c *gin.Context) {
+	owner := auth.KeyID(c)
+	deliveries := []watch.Delivery{}
+	for _, d := range watchStore.ListDeadLetters() {
+		if d.Owner == owner {
+			deliveries = append(deliveries, d.Public())
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": deliveries})
+}
+
+func RedeliverDeadLetterHandler(c *gin.Context) {
+	if err := watchManager.Redeliver(auth.KeyID(c), c.Param("id")); err != nil {
+		watchError(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func watchError(c *gin.Context, err error) {
+	if errors.Is(err, watch.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,84 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"pundix-homework/snapshot"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/cors"
+)
+
+// CORSConfig lists what cross-origin browsers may do. CORS is off when
+// AllowedOrigins is empty.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// CORSConfigFromEnv reads the comma separated CORS_ALLOWED_ORIGINS,
+// CORS_ALLOWED_METHODS and CORS_ALLOWED_HEADERS lists and
+// CORS_ALLOW_CREDENTIALS.
+func CORSConfigFromEnv() (CORSConfig, error) {
+	cfg := CORSConfig{
+		AllowedOrigins: splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "traceparent", "If-None-Match", snapshot.HeaderName},
+		MaxAge:         600,
+	}
+	if methods := splitList(os.Getenv("CORS_ALLOWED_METHODS")); len(methods) > 0 {
+		cfg.AllowedMethods = methods
+	}
+	if headers := splitList(os.Getenv("CORS_ALLOWED_HEADERS")); len(headers) > 0 {
+		cfg.AllowedHeaders = headers
+	}
+	if value := os.Getenv("CORS_ALLOW_CREDENTIALS"); value != "" {
+		credentials, err := strconv.ParseBool(value)
+		if err != nil {
+			return CORSConfig{}, err
+		}
+		cfg.AllowCredentials = credentials
+	}
+	return cfg, nil
+}
+
+// CORS answers preflight requests and adds the CORS headers to actual
+// requests. It must run before any middleware that can reject the request,
+// so browsers can read the error.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	if len(cfg.AllowedOrigins) == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	handler := cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   []string{"X-Request-ID", "X-Cache", "X-Verified", "ETag", "Retry-After"},
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	})
+	return func(c *gin.Context) {
+		handler.HandlerFunc(c.Writer, c.Request)
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			// HandlerFunc already wrote the preflight response
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatParam    = "format"
+	PrecisionParam = "precision"
+	SeparatorParam = "separator"
+
+	FormatDisplay = "display"
+
+	defaultPrecision = 6
+	maxPrecision     = 36
+	defaultSeparator = ","
+)
+
+// DenomResolver maps a base denom to the denom and exponent amounts are
+// displayed in, ok is false when the denom is unknown.
+type DenomResolver interface {
+	DisplayUnit(ctx context.Context, base string) (display string, exponent uint32, ok bool)
+}
+
+// displayOptions are the ?format=display settings.
+type displayOptions struct {
+	precision int
+	separator string
+}
+
+func parseDisplayOptions(format, precision string, separator *string) (*displayOptions, error) {
+	if format == "" {
+		return nil, nil
+	}
+	if format != FormatDisplay {
+		return nil, errors.New("unknown format " + format)
+	}
+	opts := &displayOptions{precision: defaultPrecision, separator: defaultSeparator}
+	if precision != "" {
+		p, err := strconv.Atoi(precision)
+		if err != nil || p < 0 || p > maxPrecision {
+			return nil, errors.New("precision must be between 0 and 36")
+		}
+		opts.precision = p
+	}
+	if separator != nil {
+		opts.separator = *separator
+	}
+	return opts, nil
+}
+
+// displayAmounts rewrites every {"denom", "amount"} object below value, which
+// covers Coin and DecCoin fields whatever the route, to display units.
+func displayAmounts(ctx context.Context, value interface{}, denoms DenomResolver, opts *displayOptions) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if coin, ok := displayCoin(ctx, value, denoms, opts); ok {
+			return coin
+		}
+		for key, v := range value {
+			value[key] = displayAmounts(ctx, v, denoms, opts)
+		}
+		return value
+	case []interface{}:
+		for i, v := range value {
+			value[i] = displayAmounts(ctx, v, denoms, opts)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func displayCoin(ctx context.Context, coin map[string]interface{}, denoms DenomResolver, opts *displayOptions) (map[string]interface{}, bool) {
+	denom, ok := coin["denom"].(string)
+	if !ok {
+		return nil, false
+	}
+	amount, ok := coin["amount"].(string)
+	if !ok {
+		return nil, false
+	}
+	rat, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, false
+	}
+	display, exponent, ok := denoms.DisplayUnit(ctx, denom)
+	if !ok {
+		// unknown denoms keep their base unit but get the same formatting
+		display, exponent = denom, 0
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	rat.Quo(rat, new(big.Rat).SetInt(scale))
+
+	coin["denom"] = display
+	coin["amount"] = groupThousands(rat.FloatString(opts.precision), opts.separator)
+	return coin, true
+}
+
+// groupThousands inserts separator between groups of three integer digits.
+func groupThousands(number, separator string) string {
+	if separator == "" {
+		return number
+	}
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+	integer, fraction := number, ""
+	if i := strings.IndexByte(number, '.'); i >= 0 {
+		integer, fraction = number[:i], number[i:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(fraction)
+	return b.String()
+}
@@ -0,0 +1,398 @@
+package web
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+)
+
+const OutputParam = "output"
+
+// Output is a response encoding a client can ask for.
+type Output string
+
+const (
+	// OutputJSON is the default, the Go JSON encoding of the response.
+	OutputJSON Output = "json"
+	// OutputProtoJSON is the canonical proto3 JSON mapping.
+	OutputProtoJSON Output = "protojson"
+	// OutputAmino is the legacy Amino JSON encoding.
+	OutputAmino Output = "amino"
+	// OutputProto is the raw protobuf bytes.
+	OutputProto Output = "proto"
+	OutputYAML  Output = "yaml"
+	// OutputCSV only applies to list responses.
+	OutputCSV Output = "csv"
+)
+
+const (
+	MIMEProtobuf  = "application/x-protobuf"
+	MIMEProtoJSON = "application/vnd.cosmos.protojson+json"
+	MIMEAminoJSON = "application/vnd.cosmos.amino+json"
+	MIMEYAML      = "application/yaml"
+	MIMECSV       = "text/csv"
+)
+
+var outputs = map[Output]bool{
+	OutputJSON:      true,
+	OutputProtoJSON: true,
+	OutputAmino:     true,
+	OutputProto:     true,
+	OutputYAML:      true,
+	OutputCSV:       true,
+}
+
+var mediaTypes = map[string]Output{
+	"*/*":                  OutputJSON,
+	"application/*":        OutputJSON,
+	"application/json":     OutputJSON,
+	MIMEProtoJSON:          OutputProtoJSON,
+	MIMEAminoJSON:          OutputAmino,
+	MIMEProtobuf:           OutputProto,
+	"application/protobuf": OutputProto,
+	MIMEYAML:               OutputYAML,
+	"application/x-yaml":   OutputYAML,
+	"text/yaml":            OutputYAML,
+	MIMECSV:                OutputCSV,
+}
+
+var ErrNotAcceptable = errors.New("none of the accepted media types can be produced")
+
+const outputKey = "web.output"
+
+// Negotiate rejects requests for an output that can't be produced before
+// any work is done, and records the chosen one for OutputFrom.
+func Negotiate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		output, err := NegotiateOutput(c)
+		if errors.Is(err, ErrNotAcceptable) {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(outputKey, output)
+		c.Next()
+	}
+}
+
+// OutputFrom returns the output negotiated for the request, JSON if the
+// route isn't behind Negotiate.
+func OutputFrom(c *gin.Context) Output {
+	if output, ok := c.Get(outputKey); ok {
+		return output.(Output)
+	}
+	return OutputJSON
+}
+
+// NegotiateOutput picks the response encoding from ?output=, falling back to
+// the Accept header. Without either the default JSON is used.
+func NegotiateOutput(c *gin.Context) (Output, error) {
+	if values, ok := c.GetQueryArray(OutputParam); ok {
+		output := Output(strings.ToLower(values[0]))
+		if !outputs[output] {
+			return "", fmt.Errorf("invalid output %q", values[0])
+		}
+		return output, nil
+	}
+	return parseAccept(c.GetHeader("Accept"))
+}
+
+// parseAccept returns the supported media type with the highest quality,
+// the earliest listed one on ties.
+func parseAccept(accept string) (Output, error) {
+	if strings.TrimSpace(accept) == "" {
+		return OutputJSON, nil
+	}
+
+	type candidate struct {
+		output Output
+		q      float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		output, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{output: output, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrNotAcceptable
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].output, nil
+}
+
+// JSONToYAML converts a JSON document to YAML keeping the key order.
+func JSONToYAML(bz []byte) ([]byte, error) {
+	v, err := decodeOrdered(bz)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(toYAML(v))
+}
+
+func toYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case orderedObject:
+		out := make(yaml.MapSlice, len(v))
+		for i, f := range v {
+			out[i] = yaml.MapItem{Key: f.key, Value: toYAML(f.value)}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = toYAML(e)
+		}
+		return out
+	case json.Number:
+		// written bare so it reads as a number, not a quoted string
+		return yamlNumber(v)
+	default:
+		return v
+	}
+}
+
+type yamlNumber json.Number
+
+func (n yamlNumber) MarshalYAML() (interface{}, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u, nil
+	}
+	return strconv.ParseFloat(string(n), 64)
+}
+
+var ErrNotList = errors.New("csv output is only available for list endpoints")
+
+// JSONToCSV writes the first list found in the JSON document, depth first in
+// key order, as CSV. Objects are flattened into dotted columns, nested lists
+// are kept as JSON in a single cell.
+func JSONToCSV(bz []byte) ([]byte, error) {
+	v, err := decodeOrdered(bz)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := findList(v)
+	if !ok {
+		return nil, ErrNotList
+	}
+
+	var header []string
+	seen := map[string]bool{}
+	rows := make([]map[string]string, len(list))
+	for i, e := range list {
+		row := map[string]string{}
+		flatten("", e, row, func(column string) {
+			if !seen[column] {
+				seen[column] = true
+				header = append(header, column)
+			}
+		})
+		rows[i] = row
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if len(header) > 0 {
+		_ = w.Write(header)
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = row[column]
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// AddJSONField appends key with value to the fields of the JSON object bz,
+// keeping their order.
+func AddJSONField(bz []byte, key string, value interface{}) ([]byte, error) {
+	bz = bytes.TrimSpace(bz)
+	if len(bz) < 2 || bz[0] != '{' || bz[len(bz)-1] != '}' {
+		return nil, errors.New("not a json object")
+	}
+	encoded, err := json.Marshal(map[string]interface{}{key: value})
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(nil), bz[:len(bz)-1]...)
+	if len(bytes.TrimSpace(bz[1:len(bz)-1])) > 0 {
+		out = append(out, ',')
+	}
+	return append(out, encoded[1:]...), nil
+}
+
+// AddCSVColumn appends a column holding value on every row to the CSV bz.
+func AddCSVColumn(bz []byte, column, value string) ([]byte, error) {
+	records, err := csv.NewReader(bytes.NewReader(bz)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for i, record := range records {
+		if i == 0 {
+			record = append(record, column)
+		} else {
+			record = append(record, value)
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func findList(v interface{}) ([]interface{}, bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, true
+	case orderedObject:
+		for _, f := range v {
+			if list, ok := findList(f.value); ok {
+				return list, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func flatten(prefix string, v interface{}, row map[string]string, column func(string)) {
+	name := prefix
+	if name == "" {
+		name = "value"
+	}
+	switch v := v.(type) {
+	case orderedObject:
+		for _, f := range v {
+			key := f.key
+			if prefix != "" {
+				key = prefix + "." + f.key
+			}
+			flatten(key, f.value, row, column)
+		}
+		return
+	case []interface{}:
+		bz, _ := json.Marshal(toPlain(v))
+		row[name] = string(bz)
+	case nil:
+		row[name] = ""
+	case json.Number:
+		row[name] = string(v)
+	case string:
+		row[name] = v
+	default:
+		row[name] = fmt.Sprint(v)
+	}
+	column(name)
+}
+
+func toPlain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case orderedObject:
+		out := make(map[string]interface{}, len(v))
+		for _, f := range v {
+			out[f.key] = toPlain(f.value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = toPlain(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// orderedObject is a JSON object that remembers its key order, which the
+// YAML and CSV outputs keep.
+type orderedObject []field
+
+func decodeOrdered(bz []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after json value")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := orderedObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{key: key.(string), value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	default:
+		return tok, nil
+	}
+}
@@ -0,0 +1,207 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	PrettyParam = "pretty"
+	FieldsParam = "fields"
+)
+
+// Render applies the ?format=display, ?fields=a.b,c and ?pretty=true options
+// to JSON responses. Display formatting converts coin amounts to display
+// units with denoms. Fields are dotted paths, arrays are filtered element by
+// element. Only successful responses are reformatted so errors stay
+// readable. Other responses, streams included, pass through untouched.
+func Render(denoms DenomResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pretty, _ := strconv.ParseBool(c.Query(PrettyParam))
+		fields := parseFields(c.Query(FieldsParam))
+		var separator *string
+		if values, ok := c.GetQueryArray(SeparatorParam); ok {
+			separator = &values[0]
+		}
+		display, err := parseDisplayOptions(c.Query(FormatParam), c.Query(PrecisionParam), separator)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !pretty && fields == nil && display == nil {
+			c.Next()
+			return
+		}
+
+		w := &renderWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if !w.decided {
+			w.ResponseWriter.WriteHeader(w.status)
+			return
+		}
+		if !w.buffering {
+			return
+		}
+
+		body := w.body.Bytes()
+		if (fields != nil || display != nil) && w.status/100 == 2 {
+			body = transform(c.Request.Context(), body, fields, display, denoms)
+		}
+		if pretty {
+			var indented bytes.Buffer
+			if json.Indent(&indented, body, "", "  ") == nil {
+				indented.WriteByte('\n')
+				body = indented.Bytes()
+			}
+		}
+		w.Header().Del("Content-Length")
+		w.ResponseWriter.WriteHeader(w.status)
+		_, _ = w.ResponseWriter.Write(body)
+	}
+}
+
+// renderWriter buffers JSON bodies and passes anything else through, decided
+// on the first write from the Content-Type.
+type renderWriter struct {
+	gin.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (w *renderWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *renderWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+	}
+}
+
+func (w *renderWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *renderWriter) Write(bz []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(bz)
+	}
+	return w.ResponseWriter.Write(bz)
+}
+
+func (w *renderWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *renderWriter) Status() int {
+	if w.buffering || !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *renderWriter) Written() bool {
+	return w.decided
+}
+
+func (w *renderWriter) Flush() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// fieldTree is a parsed ?fields= list, a nil child keeps the whole value.
+type fieldTree map[string]fieldTree
+
+func parseFields(value string) fieldTree {
+	paths := splitList(value)
+	if len(paths) == 0 {
+		return nil
+	}
+	tree := fieldTree{}
+	for _, path := range paths {
+		node := tree
+		parts := strings.Split(path, ".")
+		for i, part := range parts {
+			child, ok := node[part]
+			if i == len(parts)-1 {
+				// a shorter path keeps everything below it
+				node[part] = nil
+				break
+			}
+			if ok && child == nil {
+				break
+			}
+			if !ok {
+				child = fieldTree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return tree
+}
+
+func transform(ctx context.Context, body []byte, fields fieldTree, display *displayOptions, denoms DenomResolver) []byte {
+	// numbers stay json.Number so large integers survive the round trip
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return body
+	}
+	if display != nil {
+		value = displayAmounts(ctx, value, denoms, display)
+	}
+	bz, err := json.Marshal(fields.apply(value))
+	if err != nil {
+		return body
+	}
+	return bz
+}
+
+func (t fieldTree) apply(value interface{}) interface{} {
+	if t == nil {
+		return value
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for key, child := range t {
+			if v, ok := value[key]; ok {
+				out[key] = child.apply(v)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, v := range value {
+			out[i] = t.apply(v)
+		}
+		return out
+	default:
+		return value
+	}
+}
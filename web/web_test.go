@@ -0,0 +1,238 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDenoms struct{}
+
+func (fakeDenoms) DisplayUnit(_ context.Context, base string) (string, uint32, bool) {
+	switch base {
+	case "FX":
+		return "FX", 18, true
+	case "upundix":
+		return "PUNDIX", 6, true
+	}
+	return "", 0, false
+}
+
+func newTestEngine(cfg CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CORS(cfg), Render(fakeDenoms{}))
+	engine.GET("/query/bank/balance", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"balance":    gin.H{"denom": "FX", "amount": "10"},
+			"pagination": nil,
+			"balances":   []gin.H{{"denom": "FX", "amount": "1"}, {"denom": "PUNDIX", "amount": "2"}},
+		})
+	})
+	engine.GET("/query/distribution/validatorCommission", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"height": uint64(9007199254740993),
+			"commission": gin.H{"commission": []gin.H{
+				{"denom": "FX", "amount": "498642746962843784115573074.123000000000000000"},
+				{"denom": "upundix", "amount": "1500000"},
+				{"denom": "eth0xabc", "amount": "7"},
+			}},
+		})
+	})
+	engine.GET("/query/bad", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "address empty"})
+	})
+	engine.GET("/stream/blocks", func(c *gin.Context) {
+		c.Stream(func(w io.Writer) bool {
+			c.SSEvent("message", "block")
+			return false
+		})
+	})
+	engine.DELETE("/watches/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return engine
+}
+
+// streamRecorder adds the CloseNotify gin's Stream needs.
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func do(engine *gin.Engine, method, target string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v[0])
+	}
+	engine.ServeHTTP(streamRecorder{w}, req)
+	return w
+}
+
+func Test_Fields(t *testing.T) {
+	engine := newTestEngine(CORSConfig{})
+	w := do(engine, http.MethodGet, "/query/bank/balance?fields=balance.amount,balances.denom", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"balance":{"amount":"10"},"balances":[{"denom":"FX"},{"denom":"PUNDIX"}]}`, w.Body.String())
+
+	// a shorter path wins over a longer one
+	w = do(engine, http.MethodGet, "/query/bank/balance?fields=balance.amount,balance", nil)
+	require.JSONEq(t, `{"balance":{"denom":"FX","amount":"10"}}`, w.Body.String())
+
+	// errors are not filtered
+	w = do(engine, http.MethodGet, "/query/bad?fields=balance", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.JSONEq(t, `{"error":"address empty"}`, w.Body.String())
+}
+
+func Test_Pretty(t *testing.T) {
+	engine := newTestEngine(CORSConfig{})
+	w := do(engine, http.MethodGet, "/query/bad?pretty=true", nil)
+	require.Equal(t, "{\n  \"error\": \"address empty\"\n}\n", w.Body.String())
+
+	// non JSON and empty responses pass through
+	w = do(engine, http.MethodGet, "/stream/blocks?pretty=true", nil)
+	require.Equal(t, "event:message\ndata:block\n\n", w.Body.String())
+	w = do(engine, http.MethodDelete, "/watches/1?pretty=true", nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func Test_CORS(t *testing.T) {
+	engine := newTestEngine(CORSConfig{
+		AllowedOrigins: []string{"https://dashboard.pundix.com"},
+		AllowedMethods: []string{http.MethodGet},
+		AllowedHeaders: []string{"X-API-Key"},
+	})
+
+	preflight := do(engine, http.MethodOptions, "/query/bank/balance", http.Header{
+		"Origin":                         {"https://dashboard.pundix.com"},
+		"Access-Control-Request-Method":  {"GET"},
+		"Access-Control-Request-Headers": {"X-API-Key"},
+	})
+	require.Equal(t, http.StatusNoContent, preflight.Code)
+	require.Equal(t, "https://dashboard.pundix.com", preflight.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "X-Api-Key", preflight.Header().Get("Access-Control-Allow-Headers"))
+
+	actual := do(engine, http.MethodGet, "/query/bank/balance", http.Header{"Origin": {"https://dashboard.pundix.com"}})
+	require.Equal(t, "https://dashboard.pundix.com", actual.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, actual.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id")
+
+	other := do(engine, http.MethodGet, "/query/bank/balance", http.Header{"Origin": {"https://evil.example"}})
+	require.Empty(t, other.Header().Get("Access-Control-Allow-Origin"))
+
+	// disabled without origins
+	disabled := do(newTestEngine(CORSConfig{}), http.MethodGet, "/query/bank/balance", http.Header{"Origin": {"https://dashboard.pundix.com"}})
+	require.Empty(t, disabled.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func Test_CORSDefaultHeaders(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://dashboard.pundix.com")
+	cfg, err := CORSConfigFromEnv()
+	require.NoError(t, err)
+
+	// snapshot tokens are sent as a header by browsers too
+	preflight := do(newTestEngine(cfg), http.MethodOptions, "/query/bank/balance", http.Header{
+		"Origin":                         {"https://dashboard.pundix.com"},
+		"Access-Control-Request-Method":  {"GET"},
+		"Access-Control-Request-Headers": {"X-API-Key,X-Snapshot"},
+	})
+	require.Equal(t, http.StatusNoContent, preflight.Code)
+	require.Equal(t, "X-Api-Key, X-Snapshot", preflight.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func Test_DisplayFormat(t *testing.T) {
+	engine := newTestEngine(CORSConfig{})
+	w := do(engine, http.MethodGet, "/query/distribution/validatorCommission?format=display", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"height":9007199254740993,"commission":{"commission":[
+		{"denom":"FX","amount":"498,642,746.962844"},
+		{"denom":"PUNDIX","amount":"1.500000"},
+		{"denom":"eth0xabc","amount":"7.000000"}]}}`, w.Body.String())
+
+	w = do(engine, http.MethodGet, "/query/distribution/validatorCommission?format=display&precision=2&separator=&fields=commission.commission.amount", nil)
+	require.JSONEq(t, `{"commission":{"commission":[{"amount":"498642746.96"},{"amount":"1.50"},{"amount":"7.00"}]}}`, w.Body.String())
+
+	w = do(engine, http.MethodGet, "/query/bank/balance?format=display&precision=99", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(engine, http.MethodGet, "/query/bank/balance?format=xml", nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func Test_GroupThousands(t *testing.T) {
+	require.Equal(t, "-1 234 567.89", groupThousands("-1234567.89", " "))
+	require.Equal(t, "123", groupThousands("123", ","))
+	require.Equal(t, "1,000", groupThousands("1000", ","))
+}
+
+func Test_NegotiateOutput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/query/bank/balance", Negotiate(), func(c *gin.Context) {
+		c.String(http.StatusOK, string(OutputFrom(c)))
+	})
+
+	for _, tc := range []struct {
+		target, accept string
+		status         int
+		output         Output
+	}{
+		{"/query/bank/balance", "", http.StatusOK, OutputJSON},
+		{"/query/bank/balance", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK, OutputJSON},
+		{"/query/bank/balance", "application/json;q=0.5, application/yaml", http.StatusOK, OutputYAML},
+		{"/query/bank/balance", "application/x-protobuf", http.StatusOK, OutputProto},
+		{"/query/bank/balance", MIMEAminoJSON, http.StatusOK, OutputAmino},
+		{"/query/bank/balance?output=CSV", "application/json", http.StatusOK, OutputCSV},
+		{"/query/bank/balance?output=xml", "", http.StatusBadRequest, ""},
+		{"/query/bank/balance", "text/html", http.StatusNotAcceptable, ""},
+		{"/query/bank/balance", "application/yaml;q=0", http.StatusNotAcceptable, ""},
+	} {
+		w := do(engine, http.MethodGet, tc.target, http.Header{"Accept": {tc.accept}})
+		require.Equal(t, tc.status, w.Code, tc.target+" "+tc.accept)
+		if tc.status == http.StatusOK {
+			require.Equal(t, string(tc.output), w.Body.String())
+		}
+	}
+}
+
+func Test_JSONToYAML(t *testing.T) {
+	bz, err := JSONToYAML([]byte(`{"params":{"community_tax":"0.4","withdraw_addr_enabled":true,"height":42}}`))
+	require.NoError(t, err)
+	require.Equal(t, "params:\n  community_tax: \"0.4\"\n  withdraw_addr_enabled: true\n  height: 42\n", string(bz))
+}
+
+func Test_JSONToCSV(t *testing.T) {
+	bz, err := JSONToCSV([]byte(`{"commission":{"commission":[{"denom":"FX","amount":"1.5"},{"denom":"upundix","amount":"2","extra":{"a":[1,2]}}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, "denom,amount,extra.a\nFX,1.5,\nupundix,2,\"[1,2]\"\n", string(bz))
+
+	bz, err = JSONToCSV([]byte(`{"addresses":["fx1a","fx1b"]}`))
+	require.NoError(t, err)
+	require.Equal(t, "value\nfx1a\nfx1b\n", string(bz))
+
+	_, err = JSONToCSV([]byte(`{"amount":{"denom":"FX","amount":"1"}}`))
+	require.ErrorIs(t, err, ErrNotList)
+}
+
+func Test_AddFields(t *testing.T) {
+	bz, err := AddJSONField([]byte(`{"b":1,"a":2}`+"\n"), "verified", true)
+	require.NoError(t, err)
+	require.Equal(t, `{"b":1,"a":2,"verified":true}`, string(bz))
+	bz, err = AddJSONField([]byte(`{}`), "verified", true)
+	require.NoError(t, err)
+	require.Equal(t, `{"verified":true}`, string(bz))
+	_, err = AddJSONField([]byte(`[1]`), "verified", true)
+	require.Error(t, err)
+
+	bz, err = AddCSVColumn([]byte("denom,amount\nFX,1\nupundix,2\n"), "verified", "true")
+	require.NoError(t, err)
+	require.Equal(t, "denom,amount,verified\nFX,1,true\nupundix,2,true\n", string(bz))
+}